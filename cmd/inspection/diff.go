@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+	"github.com/kekexiaoai/inspection/pkg/store"
+)
+
+// runDiff 比较同一模板的两次报告，输出 JSON 格式的差异
+func runDiff(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	storeAddr := fs.String("store", "", "从存储中按运行 ID 读取报告，不设置时参数为报告 JSON 文件")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: inspection diff [-store addr] <base> <current>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		fs.Usage()
+		return errors.New("diff: expected base and current reports")
+	}
+
+	load := loadReportFile
+	if *storeAddr != "" {
+		s, err := store.Open(*storeAddr)
+		if err != nil {
+			return fmt.Errorf("diff: %w", err)
+		}
+		defer s.Close()
+		load = func(id string) (*inspection.Report, error) {
+			r, err := s.Get(context.Background(), id)
+			if err != nil {
+				return nil, err
+			}
+			return r.Report, nil
+		}
+	}
+	base, err := load(fs.Arg(0))
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+	current, err := load(fs.Arg(1))
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+
+	diff, err := inspection.DiffReports(base, current)
+	if err != nil {
+		return fmt.Errorf("diff: %w", err)
+	}
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(diff)
+}
+
+// loadReportFile 读取 run 命令输出的报告
+func loadReportFile(path string) (*inspection.Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report inspection.Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("parse report %s: %w", path, err)
+	}
+	return &report, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// varFlags 可重复的 -var key=value 参数
+type varFlags map[string]string
+
+func (v varFlags) String() string { return "" }
+
+func (v varFlags) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("invalid var %q, expected key=value", s)
+	}
+	v[key] = value
+	return nil
+}
+
+// runDryRun 渲染模板中所有启用指标的查询，不执行查询
+func runDryRun(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("dryrun", flag.ContinueOnError)
+	file := fs.String("f", "", "模板文件路径（必填）")
+	asJSON := fs.Bool("json", false, "以 JSON 格式输出")
+	vars := varFlags{}
+	fs.Var(vars, "var", "变量输入，格式 key=value，可重复")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("dryrun: -f is required")
+	}
+
+	tpl, err := inspection.ParseTemplateFile(*file)
+	if err != nil {
+		return err
+	}
+	result, runErr := inspection.DryRun(tpl, vars)
+
+	if *asJSON {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(result); err != nil {
+			return err
+		}
+	} else {
+		printDryRun(stdout, result)
+	}
+	if runErr != nil {
+		return fmt.Errorf("dryrun failed:\n%w", runErr)
+	}
+	return nil
+}
+
+func printDryRun(w io.Writer, result *inspection.DryRunResult) {
+	fmt.Fprintf(w, "template: %s (%s)\n", result.Template, result.DisplayName)
+	for i, ind := range result.Indicators {
+		fmt.Fprintf(w, "\n[%d] %s\n", i+1, ind.Name)
+		fmt.Fprintf(w, "    source:     %s / %s\n", ind.Source, ind.Type)
+		fmt.Fprintf(w, "    exporter:   %s\n", ind.ExporterPool)
+		fmt.Fprintf(w, "    time_range: %s\n", ind.TimeRange)
+		if ind.Resolution != "" {
+			fmt.Fprintf(w, "    resolution: %s\n", ind.Resolution)
+		}
+		for _, th := range ind.Thresholds {
+			value := "<nil>"
+			if th.Value != nil {
+				value = fmt.Sprint(*th.Value)
+			}
+			fmt.Fprintf(w, "    threshold:  %-8s %s %s  %s\n", th.Level, th.Operator, value, th.Description)
+		}
+		fmt.Fprintln(w, "    query:")
+		for _, line := range strings.Split(strings.TrimRight(ind.Query, "\n"), "\n") {
+			fmt.Fprintf(w, "      %s\n", line)
+		}
+	}
+	if len(result.Skipped) > 0 {
+		fmt.Fprintf(w, "\nskipped (disabled): %s\n", strings.Join(result.Skipped, ", "))
+	}
+}
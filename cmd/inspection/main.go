@@ -0,0 +1,65 @@
+// Command inspection 巡检模板的命令行工具
+//
+// 用法：
+//
+//	inspection <command> [flags]
+//
+// 支持的命令：
+//
+//	schema   输出模板格式的 JSON Schema
+//	dryrun   渲染模板中所有启用指标的查询，不执行查询
+//	run      执行模板并输出报告，支持录制与回放 Prometheus 响应
+//	test     执行模板测试文件，校验指标的阈值判断
+//	diff     比较同一模板的两次报告
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// command 子命令定义
+type command struct {
+	name  string
+	usage string
+	run   func(args []string, stdout io.Writer) error
+}
+
+var commands = []command{
+	{name: "schema", usage: "输出模板格式的 JSON Schema", run: runSchema},
+	{name: "dryrun", usage: "渲染模板中所有启用指标的查询，不执行查询", run: runDryRun},
+	{name: "run", usage: "执行模板并输出报告，支持录制与回放 Prometheus 响应", run: runRun},
+	{name: "test", usage: "执行模板测试文件，校验指标的阈值判断", run: runTest},
+	{name: "diff", usage: "比较同一模板的两次报告", run: runDiff},
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(stdout)
+		return nil
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:], stdout)
+		}
+	}
+	printUsage(os.Stderr)
+	return fmt.Errorf("unknown command %q", args[0])
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: inspection <command> [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-8s %s\n", c.name, c.usage)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+	"github.com/kekexiaoai/inspection/pkg/notify"
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/store"
+)
+
+// runRun 执行模板并输出 JSON 报告，可录制 Prometheus 响应或回放录制包
+func runRun(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	file := fs.String("f", "", "模板文件路径（必填）")
+	addr := fs.String("addr", "", "Prometheus 地址，回放时不需要")
+	user := fs.String("user", "", "报告中的执行人")
+	timeout := fs.Duration("timeout", 30*time.Second, "单个查询的超时时间")
+	record := fs.String("record", "", "将本次执行的输入与 Prometheus 响应录制到该文件")
+	replay := fs.String("replay", "", "回放录制包，不访问 Prometheus（忽略 -addr、-var、-user，不能与 -store 同时使用）")
+	storeAddr := fs.String("store", "", "保存报告的存储，如 sqlite:reports.db 或 file:reports（目录）；报告中附带与上次报告相比的变化")
+	historyRuns := fs.Int("history", 30, "设置 -store 时，按最近多少次执行计算每个目标的状态历史，0 表示不计算")
+	notifyConfig := fs.String("notify", "", "通知配置文件，执行完成后按其中的路由规则发送报告")
+	vars := varFlags{}
+	fs.Var(vars, "var", "变量输入，格式 key=value，可重复")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("run: -f is required")
+	}
+	if *replay != "" && *record != "" {
+		return errors.New("run: -record and -replay are mutually exclusive")
+	}
+	// 回放的报告已在录制时的执行中产生，再次保存会让同一次执行出现两条记录
+	if *replay != "" && *storeAddr != "" {
+		return errors.New("run: -store and -replay are mutually exclusive")
+	}
+	if *replay == "" && *addr == "" {
+		return errors.New("run: -addr is required")
+	}
+
+	tpl, err := inspection.ParseTemplateFile(*file)
+	if err != nil {
+		return err
+	}
+	var dispatcher *notify.Dispatcher
+	if *notifyConfig != "" {
+		if dispatcher, err = notify.LoadConfig(*notifyConfig); err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+	}
+	var reports store.Store
+	if *storeAddr != "" {
+		if reports, err = store.Open(*storeAddr); err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+		defer reports.Close()
+	}
+
+	var report *inspection.Report
+	var runErr error
+	if *replay != "" {
+		bundle, err := inspection.LoadRunBundle(*replay)
+		if err != nil {
+			return err
+		}
+		report, runErr = inspection.Replay(context.Background(), tpl, bundle)
+		vars = bundle.Vars
+	} else {
+		var rec *prom.Recorder
+		opts := []prom.Option{prom.WithTimeout(*timeout)}
+		if *record != "" {
+			rec = prom.NewRecorder(nil)
+			opts = append(opts, prom.WithRoundTripper(rec))
+		}
+		client, err := prom.NewClient(*addr, opts...)
+		if err != nil {
+			return err
+		}
+		defer client.Close()
+		targets := prom.NewIndexedTargetCache(client, time.Minute)
+		defer targets.Close()
+		// 目标列表不可用时无法判断缺失的目标，等待首次刷新成功后再执行
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		err = targets.WaitReady(ctx)
+		cancel()
+		if err != nil {
+			return fmt.Errorf("run: target cache: %w", errors.Join(err, targets.LastError()))
+		}
+
+		execOpts := []inspection.ExecutorOption{inspection.WithExecutedBy(*user)}
+		if reports != nil && *historyRuns > 0 {
+			execOpts = append(execOpts, inspection.WithHistory(store.History(reports, *historyRuns)))
+		}
+		exec := inspection.NewExecutor(client, targets, execOpts...)
+		report, runErr = exec.Execute(context.Background(), tpl, vars)
+		if rec != nil && report != nil {
+			if err := inspection.SaveRunBundle(*record, inspection.NewRunBundle(tpl, vars, report, rec.Exchanges())); err != nil {
+				return err
+			}
+		}
+	}
+	if report == nil {
+		return runErr
+	}
+	if reports != nil {
+		if err := saveReport(reports, tpl, vars, report, runErr); err != nil {
+			return err
+		}
+	}
+
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	if dispatcher != nil {
+		if err := dispatcher.Dispatch(context.Background(), report); err != nil {
+			runErr = errors.Join(runErr, fmt.Errorf("notify: %w", err))
+		}
+	}
+	if runErr != nil {
+		return fmt.Errorf("run failed:\n%w", runErr)
+	}
+	return nil
+}
+
+// saveReport 计算与该模板上次报告相比的变化，并将报告保存到存储
+func saveReport(s store.Store, tpl *inspection.Template, vars map[string]string, report *inspection.Report, runErr error) error {
+	ctx := context.Background()
+	last, err := s.List(ctx, store.Query{Template: tpl.Name, Until: report.Template.ExecutedAt, Limit: 1})
+	if err != nil {
+		return fmt.Errorf("run: load last report: %w", err)
+	}
+	if len(last) > 0 {
+		if report.Changes, err = inspection.DiffReports(last[0].Report, report); err != nil {
+			return fmt.Errorf("run: %w", err)
+		}
+	}
+	if err := s.Save(ctx, store.NewRecord(tpl, vars, report, runErr)); err != nil {
+		return fmt.Errorf("run: save report: %w", err)
+	}
+	return nil
+}
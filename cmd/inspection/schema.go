@@ -0,0 +1,28 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// runSchema 输出模板 JSON Schema，-o 指定时写入文件
+func runSchema(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("schema", flag.ContinueOnError)
+	output := fs.String("o", "", "输出文件路径（默认输出到标准输出）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	data, err := inspection.JSONSchemaBytes()
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(*output, data, 0o644)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// runTest 执行模板测试文件，类似 promtool test rules
+func runTest(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: inspection test <test-file>...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("test: at least one test file is required")
+	}
+
+	failed := 0
+	for _, path := range fs.Args() {
+		fmt.Fprintf(stdout, "Unit Testing: %s\n", path)
+		result, err := inspection.RunTestSpecFile(path)
+		if err != nil {
+			fmt.Fprintf(stdout, "  FAILED: %v\n", err)
+			failed++
+			continue
+		}
+		for _, c := range result.Cases {
+			if c.Passed() {
+				fmt.Fprintf(stdout, "  PASS  %s [%s]\n", c.Name, c.Indicator)
+				continue
+			}
+			failed++
+			fmt.Fprintf(stdout, "  FAIL  %s [%s]\n", c.Name, c.Indicator)
+			for _, f := range c.Failures {
+				fmt.Fprintf(stdout, "        %s\n", f)
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("test: %d failed", failed)
+	}
+	return nil
+}
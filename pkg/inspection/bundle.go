@@ -0,0 +1,94 @@
+package inspection
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+// RunBundleVersion 录制包格式版本
+const RunBundleVersion = 1
+
+// replayAddress 回放时客户端使用的地址，请求不会发出
+const replayAddress = "http://replay.invalid"
+
+// RunBundle 录制包：一次执行的输入（模板、变量、执行时间）与 Prometheus 的原始响应，
+// 用于回放以精确复现报告，可附在问题报告中
+type RunBundle struct {
+	Version         int               `json:"version"`
+	Template        string            `json:"template"`
+	TemplateVersion string            `json:"template_version,omitempty"`
+	Vars            map[string]string `json:"vars,omitempty"`
+	ExecutedAt      time.Time         `json:"executed_at"`
+	ExecutedBy      string            `json:"executed_by,omitempty"`
+	Exchanges       []prom.Exchange   `json:"exchanges"`
+}
+
+// NewRunBundle 将一次执行的输入与录制的响应打包，
+// exchanges 通常来自配置在客户端上的 prom.Recorder
+func NewRunBundle(tpl *Template, vars map[string]string, report *Report, exchanges []prom.Exchange) *RunBundle {
+	return &RunBundle{
+		Version:         RunBundleVersion,
+		Template:        tpl.Name,
+		TemplateVersion: tpl.Version,
+		Vars:            vars,
+		ExecutedAt:      report.Template.ExecutedAt,
+		ExecutedBy:      report.Template.ExecutedBy,
+		Exchanges:       exchanges,
+	}
+}
+
+// SaveRunBundle 将录制包写入文件
+func SaveRunBundle(path string, b *RunBundle) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadRunBundle 读取录制包
+func LoadRunBundle(path string) (*RunBundle, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var b RunBundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("parse run bundle %s: %w", path, err)
+	}
+	if b.Version != RunBundleVersion {
+		return nil, fmt.Errorf("run bundle %s: unsupported version %d", path, b.Version)
+	}
+	return &b, nil
+}
+
+// Replay 使用录制包回放一次执行，不访问 Prometheus
+//
+// 执行时间、执行人与变量取自录制包，查询与目标列表由录制的响应提供；
+// 模板被修改后产生录制中不存在的查询时，对应指标返回 prom.ErrNotRecorded。
+func Replay(ctx context.Context, tpl *Template, b *RunBundle) (*Report, error) {
+	if b.Template != tpl.Name {
+		return nil, fmt.Errorf("run bundle recorded for template %q, got %q", b.Template, tpl.Name)
+	}
+	client, err := prom.NewClient(replayAddress, prom.WithRoundTripper(prom.NewReplayer(b.Exchanges)))
+	if err != nil {
+		return nil, err
+	}
+	defer client.Close()
+
+	// 回放期间不需要后台刷新
+	targets := prom.NewIndexedTargetCache(client, 24*time.Hour)
+	defer targets.Close()
+
+	executedAt := b.ExecutedAt
+	exec := NewExecutor(client, targets,
+		WithNow(func() time.Time { return executedAt }),
+		WithExecutedBy(b.ExecutedBy),
+	)
+	return exec.Execute(ctx, tpl, b.Vars)
+}
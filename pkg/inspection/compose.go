@@ -0,0 +1,372 @@
+package inspection
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 模板组合相关的 YAML 键
+const (
+	keyExtends    = "extends"
+	keyInclude    = "include"
+	keyLibrary    = "library"
+	keyVars       = "vars"
+	keyIndicators = "indicators"
+	keyUse        = "use"
+	keyName       = "name"
+)
+
+// composer 解析模板组合：extends（继承父模板）、include（引入变量与指标库片段）、
+// 以及指标的 use（引用指标库中的指标并按需覆盖字段）
+//
+// 所有相对路径均相对于声明它的文件所在目录解析。
+type composer struct {
+	// stack 当前正在解析的文件（绝对路径），用于循环检测
+	stack []string
+	// files 记录节点来自哪个文件，用于错误定位
+	files map[*yaml.Node]string
+}
+
+func newComposer() *composer {
+	return &composer{files: make(map[*yaml.Node]string)}
+}
+
+// resolveTemplate 解析模板文档的组合关系，返回合并后的映射节点
+// source 为模板文件路径（未知时为空），baseDir 为相对路径的解析目录
+func (c *composer) resolveTemplate(doc *yaml.Node, source, baseDir string) (*yaml.Node, error) {
+	root := documentRoot(doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return doc, nil
+	}
+	if source != "" {
+		c.markFile(root, source)
+	}
+	if !hasCompositionKeys(root) {
+		return doc, nil
+	}
+
+	if source != "" {
+		if err := c.push(source); err != nil {
+			return nil, err
+		}
+		defer c.pop()
+	}
+
+	merged, err := c.resolveMapping(root, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.resolveUses(merged); err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// resolveMapping 处理 extends 与 include，返回合并后的新节点（不修改输入）
+func (c *composer) resolveMapping(node *yaml.Node, baseDir string) (*yaml.Node, error) {
+	result := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+
+	// 1. 继承父模板：父模板的内容作为基础
+	if ext := mappingValue(node, keyExtends); ext != nil && ext.Value != "" {
+		parent, err := c.loadFile(ext, baseDir, true)
+		if err != nil {
+			return nil, err
+		}
+		result = c.mergeTemplateNodes(result, parent)
+	}
+
+	// 2. 引入片段：只合并 vars 与 library
+	if inc := mappingValue(node, keyInclude); inc != nil {
+		if inc.Kind != yaml.SequenceNode {
+			return nil, c.errorf(inc, "%s must be a list of file paths", keyInclude)
+		}
+		for _, item := range inc.Content {
+			fragment, err := c.loadFile(item, baseDir, false)
+			if err != nil {
+				return nil, err
+			}
+			result = c.mergeTemplateNodes(result, pickKeys(fragment, keyVars, keyLibrary))
+		}
+	}
+
+	// 3. 当前文件的定义优先级最高
+	return c.mergeTemplateNodes(result, node), nil
+}
+
+// loadFile 加载并递归解析被引用的文件；extends 的父模板与 include 的片段共用同一套规则
+func (c *composer) loadFile(ref *yaml.Node, baseDir string, isParent bool) (*yaml.Node, error) {
+	if ref.Kind != yaml.ScalarNode || ref.Value == "" {
+		return nil, c.errorf(ref, "invalid file reference")
+	}
+	path := ref.Value
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, c.errorf(ref, "resolve %s: %v", ref.Value, err)
+	}
+	if err := c.push(abs); err != nil {
+		return nil, err
+	}
+	defer c.pop()
+
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, c.errorf(ref, "read %s: %v", ref.Value, err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: yaml unmarshal: %w", abs, err)
+	}
+	root := documentRoot(&doc)
+	if root == nil {
+		return &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("%s: expected a mapping at the top level", abs)
+	}
+	c.markFile(root, abs)
+
+	resolved, err := c.resolveMapping(root, filepath.Dir(abs))
+	if err != nil {
+		return nil, err
+	}
+	if !isParent {
+		return resolved, nil
+	}
+	// 父模板的 extends/include 已在此处展开，避免被子模板继承后重复解析
+	return dropKeys(resolved, keyExtends, keyInclude), nil
+}
+
+// resolveUses 将指标中的 use 引用替换为指标库中的定义与覆盖字段的合并结果
+func (c *composer) resolveUses(root *yaml.Node) error {
+	indicators := mappingValue(root, keyIndicators)
+	if indicators == nil || indicators.Kind != yaml.SequenceNode {
+		return nil
+	}
+	library := make(map[string]*yaml.Node)
+	if lib := mappingValue(root, keyLibrary); lib != nil && lib.Kind == yaml.SequenceNode {
+		for _, item := range lib.Content {
+			if name := mappingValue(item, keyName); name != nil {
+				library[name.Value] = item
+			}
+		}
+	}
+
+	for i, item := range indicators.Content {
+		use := mappingValue(item, keyUse)
+		if use == nil {
+			continue
+		}
+		base, ok := library[use.Value]
+		if !ok {
+			return c.errorf(use, "indicator library entry %q not found", use.Value)
+		}
+		indicators.Content[i] = c.mergeNodes(c.copyNode(base), item)
+	}
+	return nil
+}
+
+func (c *composer) push(path string) error {
+	for i, p := range c.stack {
+		if p == path {
+			chain := append(append([]string{}, c.stack[i:]...), path)
+			return fmt.Errorf("template composition cycle: %s", strings.Join(chain, " -> "))
+		}
+	}
+	c.stack = append(c.stack, path)
+	return nil
+}
+
+func (c *composer) pop() { c.stack = c.stack[:len(c.stack)-1] }
+
+// markFile 记录节点树中每个节点的来源文件
+func (c *composer) markFile(node *yaml.Node, file string) {
+	c.files[node] = file
+	for _, child := range node.Content {
+		c.markFile(child, file)
+	}
+}
+
+// copyNode 深拷贝节点（指标库中的同一个指标可能被多次引用），并保留来源文件信息
+func (c *composer) copyNode(node *yaml.Node) *yaml.Node {
+	cp := *node
+	cp.Content = make([]*yaml.Node, len(node.Content))
+	for i, child := range node.Content {
+		cp.Content[i] = c.copyNode(child)
+	}
+	if file, ok := c.files[node]; ok {
+		c.files[&cp] = file
+	}
+	return &cp
+}
+
+func (c *composer) errorf(node *yaml.Node, format string, args ...any) error {
+	msg := fmt.Sprintf(format, args...)
+	if file := c.files[node]; file != "" {
+		return fmt.Errorf("%s:%d:%d: %s", file, node.Line, node.Column, msg)
+	}
+	return fmt.Errorf("line %d, column %d: %s", node.Line, node.Column, msg)
+}
+
+// -----------------------------------------------------------------------------
+// YAML 节点合并
+// -----------------------------------------------------------------------------
+
+// 合并后的映射节点位置取自 override，即最终生效的（最具体的）定义处
+
+// mergeTemplateNodes 合并两个模板级映射节点：vars / indicators / library 按 name 合并，
+// 其它键按 mergeNodes 规则合并
+func (c *composer) mergeTemplateNodes(base, override *yaml.Node) *yaml.Node {
+	result := c.derive(base, override)
+
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		idx := mappingIndex(result, key.Value)
+		if idx < 0 {
+			result.Content = append(result.Content, key, value)
+			continue
+		}
+		switch key.Value {
+		case keyVars, keyIndicators, keyLibrary:
+			result.Content[idx+1] = c.mergeNamedSequences(result.Content[idx+1], value)
+		default:
+			result.Content[idx+1] = c.mergeNodes(result.Content[idx+1], value)
+		}
+	}
+	return result
+}
+
+// mergeNodes 深度合并：映射按键递归合并，其它类型（标量、序列）由 override 整体替换
+func (c *composer) mergeNodes(base, override *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.MappingNode || override.Kind != yaml.MappingNode {
+		return override
+	}
+	result := c.derive(base, override)
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key, value := override.Content[i], override.Content[i+1]
+		if idx := mappingIndex(result, key.Value); idx >= 0 {
+			result.Content[idx+1] = c.mergeNodes(result.Content[idx+1], value)
+		} else {
+			result.Content = append(result.Content, key, value)
+		}
+	}
+	return result
+}
+
+// mergeNamedSequences 按 name 合并两个序列：同名元素深度合并并保留原位置，新元素追加到末尾
+func (c *composer) mergeNamedSequences(base, override *yaml.Node) *yaml.Node {
+	if base.Kind != yaml.SequenceNode || override.Kind != yaml.SequenceNode {
+		return override
+	}
+	result := c.derive(base, override)
+	for _, item := range override.Content {
+		name := mappingValue(item, keyName)
+		replaced := false
+		if name != nil {
+			for i, existing := range result.Content {
+				if n := mappingValue(existing, keyName); n != nil && n.Value == name.Value {
+					result.Content[i] = c.mergeNodes(existing, item)
+					replaced = true
+					break
+				}
+			}
+		}
+		if !replaced {
+			result.Content = append(result.Content, item)
+		}
+	}
+	return result
+}
+
+// derive 基于 base 的内容创建新节点，位置与来源文件取自 override
+func (c *composer) derive(base, override *yaml.Node) *yaml.Node {
+	result := &yaml.Node{
+		Kind:    base.Kind,
+		Tag:     base.Tag,
+		Line:    override.Line,
+		Column:  override.Column,
+		Content: append([]*yaml.Node{}, base.Content...),
+	}
+	if result.Kind == 0 {
+		result.Kind, result.Tag = override.Kind, override.Tag
+	}
+	if file, ok := c.files[override]; ok {
+		c.files[result] = file
+	}
+	return result
+}
+
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	if doc == nil || doc.Kind == 0 {
+		return nil
+	}
+	if doc.Kind == yaml.DocumentNode {
+		if len(doc.Content) == 0 {
+			return nil
+		}
+		return doc.Content[0]
+	}
+	return doc
+}
+
+func mappingIndex(node *yaml.Node, key string) int {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return -1
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if idx := mappingIndex(node, key); idx >= 0 {
+		return node.Content[idx+1]
+	}
+	return nil
+}
+
+// pickKeys 返回只包含指定键的映射节点
+func pickKeys(node *yaml.Node, keys ...string) *yaml.Node {
+	result := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Line: node.Line, Column: node.Column}
+	for _, key := range keys {
+		if idx := mappingIndex(node, key); idx >= 0 {
+			result.Content = append(result.Content, node.Content[idx], node.Content[idx+1])
+		}
+	}
+	return result
+}
+
+// dropKeys 返回去掉指定键的映射节点
+func dropKeys(node *yaml.Node, keys ...string) *yaml.Node {
+	result := *node
+	result.Content = nil
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if !containsString(keys, node.Content[i].Value) {
+			result.Content = append(result.Content, node.Content[i], node.Content[i+1])
+		}
+	}
+	return &result
+}
+
+func hasCompositionKeys(root *yaml.Node) bool {
+	if mappingIndex(root, keyExtends) >= 0 || mappingIndex(root, keyInclude) >= 0 || mappingIndex(root, keyLibrary) >= 0 {
+		return true
+	}
+	if indicators := mappingValue(root, keyIndicators); indicators != nil {
+		for _, item := range indicators.Content {
+			if mappingIndex(item, keyUse) >= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
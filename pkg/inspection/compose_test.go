@@ -0,0 +1,250 @@
+package inspection
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeFiles 在临时目录中写入一组文件，返回目录路径
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+const composeBaseYAML = `
+name: base
+display_name: 基础模板
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: App
+    type: string
+    default_value: node-exporter
+indicators:
+  - name: up
+    source: prometheus
+    exporter: node_exporter
+    type: point
+    query: up{app="{{.App}}"}
+    display: { type: table }
+report_layout:
+  sections:
+    - title: 基础状态
+      Indicators: [up]
+`
+
+const composeVarsYAML = `
+vars:
+  - name: DataCenterID
+    type: string
+    default_value: dc-1
+  - name: TimeRange
+    type: string
+    default_value: 1h
+`
+
+const composeLibraryYAML = `
+library:
+  - name: gpu-util
+    source: prometheus
+    exporter: gpu_exporter
+    type: range
+    query: avg(nvidia_smi_utilization_gpu_ratio{data_center_id="{{.DataCenterID}}"}[{{.TimeRange}}])
+    thresholds:
+      - { level: warning, value: 80, operator: gt, description: 负载较高 }
+    display: { type: table, unit: "%" }
+`
+
+func TestParseTemplateFile_Compose(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml":           composeBaseYAML,
+		"common/vars.yaml":    composeVarsYAML,
+		"common/library.yaml": composeLibraryYAML,
+		"gpu/child.yaml": `
+extends: ../base.yaml
+include:
+  - ../common/vars.yaml
+  - ../common/library.yaml
+name: child
+vars:
+  - name: App
+    default_value: gpu-exporter
+  - name: TimeRange
+    default_value: 6h
+indicators:
+  - use: gpu-util
+  - use: gpu-util
+    name: gpu-util-strict
+    thresholds:
+      - { level: critical, value: 95, operator: gt, description: 负载过高 }
+`,
+	})
+
+	tpl, err := ParseTemplateFile(filepath.Join(dir, "gpu/child.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tpl.Name != "child" || tpl.DisplayName != "基础模板" {
+		t.Errorf("name = %q, display_name = %q", tpl.Name, tpl.DisplayName)
+	}
+
+	vars := make(map[string]string)
+	var varNames []string
+	for _, v := range tpl.Vars {
+		vars[v.Name] = string(v.DefaultValue)
+		varNames = append(varNames, v.Name)
+	}
+	if want := []string{"App", "DataCenterID", "TimeRange"}; !reflect.DeepEqual(varNames, want) {
+		t.Errorf("vars = %v, want %v", varNames, want)
+	}
+	if vars["App"] != "gpu-exporter" || vars["TimeRange"] != "6h" || vars["DataCenterID"] != "dc-1" {
+		t.Errorf("unexpected var defaults: %v", vars)
+	}
+	// 覆盖变量时未填写的字段保留父模板中的定义
+	if tpl.Vars[0].Type != "string" {
+		t.Errorf("App.type = %q, want string", tpl.Vars[0].Type)
+	}
+
+	var names []string
+	for _, ind := range tpl.Indicators {
+		names = append(names, ind.Name)
+	}
+	if want := []string{"up", "gpu-util", "gpu-util-strict"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("indicators = %v, want %v", names, want)
+	}
+
+	util, strict := tpl.Indicators[1], tpl.Indicators[2]
+	if util.Use != "gpu-util" || util.Exporter != "gpu_exporter" || util.Display.Unit != "%" {
+		t.Errorf("library indicator not expanded: %+v", util)
+	}
+	if len(util.Thresholds) != 1 || util.Thresholds[0].Level != ThresholdLevelWarning {
+		t.Errorf("gpu-util thresholds = %+v", util.Thresholds)
+	}
+	// 序列整体替换，且不影响同一条目的其它引用
+	if len(strict.Thresholds) != 1 || strict.Thresholds[0].Level != ThresholdLevelCritical {
+		t.Errorf("gpu-util-strict thresholds = %+v", strict.Thresholds)
+	}
+	if strict.Query != util.Query {
+		t.Errorf("gpu-util-strict query = %v, want %v", strict.Query, util.Query)
+	}
+
+	query, err := tpl.RenderQueryWithVars(strict, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(query, "[6h]") {
+		t.Errorf("rendered query = %q, want time range 6h", query)
+	}
+}
+
+func TestParseTemplateBytes_ComposeBaseDir(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"base.yaml": composeBaseYAML})
+
+	tpl, err := ParseTemplateBytes([]byte("extends: base.yaml\nname: from-bytes\n"), WithBaseDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tpl.Name != "from-bytes" || len(tpl.Indicators) != 1 {
+		t.Errorf("unexpected template: %+v", tpl)
+	}
+}
+
+func TestParseTemplateFile_ComposeErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.yaml":       "extends: b.yaml\n",
+		"b.yaml":       "extends: a.yaml\n",
+		"self.yaml":    "include: [self.yaml]\n",
+		"missing.yaml": "extends: nope.yaml\n",
+		"unknown-use.yaml": `
+extends: base.yaml
+indicators:
+  - use: nope
+`,
+		"base.yaml": composeBaseYAML,
+	})
+
+	cases := []struct {
+		file string
+		want string
+	}{
+		{"a.yaml", "template composition cycle: " + filepath.Join(dir, "a.yaml") + " -> " + filepath.Join(dir, "b.yaml") + " -> " + filepath.Join(dir, "a.yaml")},
+		{"self.yaml", "template composition cycle"},
+		{"missing.yaml", "read nope.yaml"},
+		{"unknown-use.yaml", `unknown-use.yaml:4:10: indicator library entry "nope" not found`},
+	}
+	for _, c := range cases {
+		_, err := ParseTemplateFile(filepath.Join(dir, c.file))
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want containing %q", c.file, err, c.want)
+		}
+	}
+}
+
+func TestParseTemplateFile_ComposeValidationFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"base.yaml": composeBaseYAML,
+		"library.yaml": `
+library:
+  - name: broken
+    source: prometheus
+    exporter: gpu_exporter
+    type: pie
+    query: up
+    display: { type: table }
+`,
+		"child.yaml": `
+extends: base.yaml
+include: [library.yaml]
+indicators:
+  - use: broken
+`,
+	})
+
+	_, err := ParseTemplateFile(filepath.Join(dir, "child.yaml"))
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("err = %v, want one validation error", err)
+	}
+	e := errs[0]
+	if e.Path != "indicators[1].type" || e.File != filepath.Join(dir, "library.yaml") || e.Line != 6 {
+		t.Errorf("unexpected error: %+v", e)
+	}
+}
+
+func TestParseTemplateFile_GPUNodeUsesLibrary(t *testing.T) {
+	tpl, err := ParseTemplateFile("template/gpu/gpu-node.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, ind := range tpl.Indicators {
+		names = append(names, ind.Name)
+	}
+	want := []string{"节点存活状态", "节点存活状态2", "节点平均GPU算力利用率", "节点内最高GPU温度", "节点平均GPU温度"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("indicators = %v, want %v", names, want)
+	}
+	if len(tpl.Vars) != 4 {
+		t.Errorf("vars = %d, want 4 (from includes)", len(tpl.Vars))
+	}
+}
@@ -36,11 +36,24 @@ const (
 	SourcePrometheus    = "prometheus"
 	SourceElasticsearch = "elasticsearch"
 	SourceMetadata      = "metadata"
+	SourceTargets       = "targets" // 内置：Prometheus 的抓取目标状态，见 targets.go
 )
 
+// source: targets 指标的查询（query 字段）取值
+const (
+	TargetsQueryHealth         = "health"          // 抓取健康：up 为 1，down 为 0，unknown 记为缺失
+	TargetsQueryScrapeDuration = "scrape_duration" // 最近一次抓取耗时（秒）
+	TargetsQueryStaleness      = "staleness"       // 距最近一次抓取的时间（秒）
+	TargetsQueryDropped        = "dropped"         // 每个 scrapePool 被丢弃的目标数
+)
+
+// AllPools source: targets 指标的 exporter 取该值时检查所有 scrapePool
+const AllPools = "*"
+
 // 指标类型常量
 const (
 	IndicatorTypePoint     = "point"
+	IndicatorTypeRange     = "range"
 	IndicatorTypeTrend     = "trend"
 	IndicatorTypeAlertList = "alert_list"
 )
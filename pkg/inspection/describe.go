@@ -0,0 +1,227 @@
+package inspection
+
+import (
+	"fmt"
+	"sort"
+)
+
+// 变量作用域
+const (
+	VarScopeGlobal    = "global"    // 模板全局变量
+	VarScopeIndicator = "indicator" // 指标变量，覆盖同名的全局变量
+	VarScopeBuiltin   = "builtin"   // 内置变量，由模板配置提供（TimeRange、DataCenterID）
+)
+
+// 内置变量名，见 initBaseContext
+const (
+	VarTimeRange    = "TimeRange"
+	VarDataCenterID = "DataCenterID"
+)
+
+// VarDescription 变量描述，供 UI 在执行模板前渲染输入表单
+type VarDescription struct {
+	Name        string   `json:"name"`
+	Scope       string   `json:"scope"`               // global / indicator / builtin
+	Indicator   string   `json:"indicator,omitempty"` // 指标变量所属的指标
+	Type        string   `json:"type"`
+	ElemType    string   `json:"elem_type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Required    bool     `json:"required"`
+	EnumValues  []string `json:"enum_values,omitempty"`
+	// Default 未输入时使用的原始值（value 优先于 default_value），可能包含模板
+	Default string `json:"default,omitempty"`
+	// Templated Default 是否为模板，为 true 时最终值依赖其它变量，见 ResolveVars
+	Templated bool `json:"templated"`
+	// Overridable 用户输入是否生效：已声明的变量均可被输入覆盖，
+	// 内置变量只有在被声明为变量时才可覆盖
+	Overridable bool `json:"overridable"`
+	// UsedBy 实际使用该变量的指标（查询直接引用，或经由其它变量间接引用）
+	UsedBy []string `json:"used_by,omitempty"`
+}
+
+// DescribeVars 返回模板中的所有变量：内置变量、全局变量、各指标的变量（按声明顺序）
+func (tpl *Template) DescribeVars() []VarDescription {
+	usage := tpl.varUsage()
+	declared := make(map[string]bool)
+	for _, v := range tpl.Vars {
+		declared[v.Name] = true
+	}
+	for _, ind := range tpl.Indicators {
+		for _, v := range ind.Vars {
+			declared[v.Name] = true
+		}
+	}
+
+	var result []VarDescription
+	builtins := []struct{ name, typ, value, desc string }{
+		{VarTimeRange, "duration", tpl.TimeRange, "查询时间窗口，未声明同名变量时取指标 time_range，其次模板 time_range"},
+		{VarDataCenterID, "string", tpl.DataCenter.ID, "数据中心ID，取自模板 data_center.id"},
+	}
+	for _, b := range builtins {
+		result = append(result, VarDescription{
+			Name:        b.name,
+			Scope:       VarScopeBuiltin,
+			Type:        b.typ,
+			Description: b.desc,
+			Default:     b.value,
+			Overridable: declared[b.name],
+			UsedBy:      usage[varKey{scope: VarScopeBuiltin, name: b.name}],
+		})
+	}
+
+	for _, v := range tpl.Vars {
+		result = append(result, describeVar(v, VarScopeGlobal, "", usage[varKey{scope: VarScopeGlobal, name: v.Name}]))
+	}
+	for _, ind := range tpl.Indicators {
+		for _, v := range ind.Vars {
+			key := varKey{scope: VarScopeIndicator, indicator: ind.Name, name: v.Name}
+			result = append(result, describeVar(v, VarScopeIndicator, ind.Name, usage[key]))
+		}
+	}
+	return result
+}
+
+func describeVar(v Variable, scope, indicator string, usedBy []string) VarDescription {
+	raw := pickRaw(v, nil)
+	return VarDescription{
+		Name:        v.Name,
+		Scope:       scope,
+		Indicator:   indicator,
+		Type:        v.Type,
+		ElemType:    v.ElemType,
+		Description: v.Description,
+		Required:    v.Required,
+		EnumValues:  v.EnumValues,
+		Default:     raw,
+		Templated:   containsTpl(raw),
+		Overridable: true,
+		UsedBy:      usedBy,
+	}
+}
+
+// varKey 标识一个变量定义
+type varKey struct {
+	scope     string
+	indicator string
+	name      string
+}
+
+// varUsage 计算每个变量定义被哪些指标使用
+//
+// 从指标查询引用的字段出发，沿变量值中的引用查找：同名时指标变量优先于全局变量，
+// 全局变量优先于内置变量；全局变量值中的引用不会指向指标变量；
+// 变量引用自身时继续查找外层的同名定义。
+func (tpl *Template) varUsage() map[varKey][]string {
+	usage := make(map[varKey][]string)
+	for _, ind := range tpl.Indicators {
+		query, ok := ind.Query.(string)
+		if !ok {
+			continue
+		}
+		refs, err := templateRefs(ind.Name, query)
+		if err != nil {
+			continue
+		}
+
+		used := make(map[varKey]bool)
+		var visit func(name string, level int)
+		// level 表示从哪一层开始查找：0 指标变量，1 全局变量，2 内置变量
+		visit = func(name string, level int) {
+			key, v, ok := tpl.lookupVar(ind, name, level)
+			if !ok || used[key] {
+				return
+			}
+			used[key] = true
+			if v == nil {
+				return
+			}
+			raw := pickRaw(*v, nil)
+			if !containsTpl(raw) {
+				return
+			}
+			refs, err := templateRefs(v.Name, raw)
+			if err != nil {
+				return
+			}
+			// 全局变量在解析全局变量时求值，其引用从全局变量开始查找
+			for _, ref := range refs {
+				if ref == name {
+					visit(ref, scopeLevel(key.scope)+1)
+				} else {
+					visit(ref, scopeLevel(key.scope))
+				}
+			}
+		}
+		for _, ref := range refs {
+			visit(ref, 0)
+		}
+
+		for key := range used {
+			usage[key] = append(usage[key], ind.Name)
+		}
+	}
+	for key := range usage {
+		sort.Strings(usage[key])
+	}
+	return usage
+}
+
+func scopeLevel(scope string) int {
+	switch scope {
+	case VarScopeIndicator:
+		return 0
+	case VarScopeGlobal:
+		return 1
+	}
+	return 2
+}
+
+// lookupVar 从 level 层开始查找变量定义，内置变量返回的 Variable 为 nil
+func (tpl *Template) lookupVar(ind *Indicator, name string, level int) (varKey, *Variable, bool) {
+	if level <= 0 {
+		for i := range ind.Vars {
+			if ind.Vars[i].Name == name {
+				return varKey{scope: VarScopeIndicator, indicator: ind.Name, name: name}, &ind.Vars[i], true
+			}
+		}
+	}
+	if level <= 1 {
+		for i := range tpl.Vars {
+			if tpl.Vars[i].Name == name {
+				return varKey{scope: VarScopeGlobal, name: name}, &tpl.Vars[i], true
+			}
+		}
+	}
+	if name == VarTimeRange || name == VarDataCenterID {
+		return varKey{scope: VarScopeBuiltin, name: name}, nil, true
+	}
+	return varKey{}, nil, false
+}
+
+// ResolvedVars 变量解析结果
+type ResolvedVars struct {
+	// Global 只解析全局变量时的结果（不含指标相关的上下文）
+	Global map[string]any `json:"global"`
+	// Indicators 每个指标渲染查询时使用的完整上下文，key 为指标名
+	Indicators map[string]map[string]any `json:"indicators"`
+}
+
+// ResolveVars 按与 RenderQueryWithVars 相同的规则解析变量，返回最终值，不渲染、不执行查询
+func (tpl *Template) ResolveVars(input map[string]string) (*ResolvedVars, error) {
+	global := tpl.initBaseContext(&Indicator{})
+	if err := (globalVarProcessor{tpl: tpl, input: input}).processVars(global); err != nil {
+		return nil, err
+	}
+	result := &ResolvedVars{
+		Global:     global,
+		Indicators: make(map[string]map[string]any, len(tpl.Indicators)),
+	}
+	for _, ind := range tpl.Indicators {
+		values, err := tpl.resolveIndicatorVars(ind, input)
+		if err != nil {
+			return nil, fmt.Errorf("indicator %s: %w", ind.Name, err)
+		}
+		result.Indicators[ind.Name] = values
+	}
+	return result, nil
+}
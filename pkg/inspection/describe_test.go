@@ -0,0 +1,119 @@
+package inspection
+
+import (
+	"reflect"
+	"testing"
+)
+
+const describeTemplateYAML = `
+name: describe-test
+display_name: 变量描述
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+data_center: { id: dc-1 }
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: DataCenterID
+    type: string
+    required: true
+    default_value: "{{.DataCenterID}}"
+  - name: Pattern
+    type: string
+    default_value: gpu-.*
+  - name: NodeSelector
+    type: string
+    value: "{{.Pattern}}"
+  - name: Unused
+    type: enum
+    enum_values: [a, b]
+    default_value: a
+indicators:
+  - name: up
+    source: prometheus
+    exporter: node_exporter
+    type: point
+    query: up{instance=~"{{.NodeSelector}}", dc="{{.DataCenterID}}"}
+    display: { type: table }
+  - name: util
+    source: prometheus
+    exporter: gpu_exporter
+    type: range
+    time_range: 6h
+    query: avg_over_time(util{instance=~"{{.NodeSelector}}", node=~"{{.Pattern}}"}[{{.TimeRange}}])
+    vars:
+      - name: Pattern
+        type: string
+        default_value: "{{.Pattern}}|node-.*"
+    display: { type: table }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [up, util]
+`
+
+func TestTemplate_DescribeVars(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(describeTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	descs := tpl.DescribeVars()
+
+	type summary struct {
+		scope, indicator, name string
+		overridable, templated bool
+		usedBy                 []string
+	}
+	var got []summary
+	for _, d := range descs {
+		got = append(got, summary{d.Scope, d.Indicator, d.Name, d.Overridable, d.Templated, d.UsedBy})
+	}
+	want := []summary{
+		{VarScopeBuiltin, "", VarTimeRange, false, false, []string{"util"}},
+		{VarScopeBuiltin, "", VarDataCenterID, true, false, []string{"up"}},
+		{VarScopeGlobal, "", "DataCenterID", true, true, []string{"up"}},
+		{VarScopeGlobal, "", "Pattern", true, false, []string{"up", "util"}},
+		{VarScopeGlobal, "", "NodeSelector", true, true, []string{"up", "util"}},
+		{VarScopeGlobal, "", "Unused", true, false, nil},
+		{VarScopeIndicator, "util", "Pattern", true, true, []string{"util"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DescribeVars:\n got  %+v\n want %+v", got, want)
+	}
+
+	unused := descs[5]
+	if unused.Type != "enum" || !reflect.DeepEqual(unused.EnumValues, []string{"a", "b"}) || unused.Default != "a" {
+		t.Errorf("unexpected description: %+v", unused)
+	}
+	if descs[0].Default != "1h" || descs[1].Default != "dc-1" {
+		t.Errorf("unexpected builtin defaults: %+v %+v", descs[0], descs[1])
+	}
+}
+
+func TestTemplate_ResolveVars(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(describeTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resolved, err := tpl.ResolveVars(map[string]string{"Unused": "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resolved.Global["NodeSelector"] != "gpu-.*" || resolved.Global["Unused"] != "b" || resolved.Global["DataCenterID"] != "dc-1" {
+		t.Errorf("unexpected global values: %v", resolved.Global)
+	}
+	util := resolved.Indicators["util"]
+	// NodeSelector 是全局变量，按全局的 Pattern 解析；指标的 Pattern 只影响指标自身
+	if util["Pattern"] != "gpu-.*|node-.*" || util["NodeSelector"] != "gpu-.*" || util[VarTimeRange] != "6h" {
+		t.Errorf("unexpected util values: %v", util)
+	}
+
+	tpl.Vars[3].EnumValues = []string{"a"}
+	if _, err := tpl.ResolveVars(map[string]string{"Unused": "b"}); err == nil {
+		t.Error("expected invalid enum error")
+	}
+}
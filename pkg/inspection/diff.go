@@ -0,0 +1,216 @@
+package inspection
+
+import (
+	"fmt"
+	"time"
+)
+
+// 目标变化类型
+const (
+	ChangeWorsened   = "worsened"   // 状态加重，如 ok→warning、warning→critical
+	ChangeImproved   = "improved"   // 状态减轻但仍异常，如 critical→warning
+	ChangeRecovered  = "recovered"  // 异常恢复为 ok / info
+	ChangeMissing    = "missing"    // 有值变为缺失
+	ChangeReappeared = "reappeared" // 缺失恢复为有值
+	ChangeAdded      = "added"      // 上次报告中没有的目标
+	ChangeRemoved    = "removed"    // 本次报告中没有的目标
+	ChangeValue      = "value"      // 状态不变，只有值变化
+)
+
+// ReportDiff 同一模板两次报告的差异
+type ReportDiff struct {
+	Template   string           `json:"template"`
+	BaseAt     time.Time        `json:"base_at"`    // 上次报告的执行时间
+	CurrentAt  time.Time        `json:"current_at"` // 本次报告的执行时间
+	NewIssues  []Issue          `json:"new_issues"` // 新出现或加重的异常
+	Resolved   []Issue          `json:"resolved"`   // 已恢复的异常
+	Indicators []*IndicatorDiff `json:"indicators"`
+	// AddedIndicators、RemovedIndicators 只在一次报告中出现的指标
+	AddedIndicators   []string `json:"added_indicators,omitempty"`
+	RemovedIndicators []string `json:"removed_indicators,omitempty"`
+}
+
+// IndicatorDiff 单个指标的差异，只包含有变化的目标
+type IndicatorDiff struct {
+	Indicator string         `json:"indicator"`
+	Unit      string         `json:"unit"`
+	Changes   []TargetChange `json:"changes"`
+}
+
+// TargetChange 单个目标的变化
+type TargetChange struct {
+	Target         string   `json:"target"`
+	Change         string   `json:"change"`
+	PreviousStatus string   `json:"previous_status,omitempty"`
+	Status         string   `json:"status,omitempty"`
+	PreviousValue  *float64 `json:"previous_value,omitempty"`
+	Value          *float64 `json:"value,omitempty"`
+	// Delta 两次都有值时为 Value-PreviousValue
+	Delta *float64 `json:"delta,omitempty"`
+}
+
+// Issue 新增或恢复的异常，用于报告中的“新增问题 / 已解决问题”部分
+type Issue struct {
+	Indicator string `json:"indicator"`
+	TargetChange
+}
+
+// DiffReports 比较同一模板的两次报告，base 为较早的一次
+//
+// 指标按名称、目标按 Target 对应。warning、critical 与缺失视为异常：
+// 变为异常或异常加重计入 NewIssues，异常变为 ok / info 或缺失恢复计入 Resolved。
+func DiffReports(base, current *Report) (*ReportDiff, error) {
+	if base.Template.Name != current.Template.Name {
+		return nil, fmt.Errorf("cannot diff reports of different templates %q and %q", base.Template.Name, current.Template.Name)
+	}
+	diff := &ReportDiff{
+		Template:   current.Template.Name,
+		BaseAt:     base.Template.ExecutedAt,
+		CurrentAt:  current.Template.ExecutedAt,
+		NewIssues:  []Issue{},
+		Resolved:   []Issue{},
+		Indicators: []*IndicatorDiff{},
+	}
+
+	baseResults := make(map[string]*IndicatorResult, len(base.Results))
+	for _, r := range base.Results {
+		baseResults[r.Indicator] = r
+	}
+	seen := make(map[string]bool, len(current.Results))
+	for _, cur := range current.Results {
+		seen[cur.Indicator] = true
+		prev, ok := baseResults[cur.Indicator]
+		if !ok {
+			diff.AddedIndicators = append(diff.AddedIndicators, cur.Indicator)
+			continue
+		}
+		ind := diffIndicator(prev, cur)
+		if len(ind.Changes) == 0 {
+			continue
+		}
+		diff.Indicators = append(diff.Indicators, ind)
+		for _, c := range ind.Changes {
+			switch {
+			case c.isNewIssue():
+				diff.NewIssues = append(diff.NewIssues, Issue{Indicator: ind.Indicator, TargetChange: c})
+			case c.isResolved():
+				diff.Resolved = append(diff.Resolved, Issue{Indicator: ind.Indicator, TargetChange: c})
+			}
+		}
+	}
+	for _, r := range base.Results {
+		if !seen[r.Indicator] {
+			diff.RemovedIndicators = append(diff.RemovedIndicators, r.Indicator)
+		}
+	}
+	return diff, nil
+}
+
+// diffIndicator 比较同一指标两次结果中的目标，目标按本次结果的顺序，消失的目标排在最后
+func diffIndicator(prev, cur *IndicatorResult) *IndicatorDiff {
+	ind := &IndicatorDiff{Indicator: cur.Indicator, Unit: cur.Unit, Changes: []TargetChange{}}
+	prevItems := itemsByTarget(prev.Values)
+	seen := make(map[string]bool, len(cur.Values))
+	for _, item := range cur.Values {
+		if seen[item.Target] {
+			continue
+		}
+		seen[item.Target] = true
+		c := TargetChange{Target: item.Target, Status: itemStatus(item), Value: item.Value}
+		p, ok := prevItems[item.Target]
+		if !ok {
+			c.Change = ChangeAdded
+			ind.Changes = append(ind.Changes, c)
+			continue
+		}
+		c.PreviousStatus, c.PreviousValue = itemStatus(p), p.Value
+		if c.Value != nil && c.PreviousValue != nil {
+			delta := *c.Value - *c.PreviousValue
+			c.Delta = &delta
+		}
+		if c.Change = statusChange(c.PreviousStatus, c.Status); c.Change == "" {
+			if c.Delta == nil || *c.Delta == 0 {
+				continue
+			}
+			c.Change = ChangeValue
+		}
+		ind.Changes = append(ind.Changes, c)
+	}
+	for _, p := range prev.Values {
+		if seen[p.Target] {
+			continue
+		}
+		seen[p.Target] = true
+		ind.Changes = append(ind.Changes, TargetChange{
+			Target: p.Target, Change: ChangeRemoved, PreviousStatus: itemStatus(p), PreviousValue: p.Value,
+		})
+	}
+	return ind
+}
+
+// statusChange 状态变化的类型，状态相同时返回空
+func statusChange(prev, cur string) string {
+	switch {
+	case prev == cur:
+		return ""
+	case cur == StatusMissing:
+		return ChangeMissing
+	case prev == StatusMissing:
+		return ChangeReappeared
+	case isIssueStatus(prev) && !isIssueStatus(cur):
+		return ChangeRecovered
+	case ThresholdLevelPriorities[cur] < ThresholdLevelPriorities[prev]:
+		return ChangeWorsened
+	default:
+		return ChangeImproved
+	}
+}
+
+// isNewIssue 变为异常或异常加重；ok→info 虽然加重，但 info 不是异常
+func (c TargetChange) isNewIssue() bool {
+	switch c.Change {
+	case ChangeMissing:
+		return true
+	case ChangeWorsened, ChangeAdded, ChangeReappeared:
+		return isIssueStatus(c.Status)
+	}
+	return false
+}
+
+// isResolved 异常恢复
+func (c TargetChange) isResolved() bool {
+	switch c.Change {
+	case ChangeRecovered:
+		return true
+	case ChangeReappeared:
+		return !isIssueStatus(c.Status)
+	}
+	return false
+}
+
+// isIssueStatus warning、critical 与缺失视为异常
+func isIssueStatus(status string) bool {
+	return status == ThresholdLevelWarning || status == ThresholdLevelCritical || status == StatusMissing
+}
+
+// itemStatus 数据项的状态，缺失时为 StatusMissing，未设置时为 ok
+func itemStatus(item ValueItem) string {
+	switch {
+	case item.Missing:
+		return StatusMissing
+	case item.Status == "":
+		return ThresholdLevelOk
+	}
+	return item.Status
+}
+
+// itemsByTarget 按 Target 索引数据项，同一目标有多项时取第一项
+func itemsByTarget(items []ValueItem) map[string]ValueItem {
+	m := make(map[string]ValueItem, len(items))
+	for _, item := range items {
+		if _, ok := m[item.Target]; !ok {
+			m[item.Target] = item
+		}
+	}
+	return m
+}
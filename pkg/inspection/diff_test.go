@@ -0,0 +1,125 @@
+package inspection
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// diffItem 测试用数据项，status 为 StatusMissing 时记为缺失
+func diffItem(target string, value float64, status string) ValueItem {
+	if status == StatusMissing {
+		return ValueItem{Target: target, Missing: true}
+	}
+	return ValueItem{Target: target, Value: &value, Status: status}
+}
+
+func diffReport(executedAt time.Time, results ...*IndicatorResult) *Report {
+	r := &Report{Results: results}
+	r.Template.Name = "daily-gpu-inspection"
+	r.Template.ExecutedAt = executedAt
+	return r
+}
+
+func TestDiffReports(t *testing.T) {
+	day := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	base := diffReport(day,
+		&IndicatorResult{Indicator: "GPU 使用率", Unit: "%", Values: []ValueItem{
+			diffItem("a", 50, ThresholdLevelOk),
+			diffItem("b", 85, ThresholdLevelWarning),
+			diffItem("c", 99, ThresholdLevelCritical),
+			diffItem("d", 0, StatusMissing),
+			diffItem("e", 60, ThresholdLevelOk),
+			diffItem("f", 10, ThresholdLevelOk),
+			diffItem("g", 70, ThresholdLevelOk),
+			diffItem("h", 60, ThresholdLevelOk),
+			diffItem("i", 75, ThresholdLevelInfo),
+			diffItem("removed", 99, ThresholdLevelCritical),
+		}},
+		&IndicatorResult{Indicator: "GPU 温度", Values: []ValueItem{diffItem("a", 60, ThresholdLevelOk)}},
+		&IndicatorResult{Indicator: "旧指标"},
+	)
+	current := diffReport(day.AddDate(0, 0, 1),
+		&IndicatorResult{Indicator: "GPU 使用率", Unit: "%", Values: []ValueItem{
+			diffItem("a", 90, ThresholdLevelWarning),  // ok→warning
+			diffItem("b", 99, ThresholdLevelCritical), // warning→critical
+			diffItem("c", 50, ThresholdLevelOk),       // critical→ok
+			diffItem("d", 40, ThresholdLevelOk),       // 缺失恢复
+			diffItem("e", 0, StatusMissing),           // 变为缺失
+			diffItem("f", 12.5, ThresholdLevelOk),     // 只有值变化
+			diffItem("g", 70, ThresholdLevelOk),       // 无变化
+			diffItem("h", 75, ThresholdLevelInfo),     // ok→info，加重但不是异常
+			diffItem("i", 85, ThresholdLevelWarning),  // info→warning
+			diffItem("added", 95, ThresholdLevelCritical),
+		}},
+		&IndicatorResult{Indicator: "GPU 温度", Values: []ValueItem{diffItem("a", 60, ThresholdLevelOk)}},
+		&IndicatorResult{Indicator: "新指标"},
+	)
+
+	diff, err := DiffReports(base, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.BaseAt.Equal(day) || !diff.CurrentAt.Equal(day.AddDate(0, 0, 1)) {
+		t.Errorf("got base %s, current %s", diff.BaseAt, diff.CurrentAt)
+	}
+	if len(diff.Indicators) != 1 {
+		t.Fatalf("got %d changed indicators, want 1 (GPU 温度 unchanged)", len(diff.Indicators))
+	}
+
+	want := map[string]string{
+		"a":       ChangeWorsened,
+		"b":       ChangeWorsened,
+		"c":       ChangeRecovered,
+		"d":       ChangeReappeared,
+		"e":       ChangeMissing,
+		"f":       ChangeValue,
+		"h":       ChangeWorsened,
+		"i":       ChangeWorsened,
+		"added":   ChangeAdded,
+		"removed": ChangeRemoved,
+	}
+	got := make(map[string]TargetChange)
+	for _, c := range diff.Indicators[0].Changes {
+		got[c.Target] = c
+	}
+	if len(got) != len(want) {
+		t.Errorf("got changes for %d targets, want %d", len(got), len(want))
+	}
+	for target, change := range want {
+		if got[target].Change != change {
+			t.Errorf("%s: change %q, want %q", target, got[target].Change, change)
+		}
+	}
+	if f := got["f"]; f.Delta == nil || *f.Delta != 2.5 {
+		t.Errorf("f: delta %v, want 2.5", f.Delta)
+	}
+	if e := got["e"]; e.Delta != nil || *e.PreviousValue != 60 {
+		t.Errorf("e: delta %v, previous value %v; want no delta, previous 60", e.Delta, e.PreviousValue)
+	}
+
+	targets := func(issues []Issue) []string {
+		var result []string
+		for _, issue := range issues {
+			result = append(result, issue.Target)
+		}
+		return result
+	}
+	if got, want := targets(diff.NewIssues), []string{"a", "b", "e", "i", "added"}; !slices.Equal(got, want) {
+		t.Errorf("new issues: got %v, want %v", got, want)
+	}
+	if got, want := targets(diff.Resolved), []string{"c", "d"}; !slices.Equal(got, want) {
+		t.Errorf("resolved: got %v, want %v", got, want)
+	}
+	if !slices.Equal(diff.AddedIndicators, []string{"新指标"}) || !slices.Equal(diff.RemovedIndicators, []string{"旧指标"}) {
+		t.Errorf("added %v, removed %v", diff.AddedIndicators, diff.RemovedIndicators)
+	}
+}
+
+func TestDiffReports_DifferentTemplates(t *testing.T) {
+	other := diffReport(time.Now())
+	other.Template.Name = "other"
+	if _, err := DiffReports(diffReport(time.Now()), other); err == nil {
+		t.Error("expected error for reports of different templates")
+	}
+}
@@ -0,0 +1,117 @@
+package inspection
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// DryRunResult 试运行结果：每个启用指标渲染后的查询及执行参数，不访问数据源
+type DryRunResult struct {
+	Template    string                `json:"template"`
+	DisplayName string                `json:"display_name"`
+	Indicators  []*DryRunIndicator    `json:"indicators"`
+	Skipped     []string              `json:"skipped,omitempty"` // 未启用的指标
+	Vars        map[string]any        `json:"vars"`              // 全局变量的最终值
+	Errors      []*DryRunIndicatorErr `json:"errors,omitempty"`
+}
+
+// DryRunIndicator 单个指标的试运行结果
+type DryRunIndicator struct {
+	Name         string       `json:"name"`
+	Source       string       `json:"source"`
+	Type         string       `json:"type"`
+	ExporterPool string       `json:"exporter_pool"`        // 用于判断缺失目标的 scrape pool
+	Query        string       `json:"query"`                // 渲染后的查询
+	TimeRange    string       `json:"time_range"`           // 实际生效的时间范围（TimeRange 变量的最终值）
+	Resolution   string       `json:"resolution,omitempty"` // 范围查询的步长，瞬时查询为空
+	Thresholds   []*Threshold `json:"thresholds,omitempty"`
+}
+
+// DryRunIndicatorErr 指标渲染失败的原因
+type DryRunIndicatorErr struct {
+	Indicator string `json:"indicator"`
+	Message   string `json:"message"`
+	Err       error  `json:"-"`
+}
+
+func (e *DryRunIndicatorErr) Error() string {
+	return fmt.Sprintf("indicator %q: %s", e.Indicator, e.Message)
+}
+
+func (e *DryRunIndicatorErr) Unwrap() error { return e.Err }
+
+// DryRun 解析变量并渲染所有启用指标的查询，不执行查询
+//
+// 所有指标都会被渲染：任一指标失败时返回包含成功部分的结果，
+// 以及汇总了每个失败指标原因的错误（可用 errors.As 取得 *DryRunIndicatorErr 或 *PromQLSyntaxError）。
+func DryRun(tpl *Template, input map[string]string) (*DryRunResult, error) {
+	result := &DryRunResult{
+		Template:    tpl.Name,
+		DisplayName: tpl.DisplayName,
+	}
+
+	// 全局变量出错时所有指标都无法渲染，直接返回
+	global := tpl.initBaseContext(&Indicator{})
+	if err := (globalVarProcessor{tpl: tpl, input: input}).processVars(global); err != nil {
+		return result, err
+	}
+	result.Vars = global
+
+	var errs []error
+	for _, ind := range tpl.Indicators {
+		if ind.Enabled != nil && !*ind.Enabled {
+			result.Skipped = append(result.Skipped, ind.Name)
+			continue
+		}
+		item, err := tpl.dryRunIndicator(ind, input)
+		if err != nil {
+			e := &DryRunIndicatorErr{Indicator: ind.Name, Message: err.Error(), Err: err}
+			result.Errors = append(result.Errors, e)
+			errs = append(errs, e)
+			continue
+		}
+		result.Indicators = append(result.Indicators, item)
+	}
+	return result, errors.Join(errs...)
+}
+
+func (tpl *Template) dryRunIndicator(ind *Indicator, input map[string]string) (*DryRunIndicator, error) {
+	qTemplate, ok := ind.Query.(string)
+	if !ok {
+		return nil, fmt.Errorf("indicator query must be string template")
+	}
+	values, err := tpl.resolveIndicatorVars(ind, input)
+	if err != nil {
+		return nil, err
+	}
+	query, err := tpl.renderQuery(qTemplate, values)
+	if err != nil {
+		return nil, fmt.Errorf("render query: %w", err)
+	}
+	if ind.Source == SourcePrometheus {
+		if _, err := parsePromQL(query); err != nil {
+			return nil, newPromQLSyntaxError(ind.Name, query, err)
+		}
+	}
+
+	item := &DryRunIndicator{
+		Name:         ind.Name,
+		Source:       ind.Source,
+		Type:         ind.Type,
+		ExporterPool: ind.Exporter,
+		Query:        query,
+		TimeRange:    fmt.Sprint(values[VarTimeRange]),
+		Thresholds:   ind.Thresholds,
+	}
+	if ind.Type == IndicatorTypeRange || ind.Type == IndicatorTypeTrend {
+		window := tpl.EffectiveTimeRange(ind)
+		if d, err := model.ParseDuration(item.TimeRange); err == nil {
+			window = time.Duration(d)
+		}
+		item.Resolution = model.Duration(rangeStep(ind, window)).String()
+	}
+	return item, nil
+}
@@ -0,0 +1,105 @@
+package inspection
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const dryRunTemplateYAML = `
+name: dryrun-test
+display_name: 试运行
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+data_center: { id: dc-1 }
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: Job
+    type: string
+    default_value: gpu
+indicators:
+  - name: up
+    source: prometheus
+    exporter: node_exporter
+    type: point
+    query: up{job="{{.Job}}", dc="{{.DataCenterID}}"}
+    thresholds:
+      - { level: critical, value: 0, operator: eq, description: 离线 }
+    display: { type: table }
+  - name: util
+    source: prometheus
+    exporter: gpu_exporter
+    type: range
+    time_range: 6h
+    resolution: 5m
+    query: avg_over_time(util{job="{{.Job}}"}[{{.TimeRange}}])
+    display: { type: table }
+  - name: disabled
+    enabled: false
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: "{{.Missing}}"
+    display: { type: table }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [up, util]
+`
+
+func TestDryRun(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(dryRunTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := DryRun(tpl, map[string]string{"Job": "dcgm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Indicators) != 2 || len(result.Skipped) != 1 || result.Skipped[0] != "disabled" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	up, util := result.Indicators[0], result.Indicators[1]
+	if up.Query != `up{job="dcgm", dc="dc-1"}` || up.TimeRange != "1h" || up.Resolution != "" || up.ExporterPool != "node_exporter" {
+		t.Errorf("unexpected up: %+v", up)
+	}
+	if len(up.Thresholds) != 1 || up.Thresholds[0].Level != ThresholdLevelCritical {
+		t.Errorf("unexpected up thresholds: %+v", up.Thresholds)
+	}
+	if util.Query != `avg_over_time(util{job="dcgm"}[6h])` || util.TimeRange != "6h" || util.Resolution != "5m" {
+		t.Errorf("unexpected util: %+v", util)
+	}
+	if result.Vars["Job"] != "dcgm" {
+		t.Errorf("unexpected vars: %v", result.Vars)
+	}
+}
+
+func TestDryRun_Errors(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(dryRunTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.Indicators[0].Query = `up{job="{{.Job}}"`
+	tpl.Indicators[1].Query = `avg_over_time(util{job="{{.Jbo}}"}[{{.TimeRange}}])`
+
+	result, err := DryRun(tpl, nil)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(result.Errors) != 2 || len(result.Indicators) != 0 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	var syntaxErr *PromQLSyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Indicator != "up" {
+		t.Errorf("expected syntax error for up, got %v", err)
+	}
+	if msg := result.Errors[1].Error(); !strings.Contains(msg, `indicator "util"`) || !strings.Contains(msg, "Jbo") {
+		t.Errorf("unexpected util error: %s", msg)
+	}
+}
@@ -0,0 +1,215 @@
+package inspection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+// Executor 执行模板：渲染并执行所有启用指标的查询，生成报告
+type Executor struct {
+	client     *prom.Client
+	targets    prom.TargetSource
+	now        func() time.Time
+	executedBy string
+	metrics    *Metrics
+	retries    int
+	backoff    time.Duration
+	history    HistoryFunc
+}
+
+// ExecutorOption 配置 Executor
+type ExecutorOption func(*Executor)
+
+// WithNow 设置执行时间的来源，默认 time.Now；回放时固定为录制时的执行时间
+func WithNow(now func() time.Time) ExecutorOption {
+	return func(e *Executor) {
+		e.now = now
+	}
+}
+
+// WithExecutedBy 设置报告中的执行人
+func WithExecutedBy(user string) ExecutorOption {
+	return func(e *Executor) {
+		e.executedBy = user
+	}
+}
+
+// WithMetrics 设置自监控指标，记录执行次数、耗时、失败与重试
+func WithMetrics(m *Metrics) ExecutorOption {
+	return func(e *Executor) {
+		e.metrics = m
+	}
+}
+
+// WithQueryRetries 设置查询失败后的重试次数与重试间隔，默认不重试；
+// 查询语法错误与回放中未录制的请求不会重试
+func WithQueryRetries(retries int, backoff time.Duration) ExecutorOption {
+	return func(e *Executor) {
+		e.retries = retries
+		e.backoff = backoff
+	}
+}
+
+// WithHistory 设置历史报告的来源：每个数据项的 Metadata 中写入连续次数、首次出现时间与状态变化次数，
+// 高亮条件可按 consecutive、flaps 过滤。默认不写入
+func WithHistory(history HistoryFunc) ExecutorOption {
+	return func(e *Executor) {
+		e.history = history
+	}
+}
+
+// NewExecutor 创建 Executor，targets 用于判断缺失的目标
+func NewExecutor(client *prom.Client, targets prom.TargetSource, opts ...ExecutorOption) *Executor {
+	e := &Executor{
+		client:  client,
+		targets: targets,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Execute 执行模板中所有启用的指标
+//
+// 单个指标失败不影响其它指标：返回包含成功指标的报告，以及汇总了失败原因的错误。
+func (e *Executor) Execute(ctx context.Context, tpl *Template, vars map[string]string) (report *Report, err error) {
+	now := e.now()
+	if scheduled, ok := scheduledAt(ctx); ok {
+		e.metrics.observeSchedulerLag(tpl.Name, now.Sub(scheduled))
+	}
+	start := time.Now()
+	defer func() {
+		e.metrics.observeRun(tpl.Name, time.Since(start), err)
+	}()
+
+	client := e.client.WithContext(ctx)
+	defer client.Close()
+
+	report = &Report{}
+	report.Template.Name = tpl.Name
+	report.Template.DisplayName = tpl.DisplayName
+	report.Template.ExecutedAt = now
+	report.Template.ExecutedBy = e.executedBy
+	report.Sections = tpl.ReportLayout.Sections
+
+	// source: targets 的指标共用一次 /api/v1/targets 的结果，成功后才缓存，失败时可随重试重新获取
+	var scraped *v1.TargetsResult
+	fetchTargets := func() (v1.TargetsResult, error) {
+		if scraped != nil {
+			return *scraped, nil
+		}
+		targets, err := client.Targets()
+		if err != nil {
+			return v1.TargetsResult{}, fmt.Errorf("fetch targets: %w", err)
+		}
+		scraped = &targets
+		return targets, nil
+	}
+
+	var errs []error
+	var history []*Report
+	useHistory := e.history != nil
+	if useHistory {
+		// 历史不可用时不影响本次执行，只是不写入状态历史
+		if history, err = e.history(ctx, tpl.Name, now); err != nil {
+			errs = append(errs, fmt.Errorf("load history: %w", err))
+			useHistory = false
+		}
+	}
+	for _, ind := range tpl.Indicators {
+		if ind.Enabled != nil && !*ind.Enabled {
+			continue
+		}
+		var hist *statusHistory
+		if useHistory {
+			hist = newStatusHistory(history, ind.Name, now)
+		}
+		indStart := time.Now()
+		result, err := e.executeIndicator(ctx, client, fetchTargets, hist, tpl, ind, vars, now)
+		e.metrics.observeIndicator(tpl.Name, ind.Name, time.Since(indStart), err)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("indicator %q: %w", ind.Name, err))
+			continue
+		}
+		report.Results = append(report.Results, result)
+		report.SummaryOverviews = append(report.SummaryOverviews, &SummaryOverview{
+			Indicator: ind.Name,
+			Unit:      result.Unit,
+			Total:     result.Summary.Total,
+			Ok:        result.Summary.Ok,
+			Info:      result.Summary.Info,
+			Warning:   result.Summary.Warning,
+			Critical:  result.Summary.Critical,
+			Missing:   result.Summary.Missing,
+		})
+	}
+	return report, errors.Join(errs...)
+}
+
+// executeIndicator 渲染并执行单个指标的查询：range / trend 类型使用范围查询，其余使用即时查询，
+// source: targets 的指标由 fetchTargets 获取抓取目标后计算；查询失败时按配置重试。history 为 nil 时不写入状态历史
+func (e *Executor) executeIndicator(ctx context.Context, client *prom.Client, fetchTargets func() (v1.TargetsResult, error), history *statusHistory, tpl *Template, ind *Indicator, vars map[string]string, now time.Time) (*IndicatorResult, error) {
+	query, err := tpl.RenderQueryWithVars(ind, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		if ind.Source == SourceTargets {
+			var targets v1.TargetsResult
+			if targets, err = fetchTargets(); err == nil {
+				return evalTargetsIndicator(ind, query, targets, history, now)
+			}
+		} else {
+			var result *IndicatorResult
+			if result, err = e.queryIndicator(client, history, tpl, ind, query, now); err == nil {
+				return result, nil
+			}
+		}
+		if attempt >= e.retries || !retryable(err) {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(e.backoff):
+		}
+		e.metrics.observeRetry(tpl.Name, ind.Name)
+	}
+}
+
+// queryIndicator 执行一次指标查询并生成结果
+func (e *Executor) queryIndicator(client *prom.Client, history *statusHistory, tpl *Template, ind *Indicator, query string, now time.Time) (*IndicatorResult, error) {
+	// 每次尝试使用新的 handler，避免失败的尝试残留结果
+	jsonHandler, resultHandler := NewJSONResultHandler(ind, e.targets)
+	jsonHandler.history = history
+	var err error
+	switch ind.Type {
+	case IndicatorTypeRange, IndicatorTypeTrend:
+		r := tpl.QueryRange(ind, now)
+		err = prom.ExecuteQueryRange(client, query, r.Start, r.End, r.Step, resultHandler)
+	default:
+		err = prom.ExecuteQuery(client, query, now, resultHandler, func(string) {})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return jsonHandler.Finalize()
+}
+
+// retryable 判断查询错误是否值得重试：语法等请求错误与回放中未录制的请求重试也不会成功
+func retryable(err error) bool {
+	var apiErr *v1.Error
+	if errors.As(err, &apiErr) && apiErr.Type == v1.ErrBadData {
+		return false
+	}
+	return !errors.Is(err, prom.ErrNotRecorded)
+}
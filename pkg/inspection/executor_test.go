@@ -0,0 +1,175 @@
+package inspection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
+)
+
+func TestExecutor_Execute(t *testing.T) {
+	client, cache := newTestPrometheus(t)
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	exec := NewExecutor(client, cache, WithExecutedBy("admin"))
+	report, err := exec.Execute(context.Background(), tpl, map[string]string{
+		"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Template.Name != tpl.Name || report.Template.ExecutedBy != "admin" || len(report.Results) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	assertItems(t, report.Results[0], map[string]string{
+		"10.120.1.5": ThresholdLevelCritical,
+		"10.120.1.6": ThresholdLevelOk,
+		"10.120.1.7": "missing",
+	})
+	if so := report.SummaryOverviews[0]; so.Indicator != "GPU 使用率" || so.Total != 3 || so.Missing != 1 {
+		t.Errorf("unexpected summary overview: %+v", so)
+	}
+}
+
+func TestExecutor_RangeIndicator(t *testing.T) {
+	client, cache := newTestPrometheus(t)
+	tpl, err := ParseTemplateFile("template/gpu/gpu-node.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.DataCenter.ID = dcID
+
+	// 节点平均GPU温度 为 range 类型，取每个时间序列的最新值
+	report, err := NewExecutor(client, cache).Execute(context.Background(), tpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 1 {
+		t.Fatalf("expected 1 result, got %d", len(report.Results))
+	}
+	assertItems(t, report.Results[0], map[string]string{
+		"10.120.1.5:9400": ThresholdLevelWarning,
+		"10.120.1.6:9400": ThresholdLevelInfo,
+		"10.120.1.7:9400": "missing",
+	})
+}
+
+func TestExecutor_RecordAndReplay(t *testing.T) {
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`}
+	executedAt := time.Date(2026, 1, 2, 9, 0, 0, 123456789, time.Local)
+
+	// 录制：对假 Prometheus 执行一次
+	srv := promtest.NewServer(t, promtest.WithFixtureFile("testdata/prometheus.yaml"), promtest.WithNow(executedAt))
+	rec := prom.NewRecorder(nil)
+	client, err := prom.NewClient(srv.URL, prom.WithRoundTripper(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := prom.NewIndexedTargetCache(client, time.Hour)
+	defer cache.Close()
+	exec := NewExecutor(client, cache, WithNow(func() time.Time { return executedAt }), WithExecutedBy("cron"))
+	want, err := exec.Execute(context.Background(), tpl, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	if err := SaveRunBundle(path, NewRunBundle(tpl, vars, want, rec.Exchanges())); err != nil {
+		t.Fatal(err)
+	}
+
+	// 回放：服务已关闭，结果与录制时一致
+	bundle, err := LoadRunBundle(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Replay(context.Background(), tpl, bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, _ := json.Marshal(want)
+	gotJSON, _ := json.Marshal(got)
+	if !reflect.DeepEqual(wantJSON, gotJSON) {
+		t.Errorf("replayed report differs:\n got  %s\n want %s", gotJSON, wantJSON)
+	}
+
+	// 修改后的查询没有录制
+	tpl.Indicators[1].Query = `up`
+	_, err = Replay(context.Background(), tpl, bundle)
+	if !errors.Is(err, prom.ErrNotRecorded) {
+		t.Errorf("err = %v, want ErrNotRecorded", err)
+	}
+
+	tpl.Name = "other"
+	if _, err := Replay(context.Background(), tpl, bundle); err == nil {
+		t.Error("expected template mismatch error")
+	}
+}
+
+func TestExecutor_TargetCacheNotReady(t *testing.T) {
+	client, _ := newTestPrometheus(t)
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 目标列表获取失败时不能把指标当作没有缺失的目标
+	down, err := prom.NewClient("http://127.0.0.1:1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer down.Close()
+	cache := prom.NewIndexedTargetCache(down, time.Hour)
+	defer cache.Close()
+
+	report, err := NewExecutor(client, cache).Execute(context.Background(), tpl, map[string]string{
+		"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
+	})
+	if !errors.Is(err, prom.ErrCacheNotReady) {
+		t.Fatalf("err = %v, want ErrCacheNotReady", err)
+	}
+	if len(report.Results) != 0 {
+		t.Errorf("expected no results, got %d", len(report.Results))
+	}
+}
+
+func TestExecutor_TargetSource(t *testing.T) {
+	client, _ := newTestPrometheus(t)
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 目标来自外部登记而不是 Prometheus：登记了但没有数据的 10.120.1.9 为缺失
+	targets := prom.StaticTargets{
+		{ScrapePool: "dcgm_exporter", Labels: model.LabelSet{"instance": "10.120.1.5"}},
+		{ScrapePool: "dcgm_exporter", Labels: model.LabelSet{"instance": "10.120.1.9"}},
+	}
+	report, err := NewExecutor(client, targets).Execute(context.Background(), tpl, map[string]string{
+		"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertItems(t, report.Results[0], map[string]string{
+		"10.120.1.5": ThresholdLevelCritical,
+		"10.120.1.6": ThresholdLevelOk,
+		"10.120.1.9": "missing",
+	})
+}
@@ -0,0 +1,223 @@
+package inspection
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+// timeNow 返回当前时间，测试中可替换
+var timeNow = time.Now
+
+// TemplateFuncs 返回变量与查询模板共用的函数库
+//
+// 参数为列表的函数同时接受 []string、[]any 与逗号分隔的字符串。
+// 按管道习惯，被处理的值是最后一个参数，如 {{.Instances | join "|"}}。
+//
+//	regexEscape s             转义正则元字符：{{regexEscape "10.120."}} => 10\.120\.
+//	promLabelEscape s         转义 PromQL 双引号字符串中的 \ " 与换行，
+//	                          正则先转义再放入标签值时需串联使用：
+//	                          instance=~"{{"10.120." | regexEscape | promLabelEscape}}.*"
+//	join sep list             用 sep 连接列表
+//	default def v             v 为空时返回 def：{{.App | default "node-exporter"}}；引用未声明的变量仍会报错
+//	durationAdd a b           Prometheus 时长相加，支持负数：{{durationAdd .TimeRange "30m"}} => 1h30m
+//	now                       当前时间（time.Time）
+//	formatTime layout t       格式化时间，layout 为 Go 时间格式，或 unix（秒级时间戳）、rfc3339；
+//	                          t 可以是 time.Time、RFC3339 字符串或秒级时间戳
+//	lower s / upper s         大小写转换
+//	quote s                   输出带双引号且已转义的字符串字面量：{{quote .Job}} => "node"
+//	toRegexAlternation list   转义每个元素并用 | 连接，用于 =~ 匹配一组值：
+//	                          instance=~"{{toRegexAlternation .Nodes | promLabelEscape}}"
+//	promRegexIn list          等价于 toRegexAlternation 后再 promLabelEscape，可直接放入 PromQL：
+//	                          instance=~"{{promRegexIn .Nodes}}"
+//	inClause list             生成 SQL 风格的 in 列表：model in {{inClause .Models}} => ('A100', 'H100')
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"regexEscape":        regexp.QuoteMeta,
+		"promLabelEscape":    promLabelEscape,
+		"join":               joinList,
+		"default":            defaultValue,
+		"durationAdd":        durationAdd,
+		"now":                func() time.Time { return timeNow() },
+		"formatTime":         formatTime,
+		"lower":              strings.ToLower,
+		"upper":              strings.ToUpper,
+		"quote":              strconv.Quote,
+		"toRegexAlternation": toRegexAlternation,
+		"promRegexIn":        promRegexIn,
+		"inClause":           inClause,
+	}
+}
+
+// newTextTemplate 创建变量与查询模板使用的 text/template：带 TemplateFuncs，
+// 引用不存在的键时报错，default 只对已声明但为空的变量生效
+func newTextTemplate(name string) *template.Template {
+	return template.New(name).Option("missingkey=error").Funcs(TemplateFuncs())
+}
+
+var promLabelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promLabelEscape 转义 PromQL 双引号字符串中的特殊字符
+func promLabelEscape(s string) string {
+	return promLabelReplacer.Replace(s)
+}
+
+func joinList(sep string, list any) (string, error) {
+	items, err := toStringList(list)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join(items, sep), nil
+}
+
+func defaultValue(def, v any) any {
+	if isEmptyValue(v) {
+		return def
+	}
+	return v
+}
+
+func isEmptyValue(v any) bool {
+	switch val := v.(type) {
+	case nil:
+		return true
+	case string:
+		return val == ""
+	case []string:
+		return len(val) == 0
+	case []any:
+		return len(val) == 0
+	case map[string]any:
+		return len(val) == 0
+	case map[string]string:
+		return len(val) == 0
+	}
+	return false
+}
+
+// durationAdd 将 Prometheus 时长相加，结果以 Prometheus 时长格式输出
+func durationAdd(a, b string) (string, error) {
+	da, err := parseSignedDuration(a)
+	if err != nil {
+		return "", err
+	}
+	db, err := parseSignedDuration(b)
+	if err != nil {
+		return "", err
+	}
+	sum := da + db
+	if sum < 0 {
+		return "-" + model.Duration(-sum).String(), nil
+	}
+	return model.Duration(sum).String(), nil
+}
+
+// parseSignedDuration 解析 Prometheus 时长（如 1h30m），允许前导负号
+func parseSignedDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	neg := strings.HasPrefix(s, "-")
+	d, err := model.ParseDuration(strings.TrimPrefix(s, "-"))
+	if err != nil {
+		return 0, err
+	}
+	if neg {
+		return -time.Duration(d), nil
+	}
+	return time.Duration(d), nil
+}
+
+func formatTime(layout string, t any) (string, error) {
+	tm, err := toTime(t)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(layout) {
+	case "unix":
+		return strconv.FormatInt(tm.Unix(), 10), nil
+	case "rfc3339":
+		return tm.Format(time.RFC3339), nil
+	}
+	return tm.Format(layout), nil
+}
+
+func toTime(t any) (time.Time, error) {
+	switch val := t.(type) {
+	case time.Time:
+		return val, nil
+	case int:
+		return time.Unix(int64(val), 0), nil
+	case int64:
+		return time.Unix(val, 0), nil
+	case float64:
+		return time.Unix(int64(val), 0), nil
+	case string:
+		if ts, err := strconv.ParseInt(val, 10, 64); err == nil {
+			return time.Unix(ts, 0), nil
+		}
+		return time.Parse(time.RFC3339, val)
+	}
+	return time.Time{}, fmt.Errorf("cannot convert %T to time", t)
+}
+
+// toRegexAlternation 转义每个元素后用 | 连接
+func toRegexAlternation(list any) (string, error) {
+	items, err := toStringList(list)
+	if err != nil {
+		return "", err
+	}
+	for i, item := range items {
+		items[i] = regexp.QuoteMeta(item)
+	}
+	return strings.Join(items, "|"), nil
+}
+
+func promRegexIn(list any) (string, error) {
+	alt, err := toRegexAlternation(list)
+	if err != nil {
+		return "", err
+	}
+	return promLabelEscape(alt), nil
+}
+
+// inClause 生成 ('a', 'b') 形式的列表，单引号按 SQL 规则转义为两个单引号
+func inClause(list any) (string, error) {
+	items, err := toStringList(list)
+	if err != nil {
+		return "", err
+	}
+	for i, item := range items {
+		items[i] = "'" + strings.ReplaceAll(item, "'", "''") + "'"
+	}
+	return "(" + strings.Join(items, ", ") + ")", nil
+}
+
+// toStringList 将列表参数统一转换为 []string，字符串按逗号拆分
+func toStringList(list any) ([]string, error) {
+	switch val := list.(type) {
+	case nil:
+		return nil, nil
+	case []string:
+		return append([]string(nil), val...), nil
+	case []any:
+		items := make([]string, len(val))
+		for i, item := range val {
+			items[i] = fmt.Sprint(item)
+		}
+		return items, nil
+	case string:
+		if strings.TrimSpace(val) == "" {
+			return nil, nil
+		}
+		parts := strings.Split(val, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		return parts, nil
+	}
+	return nil, fmt.Errorf("expected a list, got %T", list)
+}
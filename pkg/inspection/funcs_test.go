@@ -0,0 +1,103 @@
+package inspection
+
+import (
+	"strings"
+	"testing"
+	"text/template"
+	"time"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	fixed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	timeNow = func() time.Time { return fixed }
+	defer func() { timeNow = time.Now }()
+
+	data := map[string]any{
+		"Empty": "",
+		"Nodes": []string{"10.0.0.1:9100", "10.0.0.2:9100"},
+		"CSV":   "a, b,c",
+		"Any":   []any{"x", 1},
+		"TS":    "1767323045",
+		// 已声明但未赋值的可选变量
+		"NoList": emptyVarValue(Variable{Type: "list"}),
+		"NoMap":  emptyVarValue(Variable{Type: "map"}),
+	}
+	cases := []struct {
+		tmpl string
+		want string
+	}{
+		{`{{regexEscape "10.120.[0-9]+"}}`, `10\.120\.\[0-9\]\+`},
+		{`{{promLabelEscape "a\\b\"c"}}`, `a\\b\"c`},
+		{`{{"10.120." | regexEscape | promLabelEscape}}`, `10\\.120\\.`},
+		{`{{.Nodes | join "|"}}`, `10.0.0.1:9100|10.0.0.2:9100`},
+		{`{{.CSV | join ";"}}`, `a;b;c`},
+		{`{{.Any | join ","}}`, `x,1`},
+		{`{{.Empty | default "fallback"}}`, `fallback`},
+		{`{{.NoList | default "fallback"}}`, `fallback`},
+		{`{{.NoMap | default "fallback"}}`, `fallback`},
+		{`{{"set" | default "fallback"}}`, `set`},
+		{`{{durationAdd "1h" "30m"}}`, `1h30m`},
+		{`{{durationAdd "1h" "-2h"}}`, `-1h`},
+		{`{{now | formatTime "2006-01-02"}}`, `2026-01-02`},
+		{`{{now | formatTime "unix"}}`, `1767323045`},
+		{`{{.TS | formatTime "rfc3339"}}`, fixed.Local().Format(time.RFC3339)},
+		{`{{lower "GPU"}}-{{upper "gpu"}}`, `gpu-GPU`},
+		{`{{quote "a\"b"}}`, `"a\"b"`},
+		{`{{toRegexAlternation .Nodes}}`, `10\.0\.0\.1:9100|10\.0\.0\.2:9100`},
+		{`{{toRegexAlternation "a.b,c"}}`, `a\.b|c`},
+	}
+	for _, c := range cases {
+		tmpl, err := newTextTemplate("t").Parse(c.tmpl)
+		if err != nil {
+			t.Errorf("%s: parse: %v", c.tmpl, err)
+			continue
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, data); err != nil {
+			t.Errorf("%s: execute: %v", c.tmpl, err)
+			continue
+		}
+		if b.String() != c.want {
+			t.Errorf("%s = %q, want %q", c.tmpl, b.String(), c.want)
+		}
+	}
+}
+
+func TestTemplateFuncs_Errors(t *testing.T) {
+	for _, tmplStr := range []string{
+		`{{.Missing | default "fallback"}}`, // 未声明的键在 default 之前报错
+		`{{durationAdd "1x" "1h"}}`,
+		`{{formatTime "unix" "yesterday"}}`,
+		`{{join "," 42}}`,
+	} {
+		tmpl := template.Must(newTextTemplate("t").Parse(tmplStr))
+		if err := tmpl.Execute(&strings.Builder{}, map[string]any{}); err == nil {
+			t.Errorf("%s: expected error", tmplStr)
+		}
+	}
+}
+
+func TestRenderQueryWithVars_Funcs(t *testing.T) {
+	tpl := &Template{
+		TimeRange: "1h",
+		Vars: []Variable{
+			{Name: "ClusterRegex", Type: "string", DefaultValue: `{{"10.120." | regexEscape}}[0-9]+`},
+			{Name: "NodeSelector", Type: "string", Value: "{{.ClusterRegex | promLabelEscape}}"},
+		},
+	}
+	ind := &Indicator{
+		Name:  "gpu",
+		Query: `max_over_time(up{instance=~"{{.NodeSelector}}", job={{quote "gpu"}}}[{{durationAdd .TimeRange "30m"}}])`,
+	}
+	got, err := tpl.RenderQueryWithVars(ind, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `max_over_time(up{instance=~"10\\.120\\.[0-9]+", job="gpu"}[1h30m])`
+	if got != want {
+		t.Errorf("query = %s, want %s", got, want)
+	}
+	if _, err := parsePromQL(got); err != nil {
+		t.Errorf("rendered query is not valid PromQL: %v", err)
+	}
+}
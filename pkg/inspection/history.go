@@ -0,0 +1,114 @@
+package inspection
+
+import (
+	"context"
+	"time"
+)
+
+// 状态历史写入 ValueItem.Metadata 的键
+const (
+	MetadataConsecutive = "consecutive"  // 连续处于当前状态的执行次数（含本次）
+	MetadataStatusSince = "status_since" // 当前状态开始的执行时间
+	MetadataFirstSeen   = "first_seen"   // 历史范围内首次出现的执行时间
+	MetadataFlaps       = "flaps"        // 历史范围内状态变化的次数（含本次）
+)
+
+// 高亮条件比较的字段
+const (
+	ConditionFieldValue       = "value"       // 数据项的值（默认）
+	ConditionFieldConsecutive = "consecutive" // 连续处于当前状态的执行次数，需配置历史
+	ConditionFieldFlaps       = "flaps"       // 状态变化次数，需配置历史
+)
+
+// HistoryFunc 返回模板在 before 之前的历史报告，按执行时间从新到旧排序，
+// 通常由报告存储提供，条数即状态历史的窗口大小
+type HistoryFunc func(ctx context.Context, template string, before time.Time) ([]*Report, error)
+
+// historyRun 一次历史执行中某个指标的数据项
+type historyRun struct {
+	at    time.Time
+	items map[string]ValueItem // 按 Target 索引，指标不存在时为空
+}
+
+// statusHistory 单个指标的状态历史
+type statusHistory struct {
+	runs []historyRun // 从旧到新
+	now  time.Time    // 本次执行时间
+}
+
+// newStatusHistory 从历史报告（从新到旧）中取出指标的数据项
+func newStatusHistory(reports []*Report, indicator string, now time.Time) *statusHistory {
+	h := &statusHistory{runs: make([]historyRun, 0, len(reports)), now: now}
+	for i := len(reports) - 1; i >= 0; i-- {
+		run := historyRun{at: reports[i].Template.ExecutedAt}
+		for _, r := range reports[i].Results {
+			if r.Indicator == indicator {
+				run.items = itemsByTarget(r.Values)
+				break
+			}
+		}
+		h.runs = append(h.runs, run)
+	}
+	return h
+}
+
+// annotate 将每个目标的状态历史写入数据项的 Metadata
+//
+// 目标在某次执行中不存在时，连续次数在此中断，状态变化次数不计该次执行。
+func (h *statusHistory) annotate(values []ValueItem) {
+	for i := range values {
+		item := &values[i]
+		status := itemStatus(*item)
+		consecutive, since, firstSeen, flaps := 1, h.now, h.now, 0
+
+		streak := true
+		next := status // 时间上后一次出现时的状态
+		for j := len(h.runs) - 1; j >= 0; j-- {
+			prev, ok := h.runs[j].items[item.Target]
+			if !ok {
+				streak = false
+				continue
+			}
+			prevStatus := itemStatus(prev)
+			if streak && prevStatus == status {
+				consecutive++
+				since = h.runs[j].at
+			} else {
+				streak = false
+			}
+			if prevStatus != next {
+				flaps++
+			}
+			next = prevStatus
+			firstSeen = h.runs[j].at
+		}
+
+		if item.Metadata == nil {
+			item.Metadata = make(map[string]any, 4)
+		}
+		item.Metadata[MetadataConsecutive] = consecutive
+		item.Metadata[MetadataStatusSince] = since
+		item.Metadata[MetadataFirstSeen] = firstSeen
+		item.Metadata[MetadataFlaps] = flaps
+	}
+}
+
+// conditionValue 高亮条件比较的值，数据项没有该字段时返回 false
+func conditionValue(item ValueItem, field string) (float64, bool) {
+	switch field {
+	case "", ConditionFieldValue:
+		if item.Value == nil {
+			return 0, false
+		}
+		return *item.Value, true
+	case ConditionFieldConsecutive, ConditionFieldFlaps:
+		// 从存储读回的报告中为 float64
+		switch v := item.Metadata[field].(type) {
+		case int:
+			return float64(v), true
+		case float64:
+			return v, true
+		}
+	}
+	return 0, false
+}
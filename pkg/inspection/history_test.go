@@ -0,0 +1,182 @@
+package inspection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
+)
+
+// historyReports 按 statuses 生成历史报告，statuses[i] 为 i+1 天前各目标的状态，"" 表示目标不存在
+func historyReports(now time.Time, indicator string, statuses []map[string]string) []*Report {
+	var reports []*Report
+	for i, run := range statuses {
+		result := &IndicatorResult{Indicator: indicator}
+		for target, status := range run {
+			if status != "" {
+				result.Values = append(result.Values, diffItem(target, 1, status))
+			}
+		}
+		reports = append(reports, diffReport(now.AddDate(0, 0, -(i+1)), result))
+	}
+	return reports
+}
+
+func TestStatusHistory_Annotate(t *testing.T) {
+	now := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return now.AddDate(0, 0, -n) }
+	// 从新到旧：1 天前、2 天前、3 天前、4 天前
+	history := historyReports(now, "GPU 使用率", []map[string]string{
+		{"stable": ThresholdLevelWarning, "flapping": ThresholdLevelOk, "gap": ThresholdLevelWarning, "missing": StatusMissing},
+		{"stable": ThresholdLevelWarning, "flapping": ThresholdLevelWarning, "gap": ""},
+		{"stable": ThresholdLevelWarning, "flapping": ThresholdLevelOk, "gap": ThresholdLevelWarning},
+		{"stable": ThresholdLevelOk, "flapping": ThresholdLevelWarning},
+	})
+	values := []ValueItem{
+		diffItem("stable", 90, ThresholdLevelWarning),
+		diffItem("flapping", 90, ThresholdLevelWarning),
+		diffItem("gap", 90, ThresholdLevelWarning),
+		diffItem("missing", 0, StatusMissing),
+		diffItem("new", 90, ThresholdLevelCritical),
+	}
+	newStatusHistory(history, "GPU 使用率", now).annotate(values)
+
+	tests := []struct {
+		target      string
+		consecutive int
+		since       time.Time
+		firstSeen   time.Time
+		flaps       int
+	}{
+		{"stable", 4, day(3), day(4), 1},
+		{"flapping", 1, now, day(4), 4},
+		// 2 天前不存在，连续次数在此中断，状态变化不计该次
+		{"gap", 2, day(1), day(3), 0},
+		{"missing", 2, day(1), day(1), 0},
+		{"new", 1, now, now, 0},
+	}
+	for i, tt := range tests {
+		md := values[i].Metadata
+		if md[MetadataConsecutive] != tt.consecutive || md[MetadataStatusSince] != tt.since ||
+			md[MetadataFirstSeen] != tt.firstSeen || md[MetadataFlaps] != tt.flaps {
+			t.Errorf("%s: got consecutive=%v since=%v first_seen=%v flaps=%v, want %d %s %s %d", tt.target,
+				md[MetadataConsecutive], md[MetadataStatusSince], md[MetadataFirstSeen], md[MetadataFlaps],
+				tt.consecutive, tt.since, tt.firstSeen, tt.flaps)
+		}
+	}
+}
+
+const historyTemplateYAML = `
+name: daily-gpu-inspection
+display_name: 抓取目标
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+data_center: { id: dc-1 }
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+indicators:
+  - name: health
+    source: targets
+    exporter: node_exporter
+    type: point
+    query: health
+    thresholds:
+      - { level: critical, value: 1, operator: lt, description: 抓取失败 }
+    display:
+      type: table
+      highlight:
+        enabled: true
+        logic: and
+        conditions:
+          - { level: critical }
+          - { field: consecutive, operator: gte, value: 3 }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [health]
+`
+
+func TestExecutor_HistoryHighlight(t *testing.T) {
+	now := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	down := func(instance string) v1.ActiveTarget {
+		return v1.ActiveTarget{ScrapePool: "node_exporter", Health: v1.HealthBad,
+			Labels: model.LabelSet{"job": "node_exporter", "instance": model.LabelValue(instance)}}
+	}
+	srv := promtest.NewServer(t, promtest.WithTargets(down("a"), down("b")))
+	client, err := prom.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	tpl, err := ParseTemplateBytes([]byte(historyTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a 已连续两次 critical，加上本次为 3 次；b 上次才变为 critical
+	history := historyReports(now, "health", []map[string]string{
+		{"a": ThresholdLevelCritical, "b": ThresholdLevelCritical},
+		{"a": ThresholdLevelCritical, "b": ThresholdLevelOk},
+	})
+	var gotBefore time.Time
+	exec := NewExecutor(client, nil, WithNow(func() time.Time { return now }),
+		WithHistory(func(_ context.Context, template string, before time.Time) ([]*Report, error) {
+			if template != "daily-gpu-inspection" {
+				t.Errorf("history requested for template %q", template)
+			}
+			gotBefore = before
+			return history, nil
+		}))
+	report, err := exec.Execute(context.Background(), tpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !gotBefore.Equal(now) {
+		t.Errorf("history requested before %s, want %s", gotBefore, now)
+	}
+	highlights := report.Results[0].Highlight.Values
+	if len(highlights) != 1 || highlights[0].Target != "a" {
+		t.Fatalf("highlights: got %v, want only a", highlights)
+	}
+	if highlights[0].Metadata[MetadataConsecutive] != 3 {
+		t.Errorf("a: consecutive %v, want 3", highlights[0].Metadata[MetadataConsecutive])
+	}
+
+	// 历史不可用时仍然执行，只是不写入状态历史
+	exec = NewExecutor(client, nil, WithHistory(func(context.Context, string, time.Time) ([]*Report, error) {
+		return nil, context.DeadlineExceeded
+	}))
+	report, err = exec.Execute(context.Background(), tpl, nil)
+	if err == nil || !strings.Contains(err.Error(), "load history") {
+		t.Errorf("got error %v, want load history error", err)
+	}
+	if report == nil || len(report.Results) != 1 || report.Results[0].Values[0].Metadata[MetadataConsecutive] != nil {
+		t.Errorf("expected report without status history, got %+v", report)
+	}
+}
+
+func TestValidateTemplate_ConditionField(t *testing.T) {
+	data := strings.Replace(historyTemplateYAML, "{ field: consecutive, operator: gte, value: 3 }", "{ field: streak, operator: gte, value: 3 }", 1)
+	data = strings.Replace(data, "{ level: critical }", "{ level: critical, field: flaps }", 1)
+	errs := ValidateTemplateBytes([]byte(data))
+	want := []string{
+		"indicators[0].display.highlight.conditions[0].field",
+		"indicators[0].display.highlight.conditions[1].field",
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, e.Path)
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("got errors at %v, want %v", got, want)
+	}
+}
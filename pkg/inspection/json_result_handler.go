@@ -13,9 +13,10 @@ import (
 
 // JSONResultHandler 封装累积结果的状态
 type JSONResultHandler struct {
-	indicator          *Indicator
-	indexedTargetCache *prom.IndexedTargetCache
-	result             *IndicatorResult
+	indicator *Indicator
+	targets   prom.TargetSource
+	history   *statusHistory // 为 nil 时不写入状态历史
+	result    *IndicatorResult
 	// 用于临时存储所有样本（因为处理器会被多次调用，每次处理一个样本）
 	samples []*model.Sample
 }
@@ -24,11 +25,13 @@ type JSONResultHandler struct {
 // 返回值：
 //   - *JSONResultHandler：结构体指针，用于在所有数据处理完成后调用 Finalize() 生成最终结果
 //   - prom.ResultHandler：处理器函数，用于传递给 prom 包处理查询结果
-func NewJSONResultHandler(indicator *Indicator, cache *prom.IndexedTargetCache) (*JSONResultHandler, prom.ResultHandler) {
+//
+// targets 提供应被巡检的目标，用于判断缺失的目标；为 nil 时不检查缺失。
+func NewJSONResultHandler(indicator *Indicator, targets prom.TargetSource) (*JSONResultHandler, prom.ResultHandler) {
 	// 初始化处理器结构体，存储指标元信息和临时数据
 	handler := &JSONResultHandler{
-		indicator:          indicator, // 保存指标元信息（如名称、阈值、显示配置等）
-		indexedTargetCache: cache,
+		indicator: indicator, // 保存指标元信息（如名称、阈值、显示配置等）
+		targets:   targets,
 		result: &IndicatorResult{ // 初始化最终要返回的 JSON 结构
 			Indicator:   indicator.Name,
 			Type:        indicator.Type,
@@ -78,11 +81,18 @@ func NewJSONResultHandler(indicator *Indicator, cache *prom.IndexedTargetCache)
 // Finalize 处理完所有样本后，调用此方法生成最终 JSON（需在查询结束后手动调用）
 func (h *JSONResultHandler) Finalize() (*IndicatorResult, error) {
 	// 处理所有累积的 *model.Sample 样本
-	h.processSamples()
+	if err := h.processSamples(); err != nil {
+		return nil, err
+	}
 
 	// 处理缺失值
 	h.handleMissingValues()
 
+	// 写入状态历史，高亮条件可能用到
+	if h.history != nil {
+		h.history.annotate(h.result.Values)
+	}
+
 	// 排序并提取高亮项
 	h.sortAndExtractHighlights()
 
@@ -122,9 +132,12 @@ func (h *JSONResultHandler) extractTarget(labels model.LabelSet) string {
 	return ""
 }
 
-func (h *JSONResultHandler) processSamples() {
-	// 预分配合理的容量
-	exists := make(map[string]struct{}, len(h.samples))
+func (h *JSONResultHandler) processSamples() error {
+	// 预分配合理的容量；范围查询的时间序列已在 handleSampleStream 中加入结果
+	exists := make(map[string]struct{}, len(h.samples)+len(h.result.Values))
+	for _, item := range h.result.Values {
+		exists[item.Target] = struct{}{}
+	}
 
 	// 处理存在的样本
 	for _, sample := range h.samples {
@@ -140,8 +153,14 @@ func (h *JSONResultHandler) processSamples() {
 		h.addValueItem(target, &value, false, status)
 	}
 
-	// 处理缺失的目标
-	targets := h.indexedTargetCache.GetTargetsByPool(h.indicator.Exporter)
+	// 处理缺失的目标：未配置 exporter 或目标来源时不检查；来源不可用时无法判断缺失，返回错误而不是当作没有缺失
+	if h.indicator.Exporter == "" || h.targets == nil {
+		return nil
+	}
+	targets, err := h.targets.TargetsFor(h.indicator.Exporter)
+	if err != nil {
+		return fmt.Errorf("check missing targets: %w", err)
+	}
 	if len(targets) > 0 { // 提前检查避免不必要的遍历
 		missingTargets := make([]string, 0, len(targets)/2) // 预估容量
 
@@ -161,6 +180,7 @@ func (h *JSONResultHandler) processSamples() {
 			h.addValueItem(targetName, nil, true)
 		}
 	}
+	return nil
 }
 
 // handleSampleStream 处理 *model.SampleStream 类型的时间序列流
@@ -354,9 +374,10 @@ func (h *JSONResultHandler) matchesSingleCondition(item ValueItem, cond Conditio
 	if cond.Level != "" && item.Status != cond.Level {
 		return false
 	}
-	// 检查数值阈值条件（如 value: 90, operator: gt）
+	// 检查数值阈值条件（如 value: 90, operator: gt），field 为 consecutive、flaps 时比较状态历史
 	if cond.Operator != "" {
-		if !meetsCondition(*item.Value, cond.Operator, *cond.Value) {
+		v, ok := conditionValue(item, cond.Field)
+		if !ok || !meetsCondition(v, cond.Operator, *cond.Value) {
 			return false
 		}
 	}
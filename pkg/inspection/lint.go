@@ -0,0 +1,248 @@
+package inspection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+// Lint 问题级别
+const (
+	LintSeverityError   = "error"   // 查询无法执行或必然无数据
+	LintSeverityWarning = "warning" // 查询可执行，但在检查窗口内可能无数据
+)
+
+// 默认的指标存在性检查窗口
+const defaultLintLookback = time.Hour
+
+// LintProblem 描述单个指标（indicator）查询中的一个问题
+type LintProblem struct {
+	Indicator string `json:"indicator"`
+	Severity  string `json:"severity"`
+	Metric    string `json:"metric,omitempty"`
+	Label     string `json:"label,omitempty"`
+	Message   string `json:"message"`
+}
+
+func (p LintProblem) String() string {
+	return fmt.Sprintf("[%s] indicator %s: %s", p.Severity, p.Indicator, p.Message)
+}
+
+// LintReport 模板 Lint 结果
+type LintReport struct {
+	Template string        `json:"template"`
+	Problems []LintProblem `json:"problems"`
+}
+
+// HasErrors 是否存在 error 级别的问题
+func (r *LintReport) HasErrors() bool {
+	for _, p := range r.Problems {
+		if p.Severity == LintSeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// ProblemsFor 返回指定指标的问题列表
+func (r *LintReport) ProblemsFor(indicator string) []LintProblem {
+	var result []LintProblem
+	for _, p := range r.Problems {
+		if p.Indicator == indicator {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// LintOption 配置 Lint 行为
+type LintOption func(*linter)
+
+// WithLintLookback 指定检查指标/标签是否存在时的时间窗口，
+// 未指定时使用指标的有效 time_range（均未配置时为 1h）
+func WithLintLookback(d time.Duration) LintOption {
+	return func(l *linter) {
+		l.lookback = d
+	}
+}
+
+// WithLintTime 指定检查窗口的结束时间，默认当前时间
+func WithLintTime(t time.Time) LintOption {
+	return func(l *linter) {
+		l.now = t
+	}
+}
+
+type linter struct {
+	tpl      *Template
+	client   *prom.Client
+	lookback time.Duration
+	now      time.Time
+
+	metadata   map[string]bool     // Prometheus 已知的指标名（来自 metadata API）
+	labelNames map[string][]string // 指标名 -> 检查窗口内出现过的标签名
+	report     *LintReport
+}
+
+// Lint 检查模板中每个 prometheus 指标的查询：
+//  1. 使用默认变量值渲染查询；
+//  2. 使用 PromQL 解析器解析渲染结果；
+//  3. 通过 metadata / labels API 检查引用的指标名与标签名是否存在。
+//
+// 单个指标的问题记录在返回的 LintReport 中；只有访问 Prometheus 失败时才返回 error。
+func Lint(tpl *Template, client *prom.Client, opts ...LintOption) (*LintReport, error) {
+	l := &linter{
+		tpl:        tpl,
+		client:     client,
+		now:        time.Now(),
+		labelNames: make(map[string][]string),
+		report:     &LintReport{Template: tpl.Name, Problems: []LintProblem{}},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	if err := l.loadMetadata(); err != nil {
+		return nil, err
+	}
+
+	for _, ind := range tpl.Indicators {
+		if ind.Source != SourcePrometheus {
+			continue
+		}
+		if err := l.lintIndicator(ind); err != nil {
+			return nil, fmt.Errorf("lint indicator %s: %w", ind.Name, err)
+		}
+	}
+	return l.report, nil
+}
+
+// loadMetadata 一次性加载所有指标的元数据
+func (l *linter) loadMetadata() error {
+	md, err := l.client.Metadata("", "")
+	if err != nil {
+		return fmt.Errorf("failed to get metadata: %w", err)
+	}
+	l.metadata = make(map[string]bool, len(md))
+	for name := range md {
+		l.metadata[name] = true
+	}
+	return nil
+}
+
+func (l *linter) lintIndicator(ind *Indicator) error {
+	query, err := l.tpl.RenderQueryWithVars(ind, nil)
+	if err != nil {
+		l.addProblem(ind, LintSeverityError, "", "", fmt.Sprintf("render query: %v", err))
+		return nil
+	}
+
+	expr, err := parsePromQL(query)
+	if err != nil {
+		l.addProblem(ind, LintSeverityError, "", "", newPromQLSyntaxError(ind.Name, query, err).Error())
+		return nil
+	}
+
+	lookback := l.lookbackFor(ind)
+	for _, vs := range vectorSelectors(expr) {
+		metric := selectorMetricName(vs)
+		if metric == "" {
+			continue // 无法确定指标名（如 {__name__=~"..."}），跳过存在性检查
+		}
+
+		names, err := l.labelNamesOf(metric, lookback)
+		if err != nil {
+			return err
+		}
+		if len(names) == 0 {
+			if l.knownMetric(metric) {
+				l.addProblem(ind, LintSeverityWarning, metric, "",
+					fmt.Sprintf("metric %s has no series in the last %s", metric, model.Duration(lookback)))
+			} else {
+				l.addProblem(ind, LintSeverityError, metric, "",
+					fmt.Sprintf("unknown metric %s", metric))
+			}
+			continue
+		}
+
+		for _, m := range vs.LabelMatchers {
+			if m.Name == labels.MetricName {
+				continue
+			}
+			// 能匹配空值的 matcher（如 label=""、label=~".*"）在标签缺失时依然成立
+			if m.Matches("") {
+				continue
+			}
+			if !containsString(names, m.Name) {
+				l.addProblem(ind, LintSeverityError, metric, m.Name,
+					fmt.Sprintf("label %s not found on metric %s (known labels: %s)",
+						m.Name, metric, strings.Join(names, ", ")))
+			}
+		}
+	}
+	return nil
+}
+
+// labelNamesOf 返回指标在检查窗口内出现过的标签名（按指标名缓存）
+func (l *linter) labelNamesOf(metric string, lookback time.Duration) ([]string, error) {
+	if names, ok := l.labelNames[metric]; ok {
+		return names, nil
+	}
+	selector := fmt.Sprintf("{%s=%q}", labels.MetricName, metric)
+	names, _, err := l.client.LabelNames([]string{selector}, l.now.Add(-lookback), l.now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get label names of %s: %w", metric, err)
+	}
+	sort.Strings(names)
+	l.labelNames[metric] = names
+	return names, nil
+}
+
+// knownMetric 判断指标名是否出现在 metadata 中，兼容 histogram/summary/counter 的后缀
+func (l *linter) knownMetric(metric string) bool {
+	if l.metadata[metric] {
+		return true
+	}
+	for _, suffix := range []string{"_bucket", "_sum", "_count", "_total"} {
+		if strings.HasSuffix(metric, suffix) && l.metadata[strings.TrimSuffix(metric, suffix)] {
+			return true
+		}
+	}
+	return false
+}
+
+// lookbackFor 计算指标的检查窗口：选项 > 指标 time_range > 模板 time_range > 默认值
+func (l *linter) lookbackFor(ind *Indicator) time.Duration {
+	if l.lookback > 0 {
+		return l.lookback
+	}
+	if d := l.tpl.EffectiveTimeRange(ind); d > 0 {
+		return d
+	}
+	return defaultLintLookback
+}
+
+func (l *linter) addProblem(ind *Indicator, severity, metric, label, msg string) {
+	l.report.Problems = append(l.report.Problems, LintProblem{
+		Indicator: ind.Name,
+		Severity:  severity,
+		Metric:    metric,
+		Label:     label,
+		Message:   msg,
+	})
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
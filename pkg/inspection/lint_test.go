@@ -0,0 +1,143 @@
+package inspection
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+const lintTemplateYAML = `
+name: lint-test
+display_name: Lint 测试
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: DataCenterID
+    type: string
+    default_value: dc-1
+indicators:
+  - name: ok
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: avg by (instance) (nvidia_smi_utilization_gpu_ratio{data_center_id="{{.DataCenterID}}"})
+    display: { type: table }
+  - name: typo-metric
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: nvidia_smi_utilisation_gpu_ratio{data_center_id="{{.DataCenterID}}"}
+    display: { type: table }
+  - name: typo-label
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: nvidia_smi_utilization_gpu_ratio{datacenter_id="dc-1"}
+    display: { type: table }
+  - name: stale-metric
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: nvidia_smi_temperature_gpu
+    display: { type: table }
+  - name: bad-syntax
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: sum(nvidia_smi_utilization_gpu_ratio
+    display: { type: table }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [ok]
+`
+
+// newLintTestServer 模拟 metadata 与 labels API
+func newLintTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	series := map[string][]string{
+		`{__name__="nvidia_smi_utilization_gpu_ratio"}`: {"__name__", "data_center_id", "instance", "job"},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/metadata", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIData(t, w, map[string]any{
+			"nvidia_smi_utilization_gpu_ratio": []map[string]string{{"type": "gauge"}},
+			"nvidia_smi_temperature_gpu":       []map[string]string{{"type": "gauge"}},
+		})
+	})
+	mux.HandleFunc("/api/v1/labels", func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		names := series[r.Form.Get("match[]")]
+		if names == nil {
+			names = []string{}
+		}
+		writeAPIData(t, w, names)
+	})
+	return httptest.NewServer(mux)
+}
+
+func writeAPIData(t *testing.T, w http.ResponseWriter, data any) {
+	t.Helper()
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(map[string]any{"status": "success", "data": data}); err != nil {
+		t.Errorf("encode response: %v", err)
+	}
+}
+
+func TestLint(t *testing.T) {
+	srv := newLintTestServer(t)
+	defer srv.Close()
+
+	client, err := prom.NewClient(srv.URL, prom.WithTimeout(5*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	tpl, err := ParseTemplateBytes([]byte(lintTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := Lint(tpl, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.HasErrors() {
+		t.Fatal("expected lint errors")
+	}
+
+	cases := []struct {
+		indicator string
+		severity  string
+		contains  string
+	}{
+		{"typo-metric", LintSeverityError, "unknown metric nvidia_smi_utilisation_gpu_ratio"},
+		{"typo-label", LintSeverityError, "label datacenter_id not found"},
+		{"stale-metric", LintSeverityWarning, "has no series"},
+		{"bad-syntax", LintSeverityError, "promql syntax error"},
+	}
+	for _, c := range cases {
+		problems := report.ProblemsFor(c.indicator)
+		if len(problems) != 1 {
+			t.Errorf("%s: expected 1 problem, got %v", c.indicator, problems)
+			continue
+		}
+		if problems[0].Severity != c.severity || !strings.Contains(problems[0].Message, c.contains) {
+			t.Errorf("%s: unexpected problem %s", c.indicator, problems[0])
+		}
+	}
+	if problems := report.ProblemsFor("ok"); len(problems) != 0 {
+		t.Errorf("ok: expected no problems, got %v", problems)
+	}
+}
@@ -0,0 +1,154 @@
+package inspection
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+	"github.com/prometheus/prometheus/tsdb/chunks"
+	"github.com/prometheus/prometheus/util/annotations"
+)
+
+// memStorage 测试用例输入序列的内存存储，实现 storage.Queryable 供 PromQL 引擎查询。
+// 不使用 tsdb 与 promqltest，二者会将 testing、testify 引入依赖
+type memStorage struct {
+	series []*storage.SeriesEntry // 按标签排序
+	maxT   int64                  // 最后一个样本的时间（毫秒），没有样本时为 math.MinInt64
+}
+
+var _ storage.Queryable = (*memStorage)(nil)
+
+// newMemStorage 解析 promtool 写法的输入序列，第 i 个值的时间为 epoch + i*interval；
+// 标签相同的序列以后定义的为准
+func newMemStorage(interval time.Duration, inputs []InputSeries) (*memStorage, error) {
+	byLabels := make(map[uint64]*storage.SeriesEntry, len(inputs))
+	s := &memStorage{maxT: math.MinInt64}
+	for i, in := range inputs {
+		lset, values, err := parser.ParseSeriesDesc(in.Series + " " + in.Values)
+		if err != nil {
+			return nil, fmt.Errorf("input_series[%d]: %w", i, err)
+		}
+		var samples []chunks.Sample
+		for j, v := range values {
+			if v.Omitted {
+				continue
+			}
+			t := int64(j) * interval.Milliseconds()
+			samples = append(samples, memSample{t: t, f: v.Value, fh: v.Histogram})
+			s.maxT = max(s.maxT, t)
+		}
+		byLabels[lset.Hash()] = storage.NewListSeries(lset, samples)
+	}
+	for _, series := range byLabels {
+		s.series = append(s.series, series)
+	}
+	sort.Slice(s.series, func(i, j int) bool {
+		return labels.Compare(s.series[i].Labels(), s.series[j].Labels()) < 0
+	})
+	return s, nil
+}
+
+// Querier 时间范围由引擎在读取样本时过滤，这里返回全部样本
+func (s *memStorage) Querier(_, _ int64) (storage.Querier, error) {
+	return &memQuerier{series: s.series}, nil
+}
+
+type memQuerier struct {
+	series []*storage.SeriesEntry
+}
+
+func (q *memQuerier) Select(_ context.Context, _ bool, _ *storage.SelectHints, matchers ...*labels.Matcher) storage.SeriesSet {
+	var matched []storage.Series
+	for _, series := range q.series {
+		if matchAll(series.Labels(), matchers) {
+			matched = append(matched, series)
+		}
+	}
+	return &memSeriesSet{series: matched, i: -1}
+}
+
+func (q *memQuerier) LabelValues(_ context.Context, name string, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	seen := make(map[string]bool)
+	var values []string
+	for _, series := range q.series {
+		if v := series.Labels().Get(name); v != "" && !seen[v] && matchAll(series.Labels(), matchers) {
+			seen[v] = true
+			values = append(values, v)
+		}
+	}
+	sort.Strings(values)
+	return values, nil, nil
+}
+
+func (q *memQuerier) LabelNames(_ context.Context, _ *storage.LabelHints, matchers ...*labels.Matcher) ([]string, annotations.Annotations, error) {
+	seen := make(map[string]bool)
+	var names []string
+	for _, series := range q.series {
+		if !matchAll(series.Labels(), matchers) {
+			continue
+		}
+		series.Labels().Range(func(l labels.Label) {
+			if !seen[l.Name] {
+				seen[l.Name] = true
+				names = append(names, l.Name)
+			}
+		})
+	}
+	sort.Strings(names)
+	return names, nil, nil
+}
+
+func (q *memQuerier) Close() error { return nil }
+
+func matchAll(lset labels.Labels, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		if !m.Matches(lset.Get(m.Name)) {
+			return false
+		}
+	}
+	return true
+}
+
+type memSeriesSet struct {
+	series []storage.Series
+	i      int
+}
+
+func (s *memSeriesSet) Next() bool                        { s.i++; return s.i < len(s.series) }
+func (s *memSeriesSet) At() storage.Series                { return s.series[s.i] }
+func (s *memSeriesSet) Err() error                        { return nil }
+func (s *memSeriesSet) Warnings() annotations.Annotations { return nil }
+
+// memSample 实现 chunks.Sample；输入序列只有浮点数与原生直方图（浮点）两种值
+type memSample struct {
+	t  int64
+	f  float64
+	fh *histogram.FloatHistogram
+}
+
+func (s memSample) T() int64                      { return s.t }
+func (s memSample) F() float64                    { return s.f }
+func (s memSample) H() *histogram.Histogram       { return nil }
+func (s memSample) FH() *histogram.FloatHistogram { return s.fh }
+
+func (s memSample) Type() chunkenc.ValueType {
+	if s.fh != nil {
+		return chunkenc.ValFloatHistogram
+	}
+	return chunkenc.ValFloat
+}
+
+func (s memSample) Copy() chunks.Sample {
+	c := s
+	if s.fh != nil {
+		c.fh = s.fh.Copy()
+	}
+	return c
+}
@@ -0,0 +1,203 @@
+package inspection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+// metricsNamespace 自监控指标的前缀
+const metricsNamespace = "inspection"
+
+// 执行结果标签取值
+const (
+	RunResultSuccess = "success" // 所有指标执行成功
+	RunResultFailed  = "failed"  // 至少一个指标执行失败
+)
+
+// Metrics 巡检服务的自监控指标：执行次数与耗时、指标耗时与失败、查询重试、
+// 目标缓存的刷新情况以及调度延迟
+//
+// 方法对 nil 接收者安全，未配置指标时 Executor 不做任何记录。
+type Metrics struct {
+	runs              *prometheus.CounterVec
+	runDuration       *prometheus.HistogramVec
+	indicatorDuration *prometheus.HistogramVec
+	indicatorErrors   *prometheus.CounterVec
+	queryRetries      *prometheus.CounterVec
+	schedulerLag      *prometheus.HistogramVec
+	caches            *targetCacheCollector
+}
+
+// NewMetrics 创建自监控指标并注册到 reg，reg 为 nil 时不注册
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		runs: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "runs_total",
+			Help:      "Total number of template runs by result.",
+		}, []string{"template", "result"}),
+		runDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "run_duration_seconds",
+			Help:      "Duration of template runs.",
+			Buckets:   []float64{.1, .5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+		}, []string{"template"}),
+		indicatorDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "indicator_duration_seconds",
+			Help:      "Duration of indicator queries, including retries.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"template", "indicator"}),
+		indicatorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "indicator_errors_total",
+			Help:      "Total number of failed indicators.",
+		}, []string{"template", "indicator"}),
+		queryRetries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Name:      "query_retries_total",
+			Help:      "Total number of retried Prometheus queries.",
+		}, []string{"template", "indicator"}),
+		schedulerLag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Name:      "scheduler_lag_seconds",
+			Help:      "Delay between the scheduled time and the actual start of a run.",
+			Buckets:   []float64{.01, .05, .1, .5, 1, 5, 10, 30, 60},
+		}, []string{"template"}),
+		caches: newTargetCacheCollector(),
+	}
+	if reg != nil {
+		reg.MustRegister(
+			m.runs,
+			m.runDuration,
+			m.indicatorDuration,
+			m.indicatorErrors,
+			m.queryRetries,
+			m.schedulerLag,
+			m.caches,
+		)
+	}
+	return m
+}
+
+// WatchTargetCache 暴露目标缓存的刷新统计，name 区分多个缓存；同名缓存会被替换
+func (m *Metrics) WatchTargetCache(name string, tc *prom.IndexedTargetCache) {
+	if m == nil {
+		return
+	}
+	m.caches.mutex.Lock()
+	defer m.caches.mutex.Unlock()
+	m.caches.caches[name] = tc
+}
+
+func (m *Metrics) observeRun(template string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	result := RunResultSuccess
+	if err != nil {
+		result = RunResultFailed
+	}
+	m.runs.WithLabelValues(template, result).Inc()
+	m.runDuration.WithLabelValues(template).Observe(d.Seconds())
+}
+
+func (m *Metrics) observeIndicator(template, indicator string, d time.Duration, err error) {
+	if m == nil {
+		return
+	}
+	m.indicatorDuration.WithLabelValues(template, indicator).Observe(d.Seconds())
+	if err != nil {
+		m.indicatorErrors.WithLabelValues(template, indicator).Inc()
+	}
+}
+
+func (m *Metrics) observeRetry(template, indicator string) {
+	if m == nil {
+		return
+	}
+	m.queryRetries.WithLabelValues(template, indicator).Inc()
+}
+
+func (m *Metrics) observeSchedulerLag(template string, lag time.Duration) {
+	if m == nil {
+		return
+	}
+	if lag < 0 {
+		lag = 0
+	}
+	m.schedulerLag.WithLabelValues(template).Observe(lag.Seconds())
+}
+
+type scheduledAtKey struct{}
+
+// WithScheduledAt 在 ctx 中记录本次执行的计划时间，由调度器在触发定时执行时设置；
+// Executor 据此记录调度延迟
+func WithScheduledAt(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, scheduledAtKey{}, t)
+}
+
+// scheduledAt 返回 ctx 中记录的计划时间
+func scheduledAt(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(scheduledAtKey{}).(time.Time)
+	return t, ok
+}
+
+// targetCacheCollector 在采集时读取目标缓存的刷新统计
+type targetCacheCollector struct {
+	mutex  sync.Mutex
+	caches map[string]*prom.IndexedTargetCache
+
+	refreshes       *prometheus.Desc
+	refreshFailures *prometheus.Desc
+	lastRefresh     *prometheus.Desc
+	age             *prometheus.Desc
+	targets         *prometheus.Desc
+}
+
+func newTargetCacheCollector() *targetCacheCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, "target_cache", name), help, labels, nil)
+	}
+	return &targetCacheCollector{
+		caches:          make(map[string]*prom.IndexedTargetCache),
+		refreshes:       desc("refreshes_total", "Total number of target cache refreshes.", "cache"),
+		refreshFailures: desc("refresh_failures_total", "Total number of failed target cache refreshes.", "cache"),
+		lastRefresh:     desc("last_refresh_timestamp_seconds", "Timestamp of the last successful target cache refresh.", "cache"),
+		age:             desc("age_seconds", "Seconds since the last successful target cache refresh.", "cache"),
+		targets:         desc("targets", "Number of cached targets by scrape pool.", "cache", "pool"),
+	}
+}
+
+func (c *targetCacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.refreshes
+	ch <- c.refreshFailures
+	ch <- c.lastRefresh
+	ch <- c.age
+	ch <- c.targets
+}
+
+func (c *targetCacheCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	now := time.Now()
+	for name, tc := range c.caches {
+		stats := tc.Stats()
+		ch <- prometheus.MustNewConstMetric(c.refreshes, prometheus.CounterValue, float64(stats.Refreshes), name)
+		ch <- prometheus.MustNewConstMetric(c.refreshFailures, prometheus.CounterValue, float64(stats.RefreshFailures), name)
+		// 从未成功刷新时不暴露时间与年龄，避免产生误导的极大值
+		if !stats.LastRefresh.IsZero() {
+			ch <- prometheus.MustNewConstMetric(c.lastRefresh, prometheus.GaugeValue, float64(stats.LastRefresh.UnixNano())/1e9, name)
+			ch <- prometheus.MustNewConstMetric(c.age, prometheus.GaugeValue, now.Sub(stats.LastRefresh).Seconds(), name)
+		}
+		for pool, n := range stats.TargetsByPool {
+			ch <- prometheus.MustNewConstMetric(c.targets, prometheus.GaugeValue, float64(n), name, pool)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package inspection
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
+)
+
+// flakyTransport 让前 failures 次查询请求返回连接错误
+type flakyTransport struct {
+	mutex    sync.Mutex
+	failures int
+}
+
+func (f *flakyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	f.mutex.Lock()
+	fail := f.failures > 0 && strings.HasSuffix(req.URL.Path, "/api/v1/query")
+	if fail {
+		f.failures--
+	}
+	f.mutex.Unlock()
+	if fail {
+		return nil, errors.New("connection reset by peer")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestMetrics_Executor(t *testing.T) {
+	srv := promtest.NewServer(t, promtest.WithFixtureFile("testdata/prometheus.yaml"))
+	client, err := prom.NewClient(srv.URL, prom.WithRoundTripper(&flakyTransport{failures: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	cache := prom.NewIndexedTargetCache(client, time.Hour)
+	defer cache.Close()
+
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	vars := map[string]string{"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`}
+
+	reg := prometheus.NewPedanticRegistry()
+	metrics := NewMetrics(reg)
+	metrics.WatchTargetCache("default", cache)
+
+	// 第一个指标失败两次后成功
+	exec := NewExecutor(client, cache, WithMetrics(metrics), WithQueryRetries(2, time.Millisecond))
+	if _, err := exec.Execute(context.Background(), tpl, vars); err != nil {
+		t.Fatal(err)
+	}
+
+	// 不重试时第一个指标失败，本次执行记为失败
+	client2, err := prom.NewClient(srv.URL, prom.WithRoundTripper(&flakyTransport{failures: 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client2.Close()
+	exec = NewExecutor(client2, cache, WithMetrics(metrics))
+	if _, err := exec.Execute(WithScheduledAt(context.Background(), time.Now().Add(-time.Second)), tpl, vars); err == nil {
+		t.Fatal("expected indicator error without retries")
+	}
+
+	expected := `
+# HELP inspection_indicator_errors_total Total number of failed indicators.
+# TYPE inspection_indicator_errors_total counter
+inspection_indicator_errors_total{indicator="GPU 使用率",template="daily-gpu-inspection"} 1
+# HELP inspection_query_retries_total Total number of retried Prometheus queries.
+# TYPE inspection_query_retries_total counter
+inspection_query_retries_total{indicator="GPU 使用率",template="daily-gpu-inspection"} 2
+# HELP inspection_runs_total Total number of template runs by result.
+# TYPE inspection_runs_total counter
+inspection_runs_total{result="failed",template="daily-gpu-inspection"} 1
+inspection_runs_total{result="success",template="daily-gpu-inspection"} 1
+# HELP inspection_target_cache_refresh_failures_total Total number of failed target cache refreshes.
+# TYPE inspection_target_cache_refresh_failures_total counter
+inspection_target_cache_refresh_failures_total{cache="default"} 0
+# HELP inspection_target_cache_refreshes_total Total number of target cache refreshes.
+# TYPE inspection_target_cache_refreshes_total counter
+inspection_target_cache_refreshes_total{cache="default"} 1
+# HELP inspection_target_cache_targets Number of cached targets by scrape pool.
+# TYPE inspection_target_cache_targets gauge
+inspection_target_cache_targets{cache="default",pool="dcgm_exporter"} 3
+inspection_target_cache_targets{cache="default",pool="gpu_exporter"} 3
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"inspection_indicator_errors_total",
+		"inspection_query_retries_total",
+		"inspection_runs_total",
+		"inspection_target_cache_refresh_failures_total",
+		"inspection_target_cache_refreshes_total",
+		"inspection_target_cache_targets",
+	); err != nil {
+		t.Error(err)
+	}
+
+	// 每次执行记录两个指标的耗时；只有第二次执行带有计划时间
+	if n := testutil.CollectAndCount(metrics.indicatorDuration); n != 2 {
+		t.Errorf("expected 2 indicator duration series, got %d", n)
+	}
+	if got := histogramCount(t, reg, "inspection_run_duration_seconds"); got != 2 {
+		t.Errorf("run duration count = %d, want 2", got)
+	}
+	if got := histogramCount(t, reg, "inspection_scheduler_lag_seconds"); got != 1 {
+		t.Errorf("scheduler lag count = %d, want 1", got)
+	}
+	if n := testutil.CollectAndCount(metrics.caches, "inspection_target_cache_age_seconds"); n != 1 {
+		t.Errorf("expected cache age to be exposed, got %d series", n)
+	}
+}
+
+func TestMetrics_NoRetryOnBadData(t *testing.T) {
+	client, cache := newTestPrometheus(t)
+	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tpl.Indicators[0].Query = `sum(`
+
+	metrics := NewMetrics(nil)
+	exec := NewExecutor(client, cache, WithMetrics(metrics), WithQueryRetries(3, time.Millisecond))
+	if _, err := exec.Execute(context.Background(), tpl, nil); err == nil {
+		t.Fatal("expected query error")
+	}
+	if n := testutil.CollectAndCount(metrics.queryRetries); n != 0 {
+		t.Errorf("bad_data should not be retried, got %d retry series", n)
+	}
+}
+
+// histogramCount 返回指定直方图所有序列的样本数之和
+func histogramCount(t *testing.T, g prometheus.Gatherer, name string) uint64 {
+	t.Helper()
+	families, err := g.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count uint64
+	for _, mf := range families {
+		if mf.GetName() != name {
+			continue
+		}
+		for _, m := range mf.GetMetric() {
+			count += m.GetHistogram().GetSampleCount()
+		}
+	}
+	return count
+}
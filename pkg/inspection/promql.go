@@ -0,0 +1,140 @@
+package inspection
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql/parser"
+)
+
+// parsePromQL 使用 Prometheus 官方解析器解析渲染后的 PromQL
+func parsePromQL(query string) (parser.Expr, error) {
+	return parser.ParseExpr(query)
+}
+
+// vectorSelectors 提取表达式中引用的所有向量选择器（包括范围向量内部的选择器）
+func vectorSelectors(expr parser.Expr) []*parser.VectorSelector {
+	var selectors []*parser.VectorSelector
+	parser.Inspect(expr, func(node parser.Node, _ []parser.Node) error {
+		if vs, ok := node.(*parser.VectorSelector); ok {
+			selectors = append(selectors, vs)
+		}
+		return nil
+	})
+	return selectors
+}
+
+// selectorMetricName 返回选择器引用的指标名，未使用等值 __name__ 匹配时返回空字符串
+func selectorMetricName(vs *parser.VectorSelector) string {
+	if vs.Name != "" {
+		return vs.Name
+	}
+	for _, m := range vs.LabelMatchers {
+		if m.Name == labels.MetricName && m.Type == labels.MatchEqual {
+			return m.Value
+		}
+	}
+	return ""
+}
+
+// PromQLSyntaxError PromQL 语法错误，指向具体的指标名与出错位置
+type PromQLSyntaxError struct {
+	Indicator string // 指标名
+	Query     string // 渲染后的查询语句
+	Offset    int    // 出错位置的字节偏移（从 0 开始）
+	Line      int    // 出错行（从 1 开始）
+	Column    int    // 出错列（从 1 开始，按字符计）
+	Msg       string
+}
+
+func (e *PromQLSyntaxError) Error() string {
+	return fmt.Sprintf("indicator %s: promql syntax error at line %d, column %d (offset %d): %s",
+		e.Indicator, e.Line, e.Column, e.Offset, e.Msg)
+}
+
+// newPromQLSyntaxError 将解析器返回的错误转换为 PromQLSyntaxError；
+// 非 parser.ParseErrors 类型的错误原样包装
+func newPromQLSyntaxError(indicator, query string, err error) error {
+	var parseErrs parser.ParseErrors
+	if !errors.As(err, &parseErrs) || len(parseErrs) == 0 {
+		return fmt.Errorf("indicator %s: promql parse: %w", indicator, err)
+	}
+	first := parseErrs[0]
+	offset := int(first.PositionRange.Start)
+	if offset > len(query) {
+		offset = len(query)
+	}
+	line, column := lineColumn(query, offset)
+	return &PromQLSyntaxError{
+		Indicator: indicator,
+		Query:     query,
+		Offset:    offset,
+		Line:      line,
+		Column:    column,
+		Msg:       first.Err.Error(),
+	}
+}
+
+// lineColumn 计算字节偏移对应的行号与列号（均从 1 开始）
+func lineColumn(s string, offset int) (int, int) {
+	before := s[:offset]
+	line := strings.Count(before, "\n") + 1
+	if i := strings.LastIndex(before, "\n"); i >= 0 {
+		before = before[i+1:]
+	}
+	return line, utf8.RuneCountInString(before) + 1
+}
+
+// placeholderFor 返回变量类型对应的占位值，用于离线校验查询语法
+func placeholderFor(v Variable) string {
+	switch v.Type {
+	case "number":
+		return "1"
+	case "boolean":
+		return "true"
+	case "enum":
+		if len(v.EnumValues) > 0 {
+			return v.EnumValues[0]
+		}
+	case "duration":
+		return "5m"
+	case "timestamp":
+		return "1700000000"
+	case "list":
+		return placeholderFor(Variable{Type: v.ElemType, EnumValues: v.EnumValues})
+	case "map":
+		return "key=" + placeholderFor(Variable{Type: v.ElemType, EnumValues: v.EnumValues})
+	}
+	return "placeholder"
+}
+
+// placeholderInput 为没有 value/default_value 的已声明变量生成占位输入
+func (tpl *Template) placeholderInput(ind *Indicator) map[string]string {
+	input := make(map[string]string)
+	for _, vars := range [][]Variable{tpl.Vars, ind.Vars} {
+		for _, v := range vars {
+			if pickRaw(v, nil) == "" {
+				input[v.Name] = placeholderFor(v)
+			}
+		}
+	}
+	return input
+}
+
+// validateIndicatorPromQL 使用占位变量渲染指标查询并离线解析，返回渲染或语法错误
+func (tpl *Template) validateIndicatorPromQL(ind *Indicator) error {
+	if ind.Source != SourcePrometheus {
+		return nil
+	}
+	query, err := tpl.RenderQueryWithVars(ind, tpl.placeholderInput(ind))
+	if err != nil {
+		return fmt.Errorf("indicator %s: render query: %w", ind.Name, err)
+	}
+	if _, err := parsePromQL(query); err != nil {
+		return newPromQLSyntaxError(ind.Name, query, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package inspection
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const strictTemplateYAML = `
+name: strict-test
+display_name: 严格解析测试
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: TimeRange
+    type: string
+    default_value: "{{if .IndicatorTimeRange}}{{.IndicatorTimeRange}}{{else}}{{.GlobalTimeRange}}{{end}}"
+  - name: Limit
+    type: number
+indicators:
+  - name: good
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: topk({{.Limit}}, max_over_time(up[{{.TimeRange}}]))
+    display: { type: table }
+  - name: broken
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: |
+      max by (instance) (
+        max_over_time(up{job=gpu}[{{.TimeRange}}])
+      )
+    display: { type: table }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [good]
+`
+
+func TestParseTemplateBytes_StrictPromQL(t *testing.T) {
+	// 默认模式下不校验 PromQL
+	if _, err := ParseTemplateBytes([]byte(strictTemplateYAML)); err != nil {
+		t.Fatalf("non-strict parse failed: %v", err)
+	}
+
+	_, err := ParseTemplateBytes([]byte(strictTemplateYAML), WithStrictPromQL())
+	if err == nil {
+		t.Fatal("expected strict parse to fail")
+	}
+
+	var syntaxErr *PromQLSyntaxError
+	if !errors.As(err, &syntaxErr) {
+		t.Fatalf("expected PromQLSyntaxError, got %T: %v", err, err)
+	}
+	if syntaxErr.Indicator != "broken" {
+		t.Errorf("expected indicator broken, got %s", syntaxErr.Indicator)
+	}
+	if syntaxErr.Line != 2 || syntaxErr.Column != 24 {
+		t.Errorf("unexpected position line %d column %d: %v", syntaxErr.Line, syntaxErr.Column, err)
+	}
+	// 位置需换算到 YAML 文件中的行列
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected exactly one ValidationError, got %v", err)
+	}
+	if errs[0].Path != "indicators[1].query" || errs[0].Line != 30 || errs[0].Column != 30 {
+		t.Errorf("unexpected yaml position %s line %d column %d", errs[0].Path, errs[0].Line, errs[0].Column)
+	}
+	if strings.Contains(err.Error(), "good") {
+		t.Errorf("indicator good should not be reported: %v", err)
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	query := "sum(\n  温度{a=\"b\"}"
+	offset := strings.Index(query, "{")
+	line, column := lineColumn(query, offset)
+	if line != 2 || column != 5 {
+		t.Errorf("expected 2:5, got %d:%d", line, column)
+	}
+}
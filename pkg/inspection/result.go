@@ -12,6 +12,8 @@ type Report struct {
 	SummaryOverviews []*SummaryOverview `json:"summary_overviews"`
 	Sections         []*Section         `json:"sections"`
 	Results          []*IndicatorResult `json:"results"`
+	// Changes 与上次报告相比的变化，由调用方通过 DiffReports 计算后设置
+	Changes *ReportDiff `json:"changes,omitempty"`
 }
 
 type SummaryOverview struct {
@@ -60,8 +62,9 @@ type HighlightInfo struct {
 }
 
 type ValueItem struct {
-	Target  string   `json:"target"`
-	Value   *float64 `json:"value"`
-	Status  string   `json:"status,omitempty"`
-	Missing bool     `json:"missing,omitempty"`
+	Target   string         `json:"target"`
+	Value    *float64       `json:"value"`
+	Status   string         `json:"status,omitempty"`
+	Missing  bool           `json:"missing,omitempty"`
+	Metadata map[string]any `json:"metadata,omitempty"` // 附加信息，如抓取目标的最近错误
 }
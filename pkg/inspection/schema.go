@@ -0,0 +1,238 @@
+package inspection
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//go:generate go run ../../cmd/inspection schema -o template/template.schema.json
+
+// JSONSchemaID 模板 JSON Schema 的标识
+const JSONSchemaID = "https://github.com/kekexiaoai/inspection/pkg/inspection/template/template.schema.json"
+
+// schemaFieldOverrides 补充无法从 validate 标签推导、在代码中校验的字段约束
+// key 为 "结构体名.字段名"
+var schemaFieldOverrides = map[string]map[string]any{
+	"HighlightConfig.Limit": {
+		"pattern":     validLimitPattern.String(),
+		"description": `高亮数量限制："all"、"top_N" 或 "bottom_N"`,
+	},
+	"Condition.Level": {
+		"enum": []string{ThresholdLevelCritical, ThresholdLevelWarning, ThresholdLevelInfo, ThresholdLevelOk},
+	},
+	"Condition.Operator": {
+		"enum": []string{OpGt, OpGte, OpLt, OpLte, OpEq},
+	},
+	"Variable.Value": {
+		"type":        []string{"string", "number", "boolean", "array", "object"},
+		"description": "变量值，list / map 类型的变量可直接写列表或映射",
+	},
+	"Variable.DefaultValue": {
+		"type":        []string{"string", "number", "boolean", "array", "object"},
+		"description": "变量默认值，list / map 类型的变量可直接写列表或映射",
+	},
+	"Template.TimeRange":   durationSchema,
+	"Indicator.TimeRange":  durationSchema,
+	"Indicator.Resolution": durationSchema,
+	"Schedule.Cron": {
+		"description": "标准 5 段 cron 表达式，如 \"0 9 * * *\"",
+	},
+}
+
+// durationSchema Prometheus 时长格式，与 model.ParseDuration 一致
+var durationSchema = map[string]any{
+	"pattern":     `^(0|(([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?)$`,
+	"description": "Prometheus 时长，如 30s、5m、1h30m、7d",
+}
+
+// JSONSchema 根据 Template 的 yaml / validate 结构体标签生成模板格式的 JSON Schema（draft-07）
+func JSONSchema() map[string]any {
+	g := &schemaGenerator{defs: make(map[string]any)}
+	root := g.structSchema(reflect.TypeOf(Template{}))
+	root["$schema"] = "http://json-schema.org/draft-07/schema#"
+	root["$id"] = JSONSchemaID
+	root["title"] = "Inspection Template"
+	root["definitions"] = g.defs
+
+	// 组合模板（extends / use）中的必填字段可以由父模板或指标库提供
+	relaxRequired(root, keyExtends)
+	relaxRequired(g.defs["Indicator"].(map[string]any), keyUse)
+	return root
+}
+
+// relaxRequired 仅在未声明 key 时要求 required 字段
+func relaxRequired(schema map[string]any, key string) {
+	required, ok := schema["required"]
+	if !ok {
+		return
+	}
+	delete(schema, "required")
+	schema["if"] = map[string]any{"not": map[string]any{"required": []string{key}}}
+	schema["then"] = map[string]any{"required": required}
+}
+
+// JSONSchemaBytes 返回格式化后的 JSON Schema
+func JSONSchemaBytes() ([]byte, error) {
+	data, err := json.MarshalIndent(JSONSchema(), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+type schemaGenerator struct {
+	defs map[string]any
+}
+
+// structSchema 生成结构体的 object schema
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	var required []string
+
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+
+		rules := splitValidateTag(f.Tag.Get("validate"))
+		if hasRule(rules, "required") {
+			required = append(required, name)
+		}
+
+		prop := g.typeSchema(f.Type, rules)
+		if def := f.Tag.Get("default"); def != "" {
+			prop["default"] = def
+		}
+		for k, v := range schemaFieldOverrides[t.Name()+"."+f.Name] {
+			prop[k] = v
+		}
+		properties[name] = prop
+	}
+
+	schema := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// typeSchema 生成字段类型的 schema，rules 为当前层级适用的 validate 规则
+func (g *schemaGenerator) typeSchema(t reflect.Type, rules []string) map[string]any {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// dive 之前的规则作用于容器本身，之后的规则作用于元素
+	own, elem := rules, []string(nil)
+	for i, r := range rules {
+		if r == "dive" {
+			own, elem = rules[:i], rules[i+1:]
+			break
+		}
+	}
+
+	var schema map[string]any
+	switch t.Kind() {
+	case reflect.Struct:
+		g.define(t)
+		schema = map[string]any{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Slice, reflect.Array:
+		schema = map[string]any{
+			"type":  "array",
+			"items": g.typeSchema(t.Elem(), elem),
+		}
+	case reflect.Map:
+		schema = map[string]any{"type": "object"}
+		if t.Elem().Kind() != reflect.Interface {
+			schema["additionalProperties"] = g.typeSchema(t.Elem(), elem)
+		}
+	case reflect.String:
+		schema = map[string]any{"type": "string"}
+	case reflect.Bool:
+		schema = map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		schema = map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		schema = map[string]any{"type": "number"}
+	default:
+		// any：不限制类型
+		schema = map[string]any{}
+	}
+
+	applyRules(schema, t.Kind(), own)
+	return schema
+}
+
+// define 将结构体加入 definitions，已存在时跳过
+func (g *schemaGenerator) define(t reflect.Type) {
+	if _, ok := g.defs[t.Name()]; ok {
+		return
+	}
+	g.defs[t.Name()] = nil // 占位，避免递归结构重复生成
+	g.defs[t.Name()] = g.structSchema(t)
+}
+
+// applyRules 将 validate 规则转换为 schema 约束
+func applyRules(schema map[string]any, kind reflect.Kind, rules []string) {
+	for _, r := range rules {
+		name, param, _ := strings.Cut(r, "=")
+		switch name {
+		case "oneof":
+			schema["enum"] = strings.Fields(param)
+		case "min":
+			n, err := strconv.Atoi(param)
+			if err != nil {
+				continue
+			}
+			switch kind {
+			case reflect.Slice, reflect.Array:
+				schema["minItems"] = n
+			case reflect.Map:
+				schema["minProperties"] = n
+			case reflect.String:
+				schema["minLength"] = n
+			default:
+				schema["minimum"] = n
+			}
+		case "required":
+			// 元素级 required（如 dive,required）：字符串不能为空
+			if kind == reflect.String {
+				schema["minLength"] = 1
+			}
+		}
+	}
+}
+
+func splitValidateTag(tag string) []string {
+	if tag == "" {
+		return nil
+	}
+	return strings.Split(tag, ",")
+}
+
+func hasRule(rules []string, name string) bool {
+	for _, r := range rules {
+		if r == "dive" {
+			return false
+		}
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package inspection
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestJSONSchema_UpToDate(t *testing.T) {
+	want, err := JSONSchemaBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile("template/template.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatal("template/template.schema.json is out of date, run `go generate ./pkg/inspection`")
+	}
+}
+
+func TestJSONSchema_Constraints(t *testing.T) {
+	data, err := JSONSchemaBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema struct {
+		Then struct {
+			Required []string `json:"required"`
+		} `json:"then"`
+		Definitions map[string]struct {
+			Required   []string                  `json:"required"`
+			Properties map[string]map[string]any `json:"properties"`
+		} `json:"definitions"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatal(err)
+	}
+
+	// 使用 extends 时必填字段可由父模板提供
+	if !reflect.DeepEqual(schema.Then.Required[:2], []string{"name", "display_name"}) {
+		t.Errorf("unexpected root required: %v", schema.Then.Required)
+	}
+
+	cases := []struct {
+		def, prop, key string
+		want           any
+	}{
+		{"Indicator", "type", "enum", []any{"point", "range", "trend", "alert_list"}},
+		{"Threshold", "operator", "enum", []any{"gt", "gte", "lt", "lte", "eq"}},
+		{"Condition", "level", "enum", []any{"critical", "warning", "info", "ok"}},
+		{"HighlightConfig", "limit", "pattern", validLimitPattern.String()},
+		{"HighlightConfig", "logic", "default", "or"},
+		{"Display", "page_size", "minimum", float64(1)},
+	}
+	for _, c := range cases {
+		def, ok := schema.Definitions[c.def]
+		if !ok {
+			t.Errorf("missing definition %s", c.def)
+			continue
+		}
+		if got := def.Properties[c.prop][c.key]; !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s.%s.%s = %v, want %v", c.def, c.prop, c.key, got, c.want)
+		}
+	}
+
+	if got := schema.Definitions["Threshold"].Required; !reflect.DeepEqual(got, []string{"level", "value", "operator", "description"}) {
+		t.Errorf("unexpected Threshold required: %v", got)
+	}
+}
@@ -0,0 +1,169 @@
+package inspection
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// source: targets 指标直接检查 Prometheus 的抓取目标（/api/v1/targets），不执行 PromQL：
+//
+//	- name: 抓取健康
+//	  source: targets
+//	  exporter: node_exporter   # scrapePool，"*" 表示所有 pool
+//	  type: point
+//	  query: health             # health / scrape_duration / staleness / dropped
+//	  thresholds:
+//	    - { level: critical, operator: lt, value: 1 }
+//
+// 除 dropped 外每个目标一项，值分别为健康（up 1 / down 0）、最近一次抓取耗时与距最近一次抓取的秒数，
+// 抓取地址、最近错误等放在 ValueItem.Metadata 中；dropped 每个 scrapePool 一项，值为被丢弃的目标数。
+
+// targetsQueries source: targets 支持的查询
+var targetsQueries = map[string]bool{
+	TargetsQueryHealth:         true,
+	TargetsQueryScrapeDuration: true,
+	TargetsQueryStaleness:      true,
+	TargetsQueryDropped:        true,
+}
+
+// targetsIndicatorProblems 检查 source: targets 指标的查询与类型
+func targetsIndicatorProblems(ind *Indicator) []*ValidationError {
+	if ind.Source != SourceTargets {
+		return nil
+	}
+	var problems []*ValidationError
+	if query, ok := ind.Query.(string); !ok || (!containsTpl(query) && !targetsQueries[strings.TrimSpace(query)]) {
+		problems = append(problems, newValidationError("query",
+			fmt.Sprintf("source 为 targets 时 query 只能是 %s", targetsQueryNames()),
+			fmt.Sprintf("query of a targets indicator must be one of %s", targetsQueryNames())))
+	}
+	if ind.Type != IndicatorTypePoint {
+		problems = append(problems, newValidationError("type",
+			"source 为 targets 时 type 只能是 point",
+			"type of a targets indicator must be point"))
+	}
+	return problems
+}
+
+func targetsQueryNames() string {
+	names := make([]string, 0, len(targetsQueries))
+	for name := range targetsQueries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// evalTargetsIndicator 根据抓取目标计算 source: targets 指标的结果，now 用于计算 staleness
+func evalTargetsIndicator(ind *Indicator, query string, targets v1.TargetsResult, history *statusHistory, now time.Time) (*IndicatorResult, error) {
+	query = strings.TrimSpace(query)
+	if !targetsQueries[query] {
+		return nil, fmt.Errorf("unsupported targets query %q, want one of %s", query, targetsQueryNames())
+	}
+	allPools := ind.Exporter == AllPools
+	inPool := func(pool string) bool { return allPools || pool == ind.Exporter }
+
+	h, _ := NewJSONResultHandler(ind, nil)
+	h.history = history
+	if query == TargetsQueryDropped {
+		active := make(map[string]int)
+		for _, target := range targets.Active {
+			if inPool(target.ScrapePool) {
+				active[target.ScrapePool]++
+			}
+		}
+		dropped := make(map[string]int)
+		for _, target := range targets.Dropped {
+			// 被丢弃的目标没有 scrapePool，使用发现标签中的 job，即 scrape 配置的 job_name
+			if pool := target.DiscoveredLabels["job"]; inPool(pool) {
+				dropped[pool]++
+			}
+		}
+		pools := make([]string, 0, len(active)+len(dropped))
+		for pool := range active {
+			pools = append(pools, pool)
+		}
+		for pool := range dropped {
+			if _, ok := active[pool]; !ok {
+				pools = append(pools, pool)
+			}
+		}
+		sort.Strings(pools)
+		for _, pool := range pools {
+			value := float64(dropped[pool])
+			h.addTargetItem(pool, &value, map[string]any{"active": active[pool]})
+		}
+		return h.Finalize()
+	}
+
+	for _, target := range targets.Active {
+		if !inPool(target.ScrapePool) {
+			continue
+		}
+		name := h.extractTarget(target.Labels)
+		if allPools {
+			// 同一实例可能出现在多个 pool 中
+			name = target.ScrapePool + "/" + name
+		}
+		h.addTargetItem(name, targetValue(query, target, now), targetMetadata(target))
+	}
+	return h.Finalize()
+}
+
+// targetValue 计算单个目标的值，无法计算时返回 nil（记为缺失）
+func targetValue(query string, target v1.ActiveTarget, now time.Time) *float64 {
+	var value float64
+	switch query {
+	case TargetsQueryHealth:
+		switch target.Health {
+		case v1.HealthGood:
+			value = 1
+		case v1.HealthBad:
+			value = 0
+		default:
+			return nil
+		}
+	case TargetsQueryScrapeDuration:
+		if target.LastScrape.IsZero() {
+			return nil
+		}
+		value = target.LastScrapeDuration
+	case TargetsQueryStaleness:
+		if target.LastScrape.IsZero() {
+			return nil
+		}
+		value = now.Sub(target.LastScrape).Seconds()
+	}
+	return &value
+}
+
+// targetMetadata 目标的附加信息
+func targetMetadata(target v1.ActiveTarget) map[string]any {
+	md := map[string]any{
+		"scrape_pool": target.ScrapePool,
+		"scrape_url":  target.ScrapeURL,
+		"health":      string(target.Health),
+	}
+	if !target.LastScrape.IsZero() {
+		md["last_scrape"] = target.LastScrape
+		md["last_scrape_duration"] = target.LastScrapeDuration
+	}
+	if target.LastError != "" {
+		md["last_error"] = target.LastError
+	}
+	return md
+}
+
+// addTargetItem 添加带附加信息的数据项，value 为 nil 时记为缺失
+func (h *JSONResultHandler) addTargetItem(target string, value *float64, metadata map[string]any) {
+	if value == nil {
+		h.addValueItem(target, nil, true)
+	} else {
+		h.addValueItem(target, value, false, h.determineStatus(*value))
+	}
+	h.result.Values[len(h.result.Values)-1].Metadata = metadata
+}
@@ -0,0 +1,156 @@
+package inspection
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
+)
+
+const targetsTemplateYAML = `
+name: targets-test
+display_name: 抓取目标
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+data_center: { id: dc-1 }
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+indicators:
+  - name: health
+    source: targets
+    exporter: node_exporter
+    type: point
+    query: health
+    thresholds:
+      - { level: critical, value: 1, operator: lt, description: 抓取失败 }
+    display: { type: table }
+  - name: scrape_duration
+    source: targets
+    exporter: node_exporter
+    type: point
+    query: scrape_duration
+    thresholds:
+      - { level: warning, value: 1, operator: gt, description: 抓取慢 }
+    display: { type: table }
+  - name: staleness
+    source: targets
+    exporter: "*"
+    type: point
+    query: staleness
+    thresholds:
+      - { level: critical, value: 300, operator: gt, description: 长时间未抓取 }
+    display: { type: table }
+  - name: dropped
+    source: targets
+    exporter: "*"
+    type: point
+    query: dropped
+    thresholds:
+      - { level: warning, value: 0, operator: gt, description: 有目标被丢弃 }
+    display: { type: table }
+report_layout:
+  sections:
+    - title: all
+      Indicators: [health, scrape_duration, staleness, dropped]
+`
+
+func TestExecutor_TargetsSource(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	srv := promtest.NewServer(t,
+		promtest.WithTargets(
+			v1.ActiveTarget{
+				ScrapePool: "node_exporter", ScrapeURL: "http://10.0.0.1:9100/metrics", Health: v1.HealthGood,
+				Labels:     model.LabelSet{"job": "node_exporter", "instance": "10.0.0.1:9100"},
+				LastScrape: now.Add(-10 * time.Second), LastScrapeDuration: 0.2,
+			},
+			v1.ActiveTarget{
+				ScrapePool: "node_exporter", ScrapeURL: "http://10.0.0.2:9100/metrics", Health: v1.HealthBad,
+				Labels:     model.LabelSet{"job": "node_exporter", "instance": "10.0.0.2:9100"},
+				LastScrape: now.Add(-10 * time.Minute), LastScrapeDuration: 3,
+				LastError: "context deadline exceeded",
+			},
+			v1.ActiveTarget{
+				ScrapePool: "gpu_exporter", Health: v1.HealthUnknown,
+				Labels: model.LabelSet{"job": "gpu_exporter", "instance": "10.0.0.1:9400"},
+			},
+		),
+		promtest.WithDroppedTargets(
+			v1.DroppedTarget{DiscoveredLabels: map[string]string{"job": "node_exporter", "__address__": "10.0.0.9:9100"}},
+			v1.DroppedTarget{DiscoveredLabels: map[string]string{"job": "blackbox", "__address__": "10.0.0.9:9115"}},
+		),
+	)
+	client, err := prom.NewClient(srv.URL, prom.WithTimeout(10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+
+	tpl, err := ParseTemplateBytes([]byte(targetsTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := NewExecutor(client, nil, WithNow(func() time.Time { return now })).Execute(context.Background(), tpl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Results) != 4 {
+		t.Fatalf("got %d results, want 4", len(report.Results))
+	}
+
+	assertItems(t, report.Results[0], map[string]string{
+		"10.0.0.1:9100": ThresholdLevelOk,
+		"10.0.0.2:9100": ThresholdLevelCritical,
+	})
+	down := report.Results[0].Values[1]
+	if down.Target != "10.0.0.2:9100" || down.Metadata["last_error"] != "context deadline exceeded" ||
+		down.Metadata["scrape_url"] != "http://10.0.0.2:9100/metrics" {
+		t.Errorf("unexpected metadata of %s: %v", down.Target, down.Metadata)
+	}
+
+	assertItems(t, report.Results[1], map[string]string{
+		"10.0.0.1:9100": ThresholdLevelOk,
+		"10.0.0.2:9100": ThresholdLevelWarning,
+	})
+
+	// 所有 pool 的目标以 pool/instance 命名，未抓取过的目标记为缺失
+	assertItems(t, report.Results[2], map[string]string{
+		"node_exporter/10.0.0.1:9100": ThresholdLevelOk,
+		"node_exporter/10.0.0.2:9100": ThresholdLevelCritical,
+		"gpu_exporter/10.0.0.1:9400":  "missing",
+	})
+
+	assertItems(t, report.Results[3], map[string]string{
+		"blackbox":      ThresholdLevelWarning,
+		"gpu_exporter":  ThresholdLevelOk,
+		"node_exporter": ThresholdLevelWarning,
+	})
+	for _, item := range report.Results[3].Values {
+		if item.Target == "node_exporter" && (*item.Value != 1 || item.Metadata["active"] != 2) {
+			t.Errorf("node_exporter: value %v, metadata %v; want 1 dropped, 2 active", *item.Value, item.Metadata)
+		}
+	}
+}
+
+func TestParseTemplate_TargetsIndicator(t *testing.T) {
+	data := strings.Replace(targetsTemplateYAML, "query: health", "query: up", 1)
+	data = strings.Replace(data, "type: point\n    query: dropped", "type: range\n    query: dropped", 1)
+	errs := ValidateTemplateBytes([]byte(data))
+	want := []string{"indicators[0].query", "indicators[3].type"}
+	if len(errs) != len(want) {
+		t.Fatalf("got %d errors, want %d: %v", len(errs), len(want), errs)
+	}
+	for i, path := range want {
+		if errs[i].Path != path {
+			t.Errorf("error %d: path %q, want %q", i, errs[i].Path, path)
+		}
+	}
+}
@@ -2,17 +2,23 @@ package inspection
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
-	"text/template"
+	"time"
 
 	"github.com/go-playground/validator/v10"
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 	"github.com/robfig/cron/v3"
 	"gopkg.in/yaml.v3"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
 )
 
 const (
@@ -36,13 +42,18 @@ type Template struct {
 	Version        string         `yaml:"version"`
 	CreatedBy      string         `yaml:"created_by"`
 	Schedule       Schedule       `yaml:"schedule" validate:"required"`
-	TimeRange      string         `yaml:"time_range" validate:"required"`
+	TimeRange      string         `yaml:"time_range" validate:"required,promduration"`
 	Tags           []string       `yaml:"tags"`
 	TargetRegistry TargetRegistry `yaml:"target_registry" validate:"required"`
 	Vars           []Variable     `yaml:"vars" validate:"dive"` // 全局变量
 	Indicators     []*Indicator   `yaml:"indicators" validate:"required,min=1,dive"`
 	ReportLayout   ReportLayout   `yaml:"report_layout" validate:"required"`
 	DataCenter     DataCenter     `yaml:"data_center"`
+
+	// 模板组合，解析时展开，见 compose.go
+	Extends  string       `yaml:"extends"` // 父模板路径
+	Includes []string     `yaml:"include"` // 引入的变量 / 指标库片段路径
+	Library  []*Indicator `yaml:"library"` // 指标库，供 indicators 中的 use 引用
 }
 
 // SortIndicatorThresholds 解析模板后调用，对阈值按优先级排序
@@ -55,6 +66,45 @@ func (tpl *Template) SortIndicatorThresholds() {
 	}
 }
 
+// TimeRangeDuration 返回模板全局时间范围（解析时已校验格式）
+func (tpl *Template) TimeRangeDuration() time.Duration {
+	return promDuration(tpl.TimeRange)
+}
+
+// EffectiveTimeRange 返回指标实际使用的时间范围：指标 time_range 优先，未配置时使用模板 time_range
+func (tpl *Template) EffectiveTimeRange(ind *Indicator) time.Duration {
+	if d := ind.TimeRangeDuration(); d > 0 {
+		return d
+	}
+	return tpl.TimeRangeDuration()
+}
+
+// 范围查询的默认步长，以及 Prometheus 单次范围查询允许的最大点数
+const (
+	defaultRangeStep = time.Minute
+	maxRangePoints   = 11000
+)
+
+// QueryRange 构造指标在 end 时刻的范围查询区间：
+// 区间长度为 EffectiveTimeRange，步长为指标 resolution，未配置时默认 1m，
+// 并在点数超过 Prometheus 限制时自动放大步长
+func (tpl *Template) QueryRange(ind *Indicator, end time.Time) v1.Range {
+	window := tpl.EffectiveTimeRange(ind)
+	return prom.NewRange(end.Add(-window), end, rangeStep(ind, window))
+}
+
+// rangeStep 计算时间窗口为 window 的范围查询步长
+func rangeStep(ind *Indicator, window time.Duration) time.Duration {
+	step := ind.ResolutionDuration()
+	if step <= 0 {
+		step = defaultRangeStep
+		if minStep := window / maxRangePoints; step < minStep {
+			step = minStep.Truncate(time.Second) + time.Second
+		}
+	}
+	return step
+}
+
 type DataCenter struct {
 	ID   string `yaml:"id"`
 	Name string `yaml:"name"`
@@ -74,17 +124,37 @@ type TargetRegistry struct {
 type Indicator struct {
 	Name        string       `yaml:"name" validate:"required"`
 	Description string       `yaml:"description"`
-	Source      string       `yaml:"source" validate:"required,oneof=prometheus elasticsearch metadata"`
+	Source      string       `yaml:"source" validate:"required,oneof=prometheus elasticsearch metadata targets"`
 	Exporter    string       `yaml:"exporter" validate:"required"`
 	Enabled     *bool        `yaml:"enabled"`
 	Type        string       `yaml:"type"   validate:"required,oneof=point range trend alert_list"`
 	Query       any          `yaml:"query" validate:"required"`
-	TimeRange   string       `yaml:"time_range"`
-	Resolution  string       `yaml:"resolution"`
+	TimeRange   string       `yaml:"time_range" validate:"omitempty,promduration"`
+	Resolution  string       `yaml:"resolution" validate:"omitempty,promduration"`
 	Thresholds  []*Threshold `yaml:"thresholds" validate:"dive"`
 	Required    bool         `yaml:"required"`
 	Display     Display      `yaml:"display" validate:"required"`
 	Vars        []Variable   `yaml:"vars" validate:"dive"`
+	Use         string       `yaml:"use"` // 引用的指标库条目名，解析时展开
+}
+
+// TimeRangeDuration 返回指标的时间范围，未配置时返回 0
+func (ind *Indicator) TimeRangeDuration() time.Duration {
+	return promDuration(ind.TimeRange)
+}
+
+// ResolutionDuration 返回指标范围查询的步长，未配置时返回 0
+func (ind *Indicator) ResolutionDuration() time.Duration {
+	return promDuration(ind.Resolution)
+}
+
+// promDuration 解析 Prometheus 时长，空值或格式错误时返回 0
+func promDuration(s string) time.Duration {
+	d, err := model.ParseDuration(s)
+	if err != nil {
+		return 0
+	}
+	return time.Duration(d)
 }
 
 /*
@@ -133,20 +203,22 @@ func meetsCondition(value float64, op string, threshold float64) bool {
 }
 
 type Threshold struct {
-	Level       string   `yaml:"level" validate:"required,oneof=critical warning info ok"` // 状态级别
-	Value       *float64 `yaml:"value" validate:"required"`                                // 阈值数值
-	Operator    string   `yaml:"operator" validate:"required,oneof=gt gte lt lte eq"`      // 运算符
-	Description string   `yaml:"description" validate:"required"`                          // 状态描述
+	Level       string   `yaml:"level" json:"level" validate:"required,oneof=critical warning info ok"` // 状态级别
+	Value       *float64 `yaml:"value" json:"value" validate:"required"`                                // 阈值数值
+	Operator    string   `yaml:"operator" json:"operator" validate:"required,oneof=gt gte lt lte eq"`   // 运算符
+	Description string   `yaml:"description" json:"description" validate:"required"`                    // 状态描述
 }
 
 type Variable struct {
 	Name         string   `yaml:"name" validate:"required"`
-	Type         string   `yaml:"type" validate:"required,oneof=string number boolean enum"`
+	Type         string   `yaml:"type" validate:"required,oneof=string number boolean enum duration timestamp list map"`
 	Required     bool     `yaml:"required"`
-	Value        string   `yaml:"value"`
-	DefaultValue string   `yaml:"default_value"`
+	Value        VarValue `yaml:"value"`
+	DefaultValue VarValue `yaml:"default_value"`
 	Description  string   `yaml:"description"`
 	EnumValues   []string `yaml:"enum_values"`
+	// ElemType list 的元素类型、map 的值类型，为空时不校验
+	ElemType string `yaml:"elem_type" validate:"omitempty,oneof=string number boolean enum duration timestamp"`
 }
 
 type Display struct {
@@ -170,28 +242,46 @@ type HighlightConfig struct {
 // 预编译 Limit 格式验证正则表达式
 var validLimitPattern = regexp.MustCompile(`^(all|top_\d+|bottom_\d+)$`)
 
-// Validate 验证 HighlightConfig 结构
+// Validate 验证 HighlightConfig 结构，返回发现的第一个问题
 func (h *HighlightConfig) Validate() error {
+	if problems := h.problems(); len(problems) > 0 {
+		return errors.New(problems[0].MessageEN)
+	}
+	return nil
+}
+
+// problems 返回 HighlightConfig 的所有问题，路径相对于 highlight 节点
+func (h *HighlightConfig) problems() []*ValidationError {
 	// 若未启用，无需验证其他字段
 	if !h.Enabled {
 		return nil
 	}
 
+	var problems []*ValidationError
+
 	// 验证 Limit 格式
 	if h.Limit != "" && !validLimitPattern.MatchString(h.Limit) {
-		return fmt.Errorf("invalid highlight limit format: %s", h.Limit)
+		problems = append(problems, newValidationError("limit",
+			fmt.Sprintf("高亮 limit 格式无效: %s（允许 all、top_N、bottom_N）", h.Limit),
+			fmt.Sprintf("invalid highlight limit format: %s", h.Limit)))
 	}
 
 	// 验证 Conditions
 	if len(h.Conditions) == 0 {
-		return fmt.Errorf("highlight is enabled but no conditions specified")
+		problems = append(problems, newValidationError("conditions",
+			"已启用高亮但未配置任何条件",
+			"highlight is enabled but no conditions specified"))
 	}
 
 	for i, cond := range h.Conditions {
+		path := fmt.Sprintf("conditions[%d]", i)
+
 		// 验证 Level
 		if cond.Level != "" {
 			if _, ok := ThresholdLevelPriorities[cond.Level]; !ok {
-				return fmt.Errorf("invalid level in condition %d: %s", i, cond.Level)
+				problems = append(problems, newValidationError(path+".level",
+					fmt.Sprintf("条件 %d 的级别无效: %s", i, cond.Level),
+					fmt.Sprintf("invalid level in condition %d: %s", i, cond.Level)))
 			}
 		}
 
@@ -205,23 +295,43 @@ func (h *HighlightConfig) Validate() error {
 				}
 			}
 			if !valid {
-				return fmt.Errorf("invalid operator in condition %d: %s", i, cond.Operator)
+				problems = append(problems, newValidationError(path+".operator",
+					fmt.Sprintf("条件 %d 的运算符无效: %s", i, cond.Operator),
+					fmt.Sprintf("invalid operator in condition %d: %s", i, cond.Operator)))
 			}
 		}
 
+		// 验证 Field
+		switch cond.Field {
+		case "", ConditionFieldValue, ConditionFieldConsecutive, ConditionFieldFlaps:
+			if cond.Field != "" && cond.Operator == "" {
+				problems = append(problems, newValidationError(path+".field",
+					fmt.Sprintf("条件 %d 配置了 field 但未配置 operator 和 value", i),
+					fmt.Sprintf("field requires operator and value in condition %d", i)))
+			}
+		default:
+			problems = append(problems, newValidationError(path+".field",
+				fmt.Sprintf("条件 %d 的字段无效: %s（允许 value、consecutive、flaps）", i, cond.Field),
+				fmt.Sprintf("invalid field in condition %d: %s", i, cond.Field)))
+		}
+
 		// 验证 value 和 Operator 必须同时存在或同时不存在
 		if (cond.Value != nil) != (cond.Operator != "") {
-			return fmt.Errorf("value and operator must be specified together in condition %d", i)
+			problems = append(problems, newValidationError(path,
+				fmt.Sprintf("条件 %d 的 value 和 operator 必须同时配置", i),
+				fmt.Sprintf("value and operator must be specified together in condition %d", i)))
 		}
 	}
 
-	return nil
+	return problems
 }
 
 type Condition struct {
 	Level    string   `yaml:"level"`    // 支持 critical/warning/info/ok
 	Value    *float64 `yaml:"value"`    // 可选：数值阈值
 	Operator string   `yaml:"operator"` // 可选：gt/gte/lt/lte/eq
+	// 可选：operator 比较的字段，默认为数据项的值；consecutive、flaps 取自状态历史，见 history.go
+	Field string `yaml:"field" validate:"omitempty,oneof=value consecutive flaps"`
 }
 type ReportLayout struct {
 	Sections []*Section `yaml:"sections" json:"sections" validate:"required,min=1,dive"`
@@ -244,35 +354,99 @@ func init() {
 		_, err := cron.ParseStandard(fl.Field().String())
 		return err == nil
 	})
+	_ = validate.RegisterValidation("promduration", func(fl validator.FieldLevel) bool {
+		_, err := model.ParseDuration(fl.Field().String())
+		return err == nil
+	})
 }
 
 // -----------------------------------------------------------------------------
 // Parsing helpers
 // -----------------------------------------------------------------------------
 
-func ParseTemplateFile(path string) (*Template, error) {
+// ParseOption 配置模板解析行为
+type ParseOption func(*parseConfig)
+
+type parseConfig struct {
+	strictPromQL bool
+	source       string // 模板文件路径，用于组合时的循环检测与错误定位
+	baseDir      string // extends / include 相对路径的解析目录
+}
+
+// WithStrictPromQL 开启严格解析：使用占位变量渲染每个 prometheus 指标的查询，
+// 并用 Prometheus 的 PromQL 解析器离线校验语法（不访问 Prometheus）
+func WithStrictPromQL() ParseOption {
+	return func(c *parseConfig) {
+		c.strictPromQL = true
+	}
+}
+
+// WithBaseDir 指定 extends / include 中相对路径的解析目录
+// ParseTemplateFile 默认使用模板文件所在目录，ParseTemplateBytes 默认使用当前工作目录
+func WithBaseDir(dir string) ParseOption {
+	return func(c *parseConfig) {
+		c.baseDir = dir
+	}
+}
+
+func ParseTemplateFile(path string, opts ...ParseOption) (*Template, error) {
 	byts, err := os.ReadFile(path)
 	if err != nil {
 		return nil, fmt.Errorf("read template: %w", err)
 	}
-	return ParseTemplateBytes(byts)
+	source, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template: %w", err)
+	}
+	opts = append([]ParseOption{func(c *parseConfig) {
+		c.source = source
+		c.baseDir = filepath.Dir(source)
+	}}, opts...)
+	return ParseTemplateBytes(byts, opts...)
 }
 
-func ParseTemplateBytes(data []byte) (*Template, error) {
-	var tpl Template
-	if err := yaml.Unmarshal(data, &tpl); err != nil {
+func ParseTemplateBytes(data []byte, opts ...ParseOption) (*Template, error) {
+	var cfg parseConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
 		return nil, fmt.Errorf("yaml unmarshal: %w", err)
 	}
 
+	// 展开 extends / include / use
+	comp := newComposer()
+	merged, err := comp.resolveTemplate(&root, cfg.source, cfg.baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("template composition: %w", err)
+	}
+
+	// 收集模板中的所有错误，并定位到 YAML 行列
+	collector := newValidationCollector(merged, data)
+	collector.files, collector.source = comp.files, cfg.source
+
+	var tpl Template
+	if err := merged.Decode(&tpl); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("yaml unmarshal: %w", err)
+		}
+		for _, msg := range typeErr.Errors {
+			collector.addYAMLTypeError(msg)
+		}
+	}
+
 	// 补全 HighlightConfig.Logic 的默认值
 	for _, ind := range tpl.Indicators {
-		if ind.Display.Highlight.Logic == "" {
+		if ind != nil && ind.Display.Highlight.Logic == "" {
 			ind.Display.Highlight.Logic = "or" // 默认 or 逻辑
 		}
 	}
 
 	if err := validate.Struct(tpl); err != nil {
-		return nil, fmt.Errorf("template validation: %w", err)
+		collector.addFieldErrors(err)
 	}
 
 	// 暂时强制 yaml 填写的时候，thresholds 按优先级排序
@@ -280,20 +454,35 @@ func ParseTemplateBytes(data []byte) (*Template, error) {
 	// 自动排序阈值
 	// tpl.SortIndicatorThresholds()
 	// 验证每个指标的阈值顺序和高亮配置
-	for _, ind := range tpl.Indicators {
+	for i, ind := range tpl.Indicators {
+		if ind == nil {
+			continue
+		}
 		// 未指定 enabled 时，默认开启
 		if ind.Enabled == nil {
 			ind.Enabled = new(bool)
 			*ind.Enabled = true
 		}
-		if err := validateThresholdOrder(ind); err != nil {
-			return nil, fmt.Errorf("indicator %s: %w", ind.Name, err)
-		}
-		if err := ind.Display.Highlight.Validate(); err != nil {
-			return nil, fmt.Errorf("indicator %s highlight invalid: %w", ind.Name, err)
+		prefix := fmt.Sprintf("indicators[%d]", i)
+		collector.addPrefixed(prefix, thresholdOrderProblems(ind))
+		collector.addPrefixed(prefix+".display.highlight", ind.Display.Highlight.problems())
+		collector.addPrefixed(prefix, targetsIndicatorProblems(ind))
+	}
+
+	if cfg.strictPromQL {
+		for i, ind := range tpl.Indicators {
+			if ind == nil {
+				continue
+			}
+			if err := tpl.validateIndicatorPromQL(ind); err != nil {
+				collector.addPromQLError(fmt.Sprintf("indicators[%d].query", i), err)
+			}
 		}
 	}
 
+	if len(collector.errs) > 0 {
+		return nil, collector.errs
+	}
 	return &tpl, nil
 }
 
@@ -309,13 +498,47 @@ func pickRaw(v Variable, input map[string]string) string {
 		return val
 	}
 	if v.Value != "" {
-		return v.Value
+		return string(v.Value)
 	}
-	return v.DefaultValue
+	return string(v.DefaultValue)
 }
 
-func validateVarType(v Variable, val string) error {
+// validateVarType 校验变量的原始值并转换为模板上下文中的值：
+// list 转换为 []string，map 转换为 map[string]string，其它类型保持字符串
+func validateVarType(v Variable, val string) (any, error) {
 	switch v.Type {
+	case "list":
+		items, err := parseListValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s must be list: %w", v.Name, err)
+		}
+		for i, item := range items {
+			if err := validateScalar(v, v.ElemType, item); err != nil {
+				return nil, fmt.Errorf("variable %s[%d]: %w", v.Name, i, err)
+			}
+		}
+		return items, nil
+	case "map":
+		m, err := parseMapValue(val)
+		if err != nil {
+			return nil, fmt.Errorf("variable %s must be map: %w", v.Name, err)
+		}
+		for _, k := range sortedKeys(m) {
+			if err := validateScalar(v, v.ElemType, m[k]); err != nil {
+				return nil, fmt.Errorf("variable %s[%s]: %w", v.Name, k, err)
+			}
+		}
+		return m, nil
+	}
+	if err := validateScalar(v, v.Type, val); err != nil {
+		return nil, err
+	}
+	return val, nil
+}
+
+// validateScalar 按 typ 校验单个值，list / map 的元素也使用此函数校验
+func validateScalar(v Variable, typ, val string) error {
+	switch typ {
 	case "number":
 		if _, err := strconv.ParseFloat(val, 64); err != nil {
 			return fmt.Errorf("variable %s must be number", v.Name)
@@ -335,48 +558,69 @@ func validateVarType(v Variable, val string) error {
 		if !allowed {
 			return fmt.Errorf("variable %s must be one of %v, got %s", v.Name, v.EnumValues, val)
 		}
+	case "duration":
+		if _, err := model.ParseDuration(val); err != nil {
+			return fmt.Errorf("variable %s must be duration like 5m or 1h30m: %w", v.Name, err)
+		}
+	case "timestamp":
+		if _, err := parseTimestamp(val); err != nil {
+			return fmt.Errorf("variable %s must be RFC3339 time or unix seconds: %w", v.Name, err)
+		}
 	}
 	return nil
 }
 
-// validateThresholdOrder 验证阈值顺序：禁止相同级别，且必须按优先级排列
-func validateThresholdOrder(ind *Indicator) error {
+// parseTimestamp 解析 timestamp 变量：RFC3339 时间或秒级 unix 时间戳（可带小数）
+func parseTimestamp(val string) (time.Time, error) {
+	if sec, err := strconv.ParseFloat(val, 64); err == nil {
+		whole := int64(sec)
+		return time.Unix(whole, int64((sec-float64(whole))*1e9)), nil
+	}
+	return time.Parse(time.RFC3339, val)
+}
+
+// thresholdOrderProblems 验证阈值顺序：禁止相同级别，且必须按优先级排列，返回所有问题
+// 返回的错误路径相对于指标本身
+func thresholdOrderProblems(ind *Indicator) []*ValidationError {
+	var problems []*ValidationError
 	// 记录已出现的级别，确保唯一
 	seenLevels := make(map[string]bool)
 	lastPriority := 0 // 初始化为最低优先级
 
-	for _, th := range ind.Thresholds {
-		// 1. 检查级别是否合法
+	for i, th := range ind.Thresholds {
+		if th == nil {
+			continue
+		}
+		path := fmt.Sprintf("thresholds[%d].level", i)
+
+		// 1. 检查级别是否合法（非法级别已由 validator 的 oneof 规则报告，此处跳过）
 		priority, ok := ThresholdLevelPriorities[th.Level]
 		if !ok {
-			return fmt.Errorf("无效的阈值级别: %s（允许的值：%v）",
-				th.Level, getValidLevels())
+			continue
 		}
 
 		// 2. 检查级别是否重复
 		if seenLevels[th.Level] {
-			return fmt.Errorf("阈值级别重复: %s 不能出现多次", th.Level)
+			problems = append(problems, newValidationError(path,
+				fmt.Sprintf("阈值级别重复: %s 不能出现多次", th.Level),
+				fmt.Sprintf("duplicate threshold level: %s must not appear more than once", th.Level)))
+			continue
 		}
 		seenLevels[th.Level] = true
 
 		// 3. 检查顺序是否正确（低级不能在高级之前）
 		if priority < lastPriority {
-			return fmt.Errorf("阈值顺序错误: %s（优先级 %d）不应在 %s（优先级 %d）之后",
-				th.Level, priority, getLevelByPriority(lastPriority), lastPriority)
+			problems = append(problems, newValidationError(path,
+				fmt.Sprintf("阈值顺序错误: %s（优先级 %d）不应在 %s（优先级 %d）之后",
+					th.Level, priority, getLevelByPriority(lastPriority), lastPriority),
+				fmt.Sprintf("threshold order error: %s (priority %d) must not come after %s (priority %d)",
+					th.Level, priority, getLevelByPriority(lastPriority), lastPriority)))
+			continue
 		}
 
 		lastPriority = priority
 	}
-	return nil
-}
-
-// 辅助函数：获取允许的级别列表（用于错误提示）
-func getValidLevels() []string {
-	levels := make([]string, 0, len(ThresholdLevelPriorities))
-	for level := range ThresholdLevelPriorities {
-		levels = append(levels, level)
-	}
-	return levels
+	return problems
 }
 
 // 辅助函数：通过优先级获取级别名称（用于错误提示）
@@ -399,6 +643,17 @@ func (tpl *Template) RenderQueryWithVars(ind *Indicator, input map[string]string
 		return "", fmt.Errorf("indicator query must be string template")
 	}
 
+	values, err := tpl.resolveIndicatorVars(ind, input)
+	if err != nil {
+		return "", err
+	}
+
+	// 渲染最终查询
+	return tpl.renderQuery(qTemplate, values)
+}
+
+// resolveIndicatorVars 解析指标渲染查询时使用的完整上下文
+func (tpl *Template) resolveIndicatorVars(ind *Indicator, input map[string]string) (map[string]any, error) {
 	// 初始化基础上下文
 	values := tpl.initBaseContext(ind)
 
@@ -411,39 +666,31 @@ func (tpl *Template) RenderQueryWithVars(ind *Indicator, input map[string]string
 	// 依次执行处理器
 	for _, p := range processors {
 		if err := p.processVars(values); err != nil {
-			return "", err
+			return nil, err
 		}
 	}
-
-	// 确保 TimeRange 存在
-	if _, ok := values["TimeRange"]; !ok {
-		if values["IndicatorTimeRange"] != "" {
-			values["TimeRange"] = values["IndicatorTimeRange"]
-		} else {
-			values["TimeRange"] = values["GlobalTimeRange"]
-		}
-	}
-	// 注入数据中心数据
-	if _, ok := values["DataCenterId"]; !ok {
-		values["DataCenterID"] = tpl.DataCenter.ID
-	}
-
-	// 渲染最终查询
-	return tpl.renderQuery(qTemplate, values)
+	return values, nil
 }
 
 // initBaseContext 初始化基础上下文
-func (tpl *Template) initBaseContext(ind *Indicator) map[string]string {
-	return map[string]string{
+// TimeRange 与 DataCenterID 作为外层值，可被同名变量覆盖或在变量中以 {{.TimeRange}} 等方式引用
+func (tpl *Template) initBaseContext(ind *Indicator) map[string]any {
+	timeRange := ind.TimeRange
+	if timeRange == "" {
+		timeRange = tpl.TimeRange
+	}
+	return map[string]any{
 		"IndicatorTimeRange": ind.TimeRange,
 		"GlobalTimeRange":    tpl.TimeRange,
 		"IndicatorName":      ind.Name,
+		VarTimeRange:         timeRange,
+		VarDataCenterID:      tpl.DataCenter.ID,
 	}
 }
 
 // renderQuery 渲染最终查询
-func (tpl *Template) renderQuery(qTemplate string, ctxValues map[string]string) (string, error) {
-	t, err := template.New("q").Parse(qTemplate)
+func (tpl *Template) renderQuery(qTemplate string, ctxValues map[string]any) (string, error) {
+	t, err := newTextTemplate("q").Parse(qTemplate)
 	if err != nil {
 		return "", err
 	}
@@ -3,102 +3,72 @@ package inspection
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/prometheus/common/model"
 
 	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
 )
 
-var globalClient *prom.Client
-var indexedPromTargetCache *prom.IndexedTargetCache
 var dcID = "01fd9896-3e25-4d68-b7ce-c20ab7ee13ca"
 
-func TestMain(m *testing.M) {
-	var err error
-	// globalClient, err = prom.NewClient("http://10.120.1.6:9090", prom.WithTimeout(10*time.Second))
-	globalClient, err = prom.NewClient("http://10.111.201.1:9090", prom.WithTimeout(10*time.Second))
+// newTestPrometheus 启动加载了 testdata/prometheus.yaml 的假 Prometheus，
+// 返回连接它的客户端与目标缓存
+func newTestPrometheus(t *testing.T) (*prom.Client, *prom.IndexedTargetCache) {
+	t.Helper()
+	srv := promtest.NewServer(t, promtest.WithFixtureFile("testdata/prometheus.yaml"))
+	client, err := prom.NewClient(srv.URL, prom.WithTimeout(10*time.Second))
 	if err != nil {
-		fmt.Printf("Error creating global client: %v\n", err)
-		os.Exit(1)
-	}
-	defer globalClient.Close()
-
-	indexedPromTargetCache = prom.NewIndexedTargetCache(globalClient, 60*time.Second)
-	defer indexedPromTargetCache.Close()
-
-	// 过滤标签，组装 map
-	// targetMap = make(map[string]v1.ActiveTarget)
-
-	// targets := indexedPromTargetCache.GetTargetsByPool("node_exporter")
-	// fmt.Printf("targets: %v", targets)
-
-	// for _, target := range targets {
-	// 	if target.Labels["instance"] == "" {
-	// 		continue
-	// 	}
-	// 	instance := string(target.Labels["instance"])
-	// 	// 过滤掉 data_center_id标签不符合给定值
-	// 	if string(target.Labels["data_center_id"]) != dcID {
-	// 		continue
-	// 	}
-	// 	_target := strings.Split(instance, ":")[0]
-	// 	targetMap[_target] = target
-	// }
-
-	code := m.Run()
-	os.Exit(code)
+		t.Fatalf("Error creating client: %v", err)
+	}
+	t.Cleanup(client.Close)
+
+	cache := prom.NewIndexedTargetCache(client, 60*time.Second)
+	t.Cleanup(cache.Close)
+	return client, cache
+}
+
+// sampleKey 依次取 node、instance、Hostname 标签作为结果的 key
+func sampleKey(metric model.Metric) string {
+	for _, name := range []model.LabelName{"node", "instance", "Hostname"} {
+		if v, ok := metric[name]; ok {
+			return string(v)
+		}
+	}
+	return metric.String()
 }
 
-// 测试专用的 Vector 结果处理器（替代原 ExecuteQuery 的输出逻辑）
-func testVectorHandler(t *testing.T, emptyMsg ...string) prom.ResultHandler {
+// 测试专用的 Vector 结果处理器：按 sampleKey 记录每个样本的值
+func testVectorHandler(t *testing.T, got map[string]float64) prom.ResultHandler {
 	return func(data any) error {
 		sample, ok := data.(*model.Sample)
 		if !ok {
 			return fmt.Errorf("expected *model.Sample, got %T", data)
 		}
-
-		// 提取标签并打印
-		labels := make(map[string]string)
-		for k, v := range sample.Metric {
-			labels[string(k)] = string(v)
-		}
-		t.Logf("Labels: %v", labels)
-
-		// 打印值
-		value := float64(sample.Value)
-		t.Logf("Value: %.2f", value)
+		t.Logf("Labels: %v, Value: %.2f", sample.Metric, float64(sample.Value))
+		got[sampleKey(sample.Metric)] = float64(sample.Value)
 		return nil
 	}
 }
 
-// 测试专用的 Matrix 结果处理器（替代原 ExecuteQueryRange 的输出逻辑）
-func testMatrixHandler(t *testing.T) prom.ResultHandler {
+// 测试专用的 Matrix 结果处理器：按完整标签记录每个时间序列
+func testMatrixHandler(t *testing.T, got map[string][]model.SamplePair) prom.ResultHandler {
 	return func(data any) error {
 		stream, ok := data.(*model.SampleStream)
 		if !ok {
 			return fmt.Errorf("expected *model.SampleStream, got %T", data)
 		}
-
-		// 提取标签并打印
-		labels := make(map[string]string)
-		for k, v := range stream.Metric {
-			labels[string(k)] = string(v)
-		}
-		t.Logf("Labels: %v", labels)
-
-		// 打印时间序列数据
-		for _, val := range stream.Values {
-			t.Logf("Time: %v, Value: %.2f", val.Timestamp.Time(), float64(val.Value))
-		}
+		t.Logf("Labels: %v, %d points", stream.Metric, len(stream.Values))
+		got[stream.Metric.String()] = stream.Values
 		return nil
 	}
 }
 
-// 优化后的 ExecuteQuery：复用 prom 包的 ExecuteQuery 并注入测试处理器
-func ExecuteQuery(t *testing.T, query string, ts time.Time, emptyMsg ...string) {
+// ExecuteQuery 复用 prom 包的 ExecuteQuery 并注入测试处理器，返回每个目标的值
+func ExecuteQuery(t *testing.T, client *prom.Client, query string, ts time.Time, emptyMsg ...string) map[string]float64 {
 	// 定义空结果回调（使用自定义提示）
 	var onEmpty func(string)
 	if len(emptyMsg) > 0 {
@@ -107,35 +77,85 @@ func ExecuteQuery(t *testing.T, query string, ts time.Time, emptyMsg ...string)
 		}
 	}
 
-	handler := func(data any) error {
-		return testVectorHandler(t)(data)
+	got := make(map[string]float64)
+	if err := prom.ExecuteQuery(client, query, ts, testVectorHandler(t, got), onEmpty); err != nil {
+		t.Fatalf("Query execution error: %v", err)
 	}
+	return got
+}
 
-	// 调用 prom 包的 ExecuteQuery，传入自定义空结果回调
-	err := prom.ExecuteQuery(globalClient, query, ts, handler, onEmpty)
+// ExecuteQueryRange 复用 prom 包的 ExecuteQueryRange 并注入测试处理器，返回每个时间序列
+func ExecuteQueryRange(t *testing.T, client *prom.Client, query string, rangeStart, rangeEnd time.Time, step time.Duration) map[string][]model.SamplePair {
+	got := make(map[string][]model.SamplePair)
+	err := prom.ExecuteQueryRange(client, query, rangeStart, rangeEnd, step, testMatrixHandler(t, got))
 	if err != nil {
-		t.Fatalf("Query execution error: %v", err)
+		t.Fatalf("QueryRange execution error: %v", err)
+	}
+	return got
+}
+
+func assertValues(t *testing.T, got, want map[string]float64) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Errorf("got %v, want %v", got, want)
+		return
+	}
+	for k, v := range want {
+		if g, ok := got[k]; !ok || g != v {
+			t.Errorf("%s = %v, want %v (all: %v)", k, g, v, got)
+		}
 	}
 }
 
-// 优化后的 ExecuteQueryRange：复用 prom 包的 ExecuteQueryRange 并注入测试处理器
-func ExecuteQueryRange(t *testing.T, query string, rangeStart, rangeEnd time.Time, step time.Duration) {
-	// 调用 prom 包的 ExecuteQueryRange，使用测试专用的 Matrix 处理器
-	err := prom.ExecuteQueryRange(
-		globalClient,
-		query,
-		rangeStart,
-		rangeEnd,
-		step,
-		testMatrixHandler(t),
-	)
+// renderIndicatorResult 渲染并执行指标查询，返回 JSON 结果
+func renderIndicatorResult(t *testing.T, tpl *Template, ind *Indicator, vars map[string]string, client *prom.Client, cache *prom.IndexedTargetCache) *IndicatorResult {
+	t.Helper()
+	query, err := tpl.RenderQueryWithVars(ind, vars)
 	if err != nil {
-		t.Fatalf("QueryRange execution error: %v", err)
+		t.Fatal(err)
+	}
+	t.Log("Rendered Query:\n", query)
+
+	// 创建处理器：同时获取结构体指针和处理器函数
+	jsonHandler, resultHandler := NewJSONResultHandler(ind, cache)
+
+	// 执行查询：传递 resultHandler 给 prom 包
+	if err := prom.ExecuteQuery(client, query, time.Now(), resultHandler); err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+
+	// 生成最终 JSON：通过 jsonHandler 结构体指针调用 Finalize()
+	result, err := jsonHandler.Finalize()
+	if err != nil {
+		t.Fatalf("Finalize failed: %v", err)
+	}
+	return result
+}
+
+// assertItems 检查结果中每个目标的状态，缺失的目标状态记为 missing
+func assertItems(t *testing.T, result *IndicatorResult, want map[string]string) {
+	t.Helper()
+	got := make(map[string]string, len(result.Values))
+	for _, item := range result.Values {
+		if item.Missing {
+			got[item.Target] = "missing"
+		} else {
+			got[item.Target] = item.Status
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%s: got %v, want %v", result.Indicator, got, want)
+		return
+	}
+	for target, status := range want {
+		if got[target] != status {
+			t.Errorf("%s: %s status = %q, want %q", result.Indicator, target, got[target], status)
+		}
 	}
 }
 
-// 以下测试函数保持不变（复用优化后的 ExecuteQuery/ExecuteQueryRange）
 func TestRenderGPUUsage(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
@@ -150,16 +170,14 @@ func TestRenderGPUUsage(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Log("Rendered Query:\n", query)
 
-	// 输出渲染的查询语句
-	fmt.Println("Rendered Query:\n", query)
-
-	now := time.Now()
-	ExecuteQuery(t, query, now)
+	got := ExecuteQuery(t, client, query, time.Now())
+	assertValues(t, got, map[string]float64{"10.120.1.5": 95, "10.120.1.6": 30})
 }
 
-// 以下测试函数保持不变（复用优化后的 ExecuteQuery/ExecuteQueryRange）
 func TestRenderGPUUsage_for_EmptyStr_does_not_exists(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
@@ -174,16 +192,15 @@ func TestRenderGPUUsage_for_EmptyStr_does_not_exists(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Log("Rendered Query:\n", query)
 
-	// 输出渲染的查询语句
-	fmt.Println("Rendered Query:\n", query)
-
-	now := time.Now()
-	ExecuteQuery(t, query, now)
+	if got := ExecuteQuery(t, client, query, time.Now()); len(got) != 0 {
+		t.Errorf("expected empty result, got %v", got)
+	}
 }
 
-// 以下测试函数保持不变（复用优化后的 ExecuteQuery/ExecuteQueryRange）
 func TestRenderGPUUsage_for_EmptyStr_exists(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
@@ -198,15 +215,15 @@ func TestRenderGPUUsage_for_EmptyStr_exists(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Log("Rendered Query:\n", query)
 
-	// 输出渲染的查询语句
-	fmt.Println("Rendered Query:\n", query)
-
-	now := time.Now()
-	ExecuteQuery(t, query, now, "[test empty string]: %s")
+	if got := ExecuteQuery(t, client, query, time.Now(), "[test empty string]: %s"); len(got) != 0 {
+		t.Errorf("expected empty result, got %v", got)
+	}
 }
 
 func TestRenderGPUUsage_with_TimeRange(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
@@ -220,21 +237,29 @@ func TestRenderGPUUsage_with_TimeRange(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
-	// 输出渲染的查询语句
-	fmt.Println("Rendered Query:\n", query)
+	t.Log("Rendered Query:\n", query)
 
-	now := time.Now()
-	ExecuteQuery(t, query, now)
+	// 用户输入的 TimeRange 优先于指标的 time_range
+	if !strings.Contains(query, "[1d]") {
+		t.Errorf("query does not use input TimeRange: %s", query)
+	}
+	got := ExecuteQuery(t, client, query, time.Now())
+	assertValues(t, got, map[string]float64{"10.120.1.5": 95, "10.120.1.6": 30})
 }
 
 func TestAnotherGPUQuery(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	query := `avg by (Hostname) (rate(DCGM_FI_DEV_GPU_UTIL{Hostname=~"worker-[0-9]+"}[5m]))`
 
-	now := time.Now()
-	ExecuteQuery(t, query, now)
+	got := ExecuteQuery(t, client, query, time.Now())
+	// worker-1 的 gpu0 每分钟增长 0.25，gpu1 恒定
+	if len(got) != 3 || got["worker-1"] <= 0 || got["worker-2"] != 0 || got["worker-3"] != 0 {
+		t.Errorf("unexpected result: %v", got)
+	}
 }
 
 func TestGPUUsageRange(t *testing.T) {
+	client, _ := newTestPrometheus(t)
 	// 示例：使用范围查询
 	query := `rate(DCGM_FI_DEV_GPU_UTIL{Hostname=~"worker-[0-9]+"}[5m])`
 	now := time.Now()
@@ -242,81 +267,57 @@ func TestGPUUsageRange(t *testing.T) {
 	rangeEnd := now
 	step := 5 * time.Minute // 每 5 分钟一个数据点
 
-	// 输出渲染的查询语句
-	fmt.Println("Rendered Range Query:\n", query)
-
-	// 使用范围查询函数
-	ExecuteQueryRange(t, query, rangeStart, rangeEnd, step)
+	got := ExecuteQueryRange(t, client, query, rangeStart, rangeEnd, step)
+	if len(got) != 4 {
+		t.Fatalf("expected 4 series, got %d", len(got))
+	}
+	for series, values := range got {
+		if len(values) != 13 {
+			t.Errorf("%s: expected 13 points, got %d", series, len(values))
+		}
+	}
 }
 
 func TestRenderGPUUsageWithJSON_result(t *testing.T) {
+	client, cache := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
 	}
-	ind := tpl.Indicators[0]
 
-	query, err := tpl.RenderQueryWithVars(ind, map[string]string{
+	result := renderIndicatorResult(t, tpl, tpl.Indicators[0], map[string]string{
 		"ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
-	})
-	if err != nil {
-		t.Fatal(err)
-	}
-
-	// 创建处理器：同时获取结构体指针和处理器函数
-	jsonHandler, resultHandler := NewJSONResultHandler(ind, indexedPromTargetCache)
-
-	// 执行查询：传递 resultHandler 给 prom 包
-	now := time.Now()
-	if err := prom.ExecuteQuery(globalClient, query, now, resultHandler); err != nil {
-		t.Fatalf("Query failed: %v", err)
-	}
+	}, client, cache)
 
-	// 生成最终 JSON：通过 jsonHandler 结构体指针调用 Finalize()
-	result, err := jsonHandler.Finalize()
-	if err != nil {
-		t.Fatalf("Finalize failed: %v", err)
+	// dcgm_exporter 中 10.120.1.7 没有数据
+	assertItems(t, result, map[string]string{
+		"10.120.1.5": ThresholdLevelCritical,
+		"10.120.1.6": ThresholdLevelOk,
+		"10.120.1.7": "missing",
+	})
+	if want := (Summary{Total: 3, Ok: 1, Critical: 1, Missing: 1}); result.Summary != want {
+		t.Errorf("summary = %+v, want %+v", result.Summary, want)
 	}
-
-	// 输出结果
-	fmt.Println("Final JSON:\n", result)
 }
 
 func TestRenderGPUUsageWithJSON_result_2(t *testing.T) {
+	client, cache := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/template-indicator-gpu-prometheus.yaml")
 	if err != nil {
 		t.Fatal(err)
 	}
-	ind := tpl.Indicators[1]
 
-	query, err := tpl.RenderQueryWithVars(ind, map[string]string{
-		// "ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
+	// 未输入变量时使用全局 ClusterRegex，按 instance 聚合
+	result := renderIndicatorResult(t, tpl, tpl.Indicators[1], nil, client, cache)
+	assertItems(t, result, map[string]string{
+		"10.120.1.5": ThresholdLevelCritical,
+		"10.120.1.6": ThresholdLevelOk,
+		"10.120.1.7": "missing",
 	})
-	if err != nil {
-		t.Fatal(err)
-	}
-	fmt.Println("Rendered Query:\n", query)
-
-	// 创建处理器：同时获取结构体指针和处理器函数
-	jsonHandler, resultHandler := NewJSONResultHandler(ind, indexedPromTargetCache)
-
-	// 执行查询：传递 resultHandler 给 prom 包
-	now := time.Now()
-	if err := prom.ExecuteQuery(globalClient, query, now, resultHandler); err != nil {
-		t.Fatalf("Query failed: %v", err)
-	}
-
-	// 生成最终 JSON：通过 jsonHandler 结构体指针调用 Finalize()
-	result, err := jsonHandler.Finalize()
-	if err != nil {
-		t.Fatalf("Finalize failed: %v", err)
-	}
-
-	// 输出结果
-	fmt.Println("Final JSON:\n", result)
 }
 
 func TestRenderTemplate(t *testing.T) {
+	client, cache := newTestPrometheus(t)
 	tpl, err := ParseTemplateFile("template/gpu/gpu-node.yaml")
 	if err != nil {
 		t.Fatal(err)
@@ -331,29 +332,12 @@ func TestRenderTemplate(t *testing.T) {
 	result.Sections = tpl.ReportLayout.Sections
 
 	for _, ind := range tpl.Indicators {
-		if !*ind.Enabled {
+		if ind.Enabled != nil && !*ind.Enabled {
 			continue
 		}
-		query, err := tpl.RenderQueryWithVars(ind, map[string]string{
-			// "ClusterRegex": `10\\.120\\.[0-9]+\\.[0-9]+`,
-		})
-		if err != nil {
-			t.Fatal(err)
-		}
-		fmt.Println("Rendered Query:\n", query)
-
-		// 创建处理器：同时获取结构体指针和处理器函数
-		jsonHandler, resultHandler := NewJSONResultHandler(ind, indexedPromTargetCache)
-		if err := prom.ExecuteQuery(globalClient, query, now, resultHandler); err != nil {
-			t.Fatalf("Query failed: %v", err)
-		}
-		indResult, err := jsonHandler.Finalize()
-		if err != nil {
-			t.Fatalf("Finalize failed: %v", err)
-		}
+		indResult := renderIndicatorResult(t, tpl, ind, nil, client, cache)
 		result.Results = append(result.Results, indResult)
 
-		//
 		so := &SummaryOverview{
 			Indicator: ind.Name,
 			Unit:      indResult.Unit,
@@ -365,15 +349,43 @@ func TestRenderTemplate(t *testing.T) {
 			Missing:   indResult.Summary.Missing,
 		}
 		result.SummaryOverviews = append(result.SummaryOverviews, so)
+	}
 
-		// marshal, err := json.MarshalIndent(indResult, "", "  ")
-
-		// fmt.Printf("Final JSON: %#v\n%s\n%v\n", indResult, marshal, err)
+	// 只有指标库中的「节点平均GPU温度」启用
+	if len(result.Results) != 1 {
+		t.Fatalf("expected 1 enabled indicator, got %d", len(result.Results))
+	}
+	assertItems(t, result.Results[0], map[string]string{
+		"10.120.1.5:9400": ThresholdLevelWarning, // 两卡平均 90
+		"10.120.1.6:9400": ThresholdLevelInfo,
+		"10.120.1.7:9400": "missing",
+	})
+	if so := result.SummaryOverviews[0]; so.Total != 3 || so.Warning != 1 || so.Info != 1 || so.Missing != 1 {
+		t.Errorf("unexpected summary overview: %+v", so)
 	}
 
-	marshal, err := json.MarshalIndent(result, "", "  ")
-	if err != nil {
+	if _, err := json.MarshalIndent(result, "", "  "); err != nil {
 		t.Fatal(err)
 	}
-	fmt.Println("result: \n", string(marshal))
+}
+
+func TestTemplate_QueryRange(t *testing.T) {
+	tpl := &Template{TimeRange: "24h"}
+	end := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	r := tpl.QueryRange(&Indicator{}, end)
+	if !r.Start.Equal(end.Add(-24*time.Hour)) || r.Step != time.Minute {
+		t.Errorf("default range = %+v", r)
+	}
+
+	r = tpl.QueryRange(&Indicator{TimeRange: "1h", Resolution: "30s"}, end)
+	if !r.Start.Equal(end.Add(-time.Hour)) || r.Step != 30*time.Second {
+		t.Errorf("indicator range = %+v", r)
+	}
+
+	// 点数超过 Prometheus 限制时自动放大步长
+	r = (&Template{TimeRange: "30d"}).QueryRange(&Indicator{}, end)
+	if points := r.End.Sub(r.Start) / r.Step; points > maxRangePoints {
+		t.Errorf("step %s yields %d points", r.Step, points)
+	}
 }
@@ -0,0 +1,368 @@
+package inspection
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"gopkg.in/yaml.v3"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+)
+
+// StatusMissing 测试用例与报告差异中表示目标缺失的状态
+const StatusMissing = "missing"
+
+// defaultTestInterval 输入序列的默认采样间隔
+const defaultTestInterval = "1m"
+
+// maxTestSamples 单个查询最多加载的样本数，与 promtool 一致
+const maxTestSamples = 10000
+
+// TestSpec 模板测试文件，与模板放在一起（如 gpu-node.test.yaml），类似 promtool test rules：
+// 为指标提供输入序列，离线执行查询，校验每个目标的状态、统计与高亮结果
+//
+//	template: gpu-node.yaml
+//	vars: { DataCenterID: dc-1 }
+//	targets:
+//	  gpu_exporter: ["10.0.0.1:9400", "10.0.0.2:9400"]
+//	tests:
+//	  - name: 高温告警
+//	    indicator: 节点平均GPU温度
+//	    input_series:
+//	      - series: nvidia_smi_temperature_gpu{data_center_id="dc-1", instance="10.0.0.1:9400"}
+//	        values: 80+1x10
+//	    expect:
+//	      statuses: { "10.0.0.1:9400": critical, "10.0.0.2:9400": missing }
+//	      summary: { total: 2, critical: 1, missing: 1 }
+//	      highlighted: ["10.0.0.1:9400"]
+type TestSpec struct {
+	// Template 模板文件，相对于测试文件
+	Template string `yaml:"template"`
+	// Interval 输入序列的采样间隔，默认 1m
+	Interval string `yaml:"interval"`
+	// Vars 所有用例共用的变量输入
+	Vars map[string]string `yaml:"vars"`
+	// Targets 所有用例共用的目标列表：scrape pool -> instance 列表，用于判断缺失的目标
+	Targets map[string][]string `yaml:"targets"`
+	Tests   []*TestCase         `yaml:"tests"`
+
+	path string
+}
+
+// TestCase 单个指标的测试用例
+type TestCase struct {
+	Name      string `yaml:"name"`
+	Indicator string `yaml:"indicator"`
+	// Interval 覆盖 TestSpec.Interval
+	Interval string `yaml:"interval"`
+	// EvalTime 执行时间，相对于输入序列的起点，默认为最后一个样本的时间
+	EvalTime string `yaml:"eval_time"`
+	// Vars 与 TestSpec.Vars 合并，同名时用例优先
+	Vars map[string]string `yaml:"vars"`
+	// Targets 与 TestSpec.Targets 合并，同一 pool 时用例优先
+	Targets     map[string][]string `yaml:"targets"`
+	InputSeries []InputSeries       `yaml:"input_series"`
+	Expect      TestExpectation     `yaml:"expect"`
+}
+
+// InputSeries 输入序列，values 使用 promtool 的展开写法，如 1+1x10、0 1 _ 3、stale
+type InputSeries struct {
+	Series string `yaml:"series"`
+	Values string `yaml:"values"`
+}
+
+// TestExpectation 期望结果，未填写的项不校验
+type TestExpectation struct {
+	// Statuses 每个目标的状态（critical/warning/info/ok/missing），结果中的目标必须与之完全一致
+	Statuses map[string]string `yaml:"statuses"`
+	Summary  *Summary          `yaml:"summary"`
+	// Highlighted 高亮的目标（顺序无关）；填写空列表表示期望没有高亮
+	Highlighted []string `yaml:"highlighted"`
+}
+
+// TestSpecResult 测试文件的执行结果
+type TestSpecResult struct {
+	File  string            `json:"file"`
+	Cases []*TestCaseResult `json:"cases"`
+}
+
+// Passed 所有用例是否通过
+func (r *TestSpecResult) Passed() bool {
+	for _, c := range r.Cases {
+		if !c.Passed() {
+			return false
+		}
+	}
+	return true
+}
+
+// TestCaseResult 用例的执行结果
+type TestCaseResult struct {
+	Name      string           `json:"name"`
+	Indicator string           `json:"indicator"`
+	Failures  []string         `json:"failures,omitempty"`
+	Result    *IndicatorResult `json:"result,omitempty"`
+}
+
+// Passed 用例是否通过
+func (r *TestCaseResult) Passed() bool { return len(r.Failures) == 0 }
+
+func (r *TestCaseResult) failf(format string, args ...any) {
+	r.Failures = append(r.Failures, fmt.Sprintf(format, args...))
+}
+
+// LoadTestSpec 读取模板测试文件
+func LoadTestSpec(path string) (*TestSpec, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var spec TestSpec
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("parse test spec %s: %w", path, err)
+	}
+	if spec.Template == "" {
+		return nil, fmt.Errorf("test spec %s: template is required", path)
+	}
+	spec.path = path
+	return &spec, nil
+}
+
+// RunTestSpecFile 读取并执行模板测试文件；文件或模板无法加载时返回错误，
+// 用例失败记录在结果中
+func RunTestSpecFile(path string) (*TestSpecResult, error) {
+	spec, err := LoadTestSpec(path)
+	if err != nil {
+		return nil, err
+	}
+	tpl, err := ParseTemplateFile(filepath.Join(filepath.Dir(path), spec.Template))
+	if err != nil {
+		return nil, fmt.Errorf("test spec %s: %w", path, err)
+	}
+	return spec.Run(tpl), nil
+}
+
+// Run 使用给定模板执行所有用例
+func (spec *TestSpec) Run(tpl *Template) *TestSpecResult {
+	result := &TestSpecResult{File: spec.path}
+	for i, tc := range spec.Tests {
+		name := tc.Name
+		if name == "" {
+			name = fmt.Sprintf("tests[%d]", i)
+		}
+		r := &TestCaseResult{Name: name, Indicator: tc.Indicator}
+		spec.runCase(tpl, tc, r)
+		result.Cases = append(result.Cases, r)
+	}
+	return result
+}
+
+func (spec *TestSpec) runCase(tpl *Template, tc *TestCase, r *TestCaseResult) {
+	var ind *Indicator
+	for _, candidate := range tpl.Indicators {
+		if candidate.Name == tc.Indicator {
+			ind = candidate
+			break
+		}
+	}
+	if ind == nil {
+		r.failf("indicator %q not found in template %s", tc.Indicator, tpl.Name)
+		return
+	}
+
+	vars := mergeMaps(spec.Vars, tc.Vars)
+	query, err := tpl.RenderQueryWithVars(ind, vars)
+	if err != nil {
+		r.failf("render query: %v", err)
+		return
+	}
+
+	interval := tc.Interval
+	if interval == "" {
+		interval = spec.Interval
+	}
+	if interval == "" {
+		interval = defaultTestInterval
+	}
+	result, err := evalTestCase(tpl, ind, query, interval, tc, mergeMaps(spec.Targets, tc.Targets))
+	if err != nil {
+		r.failf("%v", err)
+		return
+	}
+	r.Result = result
+	checkExpectation(tc.Expect, result, r)
+}
+
+// evalTestCase 将输入序列载入内存存储，使用 PromQL 引擎执行查询，并交给 JSONResultHandler 生成结果
+func evalTestCase(tpl *Template, ind *Indicator, query, interval string, tc *TestCase, pools map[string][]string) (*IndicatorResult, error) {
+	step, err := model.ParseDuration(interval)
+	if err != nil || step <= 0 {
+		return nil, fmt.Errorf("interval %q: must be a positive duration", interval)
+	}
+	db, err := newMemStorage(time.Duration(step), tc.InputSeries)
+	if err != nil {
+		return nil, err
+	}
+
+	epoch := time.Unix(0, 0).UTC()
+	evalTime := epoch
+	if tc.EvalTime != "" {
+		d, err := model.ParseDuration(tc.EvalTime)
+		if err != nil {
+			return nil, fmt.Errorf("eval_time: %w", err)
+		}
+		evalTime = epoch.Add(time.Duration(d))
+	} else if db.maxT != math.MinInt64 {
+		evalTime = time.UnixMilli(db.maxT).UTC()
+	}
+
+	engine := promql.NewEngine(promql.EngineOpts{
+		MaxSamples:           maxTestSamples,
+		Timeout:              time.Minute,
+		EnableAtModifier:     true,
+		EnableNegativeOffset: true,
+	})
+	defer engine.Close()
+
+	ctx := context.Background()
+	var q promql.Query
+	switch ind.Type {
+	case IndicatorTypeRange, IndicatorTypeTrend:
+		r := tpl.QueryRange(ind, evalTime)
+		q, err = engine.NewRangeQuery(ctx, db, nil, query, r.Start, r.End, r.Step)
+	default:
+		q, err = engine.NewInstantQuery(ctx, db, nil, query, evalTime)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", query, err)
+	}
+	defer q.Close()
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		return nil, fmt.Errorf("query %s: %w", query, res.Err)
+	}
+
+	var targets []v1.ActiveTarget
+	for pool, instances := range pools {
+		for _, instance := range instances {
+			targets = append(targets, v1.ActiveTarget{
+				ScrapePool: pool,
+				Health:     v1.HealthGood,
+				Labels:     model.LabelSet{"job": model.LabelValue(pool), "instance": model.LabelValue(instance)},
+			})
+		}
+	}
+	jsonHandler, handler := NewJSONResultHandler(ind, prom.StaticTargets(targets))
+	for _, item := range promqlItems(res.Value) {
+		if err := handler(item); err != nil {
+			return nil, err
+		}
+	}
+	return jsonHandler.Finalize()
+}
+
+// promqlItems 将引擎结果转换为 prom.ResultHandler 接收的 *model.Sample / *model.SampleStream
+func promqlItems(v parser.Value) []any {
+	var items []any
+	switch v := v.(type) {
+	case promql.Vector:
+		for _, s := range v {
+			items = append(items, &model.Sample{Metric: promqlMetric(s.Metric), Value: model.SampleValue(s.F), Timestamp: model.Time(s.T)})
+		}
+	case promql.Matrix:
+		for _, series := range v {
+			stream := &model.SampleStream{Metric: promqlMetric(series.Metric)}
+			for _, p := range series.Floats {
+				stream.Values = append(stream.Values, model.SamplePair{Timestamp: model.Time(p.T), Value: model.SampleValue(p.F)})
+			}
+			items = append(items, stream)
+		}
+	case promql.Scalar:
+		items = append(items, &model.Sample{Metric: model.Metric{}, Value: model.SampleValue(v.V), Timestamp: model.Time(v.T)})
+	}
+	return items
+}
+
+func promqlMetric(ls labels.Labels) model.Metric {
+	m := make(model.Metric, ls.Len())
+	ls.Range(func(l labels.Label) {
+		m[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	})
+	return m
+}
+
+// checkExpectation 对比结果与期望，记录所有不一致
+func checkExpectation(expect TestExpectation, result *IndicatorResult, r *TestCaseResult) {
+	if expect.Statuses != nil {
+		got := make(map[string]string, len(result.Values))
+		for _, item := range result.Values {
+			if item.Missing {
+				got[item.Target] = StatusMissing
+			} else {
+				got[item.Target] = item.Status
+			}
+		}
+		for _, target := range sortedKeys(expect.Statuses) {
+			want := expect.Statuses[target]
+			if status, ok := got[target]; !ok {
+				r.failf("target %s: not in result, want %s", target, want)
+			} else if status != want {
+				r.failf("target %s: status %s, want %s%s", target, status, want, valueSuffix(result, target))
+			}
+		}
+		for _, target := range sortedKeys(got) {
+			if _, ok := expect.Statuses[target]; !ok {
+				r.failf("target %s: unexpected in result with status %s%s", target, got[target], valueSuffix(result, target))
+			}
+		}
+	}
+
+	if expect.Summary != nil && *expect.Summary != result.Summary {
+		r.failf("summary %+v, want %+v", result.Summary, *expect.Summary)
+	}
+
+	if expect.Highlighted != nil {
+		got := make([]string, 0, len(result.Highlight.Values))
+		for _, item := range result.Highlight.Values {
+			got = append(got, item.Target)
+		}
+		want := append([]string(nil), expect.Highlighted...)
+		sort.Strings(got)
+		sort.Strings(want)
+		if strings.Join(got, "\n") != strings.Join(want, "\n") {
+			r.failf("highlighted %v, want %v", got, want)
+		}
+	}
+}
+
+// valueSuffix 失败信息中附带目标的实际值
+func valueSuffix(result *IndicatorResult, target string) string {
+	for _, item := range result.Values {
+		if item.Target == target && item.Value != nil {
+			return fmt.Sprintf(" (value %g)", *item.Value)
+		}
+	}
+	return ""
+}
+
+func mergeMaps[V any](base, override map[string]V) map[string]V {
+	merged := make(map[string]V, len(base)+len(override))
+	for k, v := range base {
+		merged[k] = v
+	}
+	for k, v := range override {
+		merged[k] = v
+	}
+	return merged
+}
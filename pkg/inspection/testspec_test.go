@@ -0,0 +1,126 @@
+package inspection
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/tsdb/chunkenc"
+)
+
+func TestRunTestSpecFile(t *testing.T) {
+	result, err := RunTestSpecFile("template/gpu/gpu-node.test.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Cases) != 3 {
+		t.Fatalf("expected 3 cases, got %d", len(result.Cases))
+	}
+	for _, c := range result.Cases {
+		if !c.Passed() {
+			t.Errorf("%s failed:\n  %s", c.Name, strings.Join(c.Failures, "\n  "))
+		}
+	}
+}
+
+func TestRunTestSpecFile_Failures(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "tpl.yaml"), []byte(dryRunTemplateYAML), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	spec := `
+template: tpl.yaml
+targets:
+  node_exporter: [a, b]
+tests:
+  - name: wrong expectations
+    indicator: up
+    vars: { Job: node }
+    input_series:
+      - series: up{job="node", dc="dc-1", instance="a"}
+        values: 0x3
+    expect:
+      statuses: { a: ok, c: critical }
+      summary: { total: 1 }
+      highlighted: [a]
+  - name: unknown indicator
+    indicator: nope
+  - name: bad series
+    indicator: up
+    input_series:
+      - series: up{job=
+        values: 1
+`
+	path := filepath.Join(dir, "tpl.test.yaml")
+	if err := os.WriteFile(path, []byte(spec), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	result, err := RunTestSpecFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Passed() || len(result.Cases) != 3 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	wrong := strings.Join(result.Cases[0].Failures, "\n")
+	for _, want := range []string{
+		"target a: status critical, want ok (value 0)",
+		"target c: not in result, want critical",
+		"target b: unexpected in result with status missing",
+		"summary {Total:2",
+		"highlighted [], want [a]",
+	} {
+		if !strings.Contains(wrong, want) {
+			t.Errorf("failures missing %q:\n%s", want, wrong)
+		}
+	}
+	if f := result.Cases[1].Failures; len(f) != 1 || !strings.Contains(f[0], `indicator "nope" not found`) {
+		t.Errorf("unexpected failures: %v", f)
+	}
+	if f := result.Cases[2].Failures; len(f) != 1 || !strings.Contains(f[0], "input_series") {
+		t.Errorf("unexpected failures: %v", f)
+	}
+}
+
+func TestNewMemStorage(t *testing.T) {
+	db, err := newMemStorage(time.Minute, []InputSeries{
+		{Series: `up{instance="b"}`, Values: "1 _ 3"},
+		{Series: `up{instance="a"}`, Values: "0 1 stale"},
+		{Series: `up{instance="b"}`, Values: "5 6"}, // 标签相同，以后定义的为准
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if db.maxT != (2 * time.Minute).Milliseconds() {
+		t.Errorf("maxT = %d", db.maxT)
+	}
+	q, _ := db.Querier(0, db.maxT)
+	set := q.Select(context.Background(), true, nil, labels.MustNewMatcher(labels.MatchEqual, "__name__", "up"))
+	var got []string
+	for set.Next() {
+		s := set.At()
+		var points []string
+		it := s.Iterator(nil)
+		for it.Next() != chunkenc.ValNone {
+			ts, v := it.At()
+			if value.IsStaleNaN(v) {
+				points = append(points, fmt.Sprintf("%d:stale", ts))
+			} else {
+				points = append(points, fmt.Sprintf("%d:%g", ts, v))
+			}
+		}
+		got = append(got, s.Labels().Get("instance")+" "+strings.Join(points, ","))
+	}
+	want := "a 0:0,60000:1,120000:stale\nb 0:5,60000:6"
+	if strings.Join(got, "\n") != want {
+		t.Errorf("got\n%s\nwant\n%s", strings.Join(got, "\n"), want)
+	}
+}
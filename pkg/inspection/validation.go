@@ -0,0 +1,396 @@
+package inspection
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"gopkg.in/yaml.v3"
+)
+
+// 校验信息语言
+const (
+	LangZH = "zh"
+	LangEN = "en"
+)
+
+// ValidationError 单条模板校验错误，定位到 YAML 中的行列
+type ValidationError struct {
+	File      string `json:"file,omitempty"` // 错误所在的被引用文件（extends / include），主模板中的错误为空
+	Path      string `json:"path"`           // YAML 路径，如 indicators[0].display.type
+	Line      int    `json:"line"`           // 行号（从 1 开始，0 表示无法定位）
+	Column    int    `json:"column"`         // 列号（从 1 开始，0 表示无法定位）
+	Message   string `json:"message"`        // 中文描述
+	MessageEN string `json:"message_en"`     // 英文描述
+	Err       error  `json:"-"`              // 原始错误（如 *PromQLSyntaxError），可能为空
+}
+
+func newValidationError(path, zh, en string) *ValidationError {
+	return &ValidationError{Path: path, Message: zh, MessageEN: en}
+}
+
+// Text 返回指定语言的错误描述，未知语言时返回中英双语
+func (e *ValidationError) Text(lang string) string {
+	switch lang {
+	case LangZH:
+		return e.Message
+	case LangEN:
+		return e.MessageEN
+	}
+	if e.Message == e.MessageEN {
+		return e.Message
+	}
+	return fmt.Sprintf("%s (%s)", e.Message, e.MessageEN)
+}
+
+// Format 按指定语言输出带位置信息的错误
+func (e *ValidationError) Format(lang string) string {
+	var b strings.Builder
+	if e.File != "" {
+		b.WriteString(e.File)
+		b.WriteString(": ")
+	}
+	if e.Line > 0 {
+		fmt.Fprintf(&b, "line %d, column %d: ", e.Line, e.Column)
+	}
+	if e.Path != "" {
+		b.WriteString(e.Path)
+		b.WriteString(": ")
+	}
+	b.WriteString(e.Text(lang))
+	return b.String()
+}
+
+func (e *ValidationError) Error() string { return e.Format("") }
+
+func (e *ValidationError) Unwrap() error { return e.Err }
+
+// ValidationErrors 模板校验报告，包含模板中的所有错误（按发现顺序）
+type ValidationErrors []*ValidationError
+
+func (es ValidationErrors) Error() string { return es.Format("") }
+
+// Format 按指定语言输出完整的校验报告
+func (es ValidationErrors) Format(lang string) string {
+	lines := make([]string, 0, len(es)+1)
+	lines = append(lines, fmt.Sprintf("template validation: %d error(s)", len(es)))
+	for _, e := range es {
+		lines = append(lines, "  "+e.Format(lang))
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (es ValidationErrors) Unwrap() []error {
+	result := make([]error, len(es))
+	for i, e := range es {
+		result[i] = e
+	}
+	return result
+}
+
+// ValidateTemplateBytes 校验模板并返回完整的校验报告，模板合法时返回 nil
+func ValidateTemplateBytes(data []byte, opts ...ParseOption) ValidationErrors {
+	_, err := ParseTemplateBytes(data, opts...)
+	if err == nil {
+		return nil
+	}
+	var errs ValidationErrors
+	if errors.As(err, &errs) {
+		return errs
+	}
+	return ValidationErrors{{Message: err.Error(), MessageEN: err.Error(), Err: err}}
+}
+
+// -----------------------------------------------------------------------------
+// 错误收集与定位
+// -----------------------------------------------------------------------------
+
+// validationCollector 收集校验错误，并通过 yaml.Node 定位行列
+type validationCollector struct {
+	root  *yaml.Node
+	lines []string // 原始 YAML 文本行，用于定位块标量内部的位置
+	errs  ValidationErrors
+
+	// 模板组合时节点可能来自其它文件：files 记录节点的来源文件，source 为主模板文件
+	files  map[*yaml.Node]string
+	source string
+}
+
+func newValidationCollector(root *yaml.Node, data []byte) *validationCollector {
+	return &validationCollector{
+		root:  root,
+		lines: strings.Split(string(data), "\n"),
+	}
+}
+
+// add 记录错误并根据 Path 填充行列
+func (c *validationCollector) add(e *ValidationError) {
+	if e.Line == 0 {
+		if node := c.locate(e.Path); node != nil {
+			e.Line, e.Column = node.Line, node.Column
+			e.File = c.foreignFile(node)
+		}
+	}
+	c.errs = append(c.errs, e)
+}
+
+// foreignFile 返回节点的来源文件，节点来自主模板时返回空
+func (c *validationCollector) foreignFile(node *yaml.Node) string {
+	if file := c.files[node]; file != c.source {
+		return file
+	}
+	return ""
+}
+
+// addPrefixed 以 prefix 作为路径前缀记录一组相对路径的错误
+func (c *validationCollector) addPrefixed(prefix string, problems []*ValidationError) {
+	for _, p := range problems {
+		p.Path = joinPath(prefix, p.Path)
+		c.add(p)
+	}
+}
+
+// addYAMLTypeError 记录 yaml 解码时的类型错误（如字符串无法转换为数字）
+func (c *validationCollector) addYAMLTypeError(msg string) {
+	e := &ValidationError{
+		Message:   "类型错误: " + msg,
+		MessageEN: "type error: " + msg,
+	}
+	if m := yamlLinePattern.FindStringSubmatch(msg); m != nil {
+		e.Line, _ = strconv.Atoi(m[1])
+		e.Column = 1
+	}
+	c.errs = append(c.errs, e)
+}
+
+var yamlLinePattern = regexp.MustCompile(`^line (\d+):`)
+
+// addFieldErrors 将 validator 的错误转换为带 YAML 路径的错误
+func (c *validationCollector) addFieldErrors(err error) {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		c.errs = append(c.errs, &ValidationError{Message: err.Error(), MessageEN: err.Error(), Err: err})
+		return
+	}
+	for _, fe := range fieldErrs {
+		zh, en := fieldErrorMessages(fe)
+		c.add(&ValidationError{
+			Path:      yamlPathOf(fe.Namespace()),
+			Message:   zh,
+			MessageEN: en,
+			Err:       fe,
+		})
+	}
+}
+
+// addPromQLError 记录 PromQL 语法错误，并将位置换算到 YAML 文件中
+func (c *validationCollector) addPromQLError(path string, err error) {
+	e := &ValidationError{
+		Path:      path,
+		Message:   "PromQL 校验失败: " + err.Error(),
+		MessageEN: "promql validation failed: " + err.Error(),
+		Err:       err,
+	}
+	node := c.locate(path)
+	var syntaxErr *PromQLSyntaxError
+	if node != nil && errors.As(err, &syntaxErr) {
+		if e.File = c.foreignFile(node); e.File == "" {
+			e.Line, e.Column = c.scalarPosition(node, syntaxErr.Line, syntaxErr.Column)
+		} else {
+			// 被引用文件的原始文本不可用，只定位到查询本身
+			e.Line, e.Column = node.Line, node.Column
+		}
+	}
+	c.add(e)
+}
+
+// scalarPosition 将标量内部的行列（均从 1 开始）换算为文件中的行列
+func (c *validationCollector) scalarPosition(node *yaml.Node, line, column int) (int, int) {
+	if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0 {
+		// 单行标量：仅在第一行内可精确定位
+		if line == 1 {
+			return node.Line, node.Column + column - 1
+		}
+		return node.Line, node.Column
+	}
+	// 块标量（| 或 >）：内容从指示符的下一行开始，列需加上块的基础缩进
+	fileLine := node.Line + line
+	if fileLine-1 >= len(c.lines) {
+		return node.Line, node.Column
+	}
+	indent := 0
+	for _, text := range c.lines[node.Line:] {
+		if strings.TrimSpace(text) != "" {
+			indent = len(text) - len(strings.TrimLeft(text, " "))
+			break
+		}
+	}
+	return fileLine, indent + column
+}
+
+// locate 按 YAML 路径查找节点；路径不存在时返回最近的已存在祖先节点
+func (c *validationCollector) locate(path string) *yaml.Node {
+	node := c.root
+	if node == nil || node.Kind == 0 {
+		return nil
+	}
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if path == "" {
+		return node
+	}
+	for _, seg := range splitPath(path) {
+		next := childNode(node, seg)
+		if next == nil {
+			return node
+		}
+		node = next
+	}
+	return node
+}
+
+// pathSegment YAML 路径中的一段：映射键或序列下标
+type pathSegment struct {
+	key   string
+	index int // -1 表示非下标
+}
+
+// splitPath 将 indicators[0].display.type 拆分为路径段
+func splitPath(path string) []pathSegment {
+	var segs []pathSegment
+	for _, part := range strings.Split(path, ".") {
+		key := part
+		var indexes []int
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			key = part[:i]
+			for _, m := range indexPattern.FindAllStringSubmatch(part[i:], -1) {
+				n, _ := strconv.Atoi(m[1])
+				indexes = append(indexes, n)
+			}
+		}
+		if key != "" {
+			segs = append(segs, pathSegment{key: key, index: -1})
+		}
+		for _, n := range indexes {
+			segs = append(segs, pathSegment{index: n})
+		}
+	}
+	return segs
+}
+
+var indexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+func childNode(node *yaml.Node, seg pathSegment) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	switch {
+	case seg.index >= 0 && node.Kind == yaml.SequenceNode:
+		if seg.index < len(node.Content) {
+			return node.Content[seg.index]
+		}
+	case seg.index < 0 && node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == seg.key {
+				return node.Content[i+1]
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	case strings.HasPrefix(path, "["):
+		return prefix + path
+	}
+	return prefix + "." + path
+}
+
+// yamlPathOf 将 validator 的命名空间（Template.Indicators[0].Display.Type）
+// 转换为 YAML 路径（indicators[0].display.type）
+func yamlPathOf(namespace string) string {
+	parts := strings.Split(namespace, ".")
+	if len(parts) > 0 {
+		parts = parts[1:] // 去掉根结构体名
+	}
+
+	typ := reflect.TypeOf(Template{})
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		name, suffix := part, ""
+		if i := strings.IndexByte(part, '['); i >= 0 {
+			name, suffix = part[:i], part[i:]
+		}
+
+		yamlName := name
+		typ = derefType(typ)
+		if typ != nil && typ.Kind() == reflect.Struct {
+			if f, ok := typ.FieldByName(name); ok {
+				if tag := strings.Split(f.Tag.Get("yaml"), ",")[0]; tag != "" && tag != "-" {
+					yamlName = tag
+				}
+				typ = f.Type
+			} else {
+				typ = nil
+			}
+		} else {
+			typ = nil
+		}
+		// 每个下标向下取一层元素类型
+		for i := 0; i < strings.Count(suffix, "["); i++ {
+			typ = derefType(typ)
+			if typ != nil && (typ.Kind() == reflect.Slice || typ.Kind() == reflect.Array || typ.Kind() == reflect.Map) {
+				typ = typ.Elem()
+			}
+		}
+		result = append(result, yamlName+suffix)
+	}
+	return strings.Join(result, ".")
+}
+
+func derefType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// fieldErrorMessages 根据 validator tag 生成中英文描述
+func fieldErrorMessages(fe validator.FieldError) (string, string) {
+	switch fe.Tag() {
+	case "required":
+		return "必填字段缺失或为空", "is required"
+	case "oneof":
+		allowed := strings.ReplaceAll(fe.Param(), " ", ", ")
+		return fmt.Sprintf("取值 %q 无效，必须是以下之一: %s", fmt.Sprint(fe.Value()), allowed),
+			fmt.Sprintf("value %q is invalid, must be one of: %s", fmt.Sprint(fe.Value()), allowed)
+	case "min":
+		switch fe.Kind() {
+		case reflect.Slice, reflect.Array, reflect.Map:
+			return fmt.Sprintf("至少需要 %s 项", fe.Param()),
+				fmt.Sprintf("must contain at least %s item(s)", fe.Param())
+		case reflect.String:
+			return fmt.Sprintf("长度不能小于 %s", fe.Param()),
+				fmt.Sprintf("length must be at least %s", fe.Param())
+		}
+		return fmt.Sprintf("不能小于 %s", fe.Param()), fmt.Sprintf("must be at least %s", fe.Param())
+	case "cronexpr":
+		return fmt.Sprintf("无效的 cron 表达式: %q", fmt.Sprint(fe.Value())),
+			fmt.Sprintf("invalid cron expression: %q", fmt.Sprint(fe.Value()))
+	case "promduration":
+		return fmt.Sprintf("无效的时长: %q，应为 Prometheus 时长格式，如 30s、5m、1h30m、7d", fmt.Sprint(fe.Value())),
+			fmt.Sprintf("invalid duration %q, expected Prometheus duration such as 30s, 5m, 1h30m, 7d", fmt.Sprint(fe.Value()))
+	}
+	return fmt.Sprintf("未通过 %s 校验（值: %v）", fe.Tag(), fe.Value()),
+		fmt.Sprintf("failed on the %q validation (value: %v)", fe.Tag(), fe.Value())
+}
@@ -0,0 +1,123 @@
+package inspection
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const invalidTemplateYAML = `name: invalid-test
+display_name: 校验测试
+schedule:
+  cron: "not a cron"
+time_range: 2hours
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+indicators:
+  - name: bad-display
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: up
+    thresholds:
+      - level: warning
+        value: 80
+        operator: gt
+        description: 偏高
+      - level: critical
+        value: 90
+        operator: gt
+        description: 过高
+    display:
+      type: pie_chart
+      highlight:
+        enabled: true
+        limit: first_3
+        conditions:
+          - operator: gt
+  - name: missing-source
+    exporter: gpu_exporter
+    type: point
+    query: up
+    display:
+      type: table
+    resolution: 5 min
+report_layout:
+  sections:
+    - title: all
+      Indicators: [bad-display]
+`
+
+func TestParseTemplateBytes_AggregatedErrors(t *testing.T) {
+	_, err := ParseTemplateBytes([]byte(invalidTemplateYAML))
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected ValidationErrors, got %T: %v", err, err)
+	}
+
+	want := []struct {
+		path string
+		line int
+	}{
+		{"schedule.cron", 4},
+		{"time_range", 5},
+		{"indicators[1].resolution", 38},
+		{"indicators[0].display.type", 26},
+		{"indicators[1].source", 32},
+		{"indicators[0].thresholds[1].level", 21},
+		{"indicators[0].display.highlight.limit", 29},
+		{"indicators[0].display.highlight.conditions[0]", 31},
+	}
+	for _, w := range want {
+		found := false
+		for _, e := range errs {
+			if e.Path == w.path {
+				found = true
+				if e.Line != w.line {
+					t.Errorf("%s: expected line %d, got %d", w.path, w.line, e.Line)
+				}
+				if e.Message == "" || e.MessageEN == "" {
+					t.Errorf("%s: expected both zh and en messages, got %q / %q", w.path, e.Message, e.MessageEN)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("missing error for %s in:\n%v", w.path, err)
+		}
+	}
+
+	if en := errs.Format(LangEN); !strings.Contains(en, "must be one of: table, line_chart") {
+		t.Errorf("unexpected english report:\n%s", en)
+	}
+	if en := errs.Format(LangEN); !strings.Contains(en, `invalid duration "2hours"`) {
+		t.Errorf("unexpected english report:\n%s", en)
+	}
+	if zh := errs.Format(LangZH); !strings.Contains(zh, "阈值顺序错误") {
+		t.Errorf("unexpected chinese report:\n%s", zh)
+	}
+}
+
+func TestValidateTemplateBytes_Valid(t *testing.T) {
+	if errs := ValidateTemplateBytes([]byte(lintTemplateYAML)); errs != nil {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+func TestYAMLPathOf(t *testing.T) {
+	cases := map[string]string{
+		"Template.Indicators[0].Display.Type":          "indicators[0].display.type",
+		"Template.ReportLayout.Sections[1].Indicators": "report_layout.sections[1].Indicators",
+		"Template.Vars[2].Type":                        "vars[2].type",
+	}
+	for ns, want := range cases {
+		if got := yamlPathOf(ns); got != want {
+			t.Errorf("yamlPathOf(%s) = %s, want %s", ns, got, want)
+		}
+	}
+}
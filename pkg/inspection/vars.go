@@ -2,14 +2,22 @@ package inspection
 
 import (
 	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"text/template"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+
+	"gopkg.in/yaml.v3"
 )
 
 // varProcessor 定义变量处理阶段的接口
 type varProcessor interface {
 	// processVars 处理变量列表，返回上下文值和错误
-	processVars(values map[string]string) error
+	processVars(values map[string]any) error
 }
 
 // globalVarProcessor 处理全局变量
@@ -25,70 +33,328 @@ type indicatorVarProcessor struct {
 }
 
 // processVars 实现全局变量的处理逻辑
-func (p globalVarProcessor) processVars(values map[string]string) error {
-	vars := p.tpl.Vars
-	return processVarsInTwoPhases(vars, p.input, values, "global")
+func (p globalVarProcessor) processVars(values map[string]any) error {
+	return resolveVars(p.tpl.Vars, p.input, values, "global")
 }
 
 // processVars 实现指标变量的处理逻辑
-func (p indicatorVarProcessor) processVars(values map[string]string) error {
-	vars := p.ind.Vars
-	return processVarsInTwoPhases(vars, p.input, values, "indicator")
+func (p indicatorVarProcessor) processVars(values map[string]any) error {
+	return resolveVars(p.ind.Vars, p.input, values, "indicator")
 }
 
-// processVarsInTwoPhases 公共的两阶段变量处理逻辑
-func processVarsInTwoPhases(vars []Variable, input map[string]string, values map[string]string, varType string) error {
-	// 第一阶段：处理非模板值
-	for _, v := range vars {
+// resolveVars 按依赖关系解析一组变量并写入 values
+//
+// 变量值可以引用同组的其它变量，解析顺序由引用关系的拓扑排序决定，循环引用返回错误。
+// 引用自身（如 DataCenterID: "{{.DataCenterID}}"）表示引用外层的同名值：
+// 基础上下文中的值，或指标变量引用同名的全局变量。
+// 未填写值的可选变量设置为对应类型的空值（外层已有同名值时保留外层的值），
+// 以便查询中可以用 {{if .X}} 判断。
+func resolveVars(vars []Variable, input map[string]string, values map[string]any, varType string) error {
+	order, err := resolveOrder(vars, input, varType)
+	if err != nil {
+		return err
+	}
+	for _, v := range order {
 		raw := pickRaw(v, input)
 		if raw == "" {
 			if v.Required {
 				return fmt.Errorf("missing required %s variable: %s", varType, v.Name)
 			}
+			if _, ok := values[v.Name]; !ok {
+				values[v.Name] = emptyVarValue(v)
+			}
 			continue
 		}
-		if !containsTpl(raw) {
-			if err := validateVarType(v, raw); err != nil {
-				return fmt.Errorf("%s variable %s invalid: %w", varType, v.Name, err)
+		if containsTpl(raw) {
+			rendered, err := renderStringTemplate(v.Name, raw, values)
+			if err != nil {
+				return fmt.Errorf("render %s variable %s: %w", varType, v.Name, err)
 			}
-			values[v.Name] = raw
+			raw = rendered
+		}
+		val, err := validateVarType(v, raw)
+		if err != nil {
+			return fmt.Errorf("%s variable %s invalid: %w", varType, v.Name, err)
 		}
+		values[v.Name] = val
+	}
+	return nil
+}
+
+// resolveOrder 返回变量的解析顺序：被引用的变量排在引用它的变量之前，其余保持声明顺序
+func resolveOrder(vars []Variable, input map[string]string, varType string) ([]Variable, error) {
+	index := make(map[string]int, len(vars))
+	for i, v := range vars {
+		index[v.Name] = i
 	}
 
-	// 第二阶段：处理模板值
-	for _, v := range vars {
-		if _, ok := values[v.Name]; ok {
-			continue // 已在第一阶段处理
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make([]int, len(vars))
+	order := make([]Variable, 0, len(vars))
+	var path []string
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		v := vars[i]
+		switch state[i] {
+		case done:
+			return nil
+		case visiting:
+			start := 0
+			for j, name := range path {
+				if name == v.Name {
+					start = j
+				}
+			}
+			cycle := append(append([]string{}, path[start:]...), v.Name)
+			return fmt.Errorf("%s variable cycle: %s", varType, strings.Join(cycle, " -> "))
 		}
+		state[i] = visiting
+		path = append(path, v.Name)
+
 		raw := pickRaw(v, input)
-		if raw == "" {
-			if v.Required {
-				return fmt.Errorf("missing required %s variable: %s", varType, v.Name)
+		if containsTpl(raw) {
+			refs, err := templateRefs(v.Name, raw)
+			if err != nil {
+				return fmt.Errorf("parse %s variable %s: %w", varType, v.Name, err)
+			}
+			for _, ref := range refs {
+				if j, ok := index[ref]; ok && ref != v.Name {
+					if err := visit(j); err != nil {
+						return err
+					}
+				}
 			}
-			continue
 		}
-		rendered, err := renderStringTemplate(raw, values)
-		if err != nil {
-			return fmt.Errorf("render %s variable %s: %w", varType, v.Name, err)
+
+		path = path[:len(path)-1]
+		state[i] = done
+		order = append(order, v)
+		return nil
+	}
+
+	for i := range vars {
+		if err := visit(i); err != nil {
+			return nil, err
 		}
-		if err := validateVarType(v, rendered); err != nil {
-			return fmt.Errorf("%s variable %s invalid after render: %w", varType, v.Name, err)
+	}
+	return order, nil
+}
+
+// templateRefs 返回模板中引用的顶层字段名（如 {{.A}}、{{.B.key}}、{{$.C}} 中的 A、B、C）
+func templateRefs(name, tmplStr string) ([]string, error) {
+	tmpl, err := newTextTemplate(name).Parse(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+	var refs []string
+	seen := make(map[string]bool)
+	add := func(ident string) {
+		if !seen[ident] {
+			seen[ident] = true
+			refs = append(refs, ident)
 		}
-		values[v.Name] = rendered
 	}
 
-	return nil
+	// top 表示 . 仍指向顶层上下文；range / with 的主体内 . 会变化，只统计 $.X 形式的引用
+	var walk func(node parse.Node, top bool)
+	walk = func(node parse.Node, top bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, top)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, top)
+		case *parse.IfNode:
+			walk(n.Pipe, top)
+			walk(n.List, top)
+			walk(n.ElseList, top)
+		case *parse.RangeNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.WithNode:
+			walk(n.Pipe, top)
+			walk(n.List, false)
+			walk(n.ElseList, top)
+		case *parse.TemplateNode:
+			walk(n.Pipe, top)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, top)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, top)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, top)
+		case *parse.FieldNode:
+			if top {
+				add(n.Ident[0])
+			}
+		case *parse.VariableNode:
+			if len(n.Ident) > 1 && n.Ident[0] == "$" {
+				add(n.Ident[1])
+			}
+		}
+	}
+	if tmpl.Tree != nil {
+		walk(tmpl.Tree.Root, true)
+	}
+	return refs, nil
 }
 
-// Helper to safely execute small templates for variable substitution
-func renderStringTemplate(tmplStr string, values map[string]string) (string, error) {
-	// 自定义函数映射
-	funcMap := template.FuncMap{}
-	tmpl, err := template.New("var").Funcs(funcMap).Parse(tmplStr)
+// emptyVarValue 返回可选变量未赋值时的空值
+func emptyVarValue(v Variable) any {
+	switch v.Type {
+	case "list":
+		return []string{}
+	case "map":
+		return map[string]string{}
+	}
+	return ""
+}
+
+// renderStringTemplate 渲染变量值模板，引用不存在的键时返回错误
+func renderStringTemplate(name, tmplStr string, values map[string]any) (string, error) {
+	tmpl, err := newTextTemplate(name).Parse(tmplStr)
 	if err != nil {
-		return tmplStr, err
+		return "", err
 	}
 	var buf bytes.Buffer
-	_ = tmpl.Execute(&buf, values)
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return "", err
+	}
 	return buf.String(), nil
 }
+
+// VarValue 变量的原始值（value / default_value）
+//
+// YAML 中可以写标量，也可以为 list / map 类型的变量直接写列表或映射：
+// 列表与映射会转换为 JSON 字符串保存，与用户输入的 JSON 字符串走同一套解析逻辑。
+type VarValue string
+
+// UnmarshalYAML 实现 yaml.Unmarshaler
+func (v *VarValue) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*v = VarValue(node.Value)
+		return nil
+	}
+	var raw any
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+	data, err := json.Marshal(raw)
+	if err != nil {
+		return fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	*v = VarValue(data)
+	return nil
+}
+
+// parseListValue 解析 list 变量：JSON 数组（如 ["a","b"]）或逗号分隔的字符串（如 a, b）
+func parseListValue(raw string) ([]string, error) {
+	raw = strings.TrimSpace(raw)
+	if strings.HasPrefix(raw, "[") {
+		var items []any
+		if err := decodeJSON(raw, &items); err != nil {
+			return nil, err
+		}
+		result := make([]string, len(items))
+		for i, item := range items {
+			s, err := scalarString(item)
+			if err != nil {
+				return nil, fmt.Errorf("element %d: %w", i, err)
+			}
+			result[i] = s
+		}
+		return result, nil
+	}
+	var result []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result, nil
+}
+
+// parseMapValue 解析 map 变量：JSON 对象（如 {"a":"1"}）或逗号分隔的键值对（如 a=1, b=2）
+func parseMapValue(raw string) (map[string]string, error) {
+	raw = strings.TrimSpace(raw)
+	result := make(map[string]string)
+	if strings.HasPrefix(raw, "{") {
+		var m map[string]any
+		if err := decodeJSON(raw, &m); err != nil {
+			return nil, err
+		}
+		for k, item := range m {
+			s, err := scalarString(item)
+			if err != nil {
+				return nil, fmt.Errorf("key %s: %w", k, err)
+			}
+			result[k] = s
+		}
+		return result, nil
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		k, val, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid pair %q, expected key=value", pair)
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(val)
+	}
+	return result, nil
+}
+
+// decodeJSON 解析恰好一个 JSON 值，之后只能有空白
+func decodeJSON(raw string, v any) error {
+	dec := json.NewDecoder(strings.NewReader(raw))
+	dec.UseNumber() // 保留数字的原始写法
+	if err := dec.Decode(v); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("unexpected data after JSON value")
+	}
+	return nil
+}
+
+// scalarString 将 JSON 标量转换为字符串，不支持嵌套的列表或对象
+func scalarString(v any) (string, error) {
+	switch val := v.(type) {
+	case string:
+		return val, nil
+	case json.Number:
+		return val.String(), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case nil:
+		return "", nil
+	}
+	return "", fmt.Errorf("nested %T is not supported", v)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,285 @@
+package inspection
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const listVarsTemplateYAML = `
+name: list-vars
+display_name: 列表变量
+schedule:
+  cron: "0 9 * * *"
+time_range: 1h
+target_registry:
+  source: metadata
+  query:
+    entity_type: gpu_node
+vars:
+  - name: Nodes
+    type: list
+    default_value: [10.0.0.1:9100, 10.0.0.2:9100]
+  - name: Models
+    type: list
+    elem_type: enum
+    enum_values: [A100, H100, L40S]
+    default_value: A100, H100
+  - name: Ports
+    type: list
+    elem_type: number
+    default_value: "[9100, 9400]"
+  - name: Labels
+    type: map
+    default_value: { job: gpu, env: prod }
+indicators:
+  - name: up
+    source: prometheus
+    exporter: gpu_exporter
+    type: point
+    query: up{instance=~"{{promRegexIn .Nodes}}", job="{{.Labels.job}}", model=~"{{.Models | join "|"}}"}
+    display: { type: table }
+report_layout:
+  sections:
+    - title: 节点
+      Indicators: [up]
+`
+
+func TestParseTemplateBytes_ListVarDefaults(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(listVarsTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]VarValue{
+		"Nodes":  `["10.0.0.1:9100","10.0.0.2:9100"]`,
+		"Models": `A100, H100`,
+		"Ports":  `[9100, 9400]`,
+		"Labels": `{"env":"prod","job":"gpu"}`,
+	}
+	for _, v := range tpl.Vars {
+		if v.DefaultValue != want[v.Name] {
+			t.Errorf("%s default_value = %s, want %s", v.Name, v.DefaultValue, want[v.Name])
+		}
+	}
+}
+
+func TestRenderQueryWithVars_ListVars(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(listVarsTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ind := tpl.Indicators[0]
+
+	cases := []struct {
+		name  string
+		input map[string]string
+		want  string
+	}{
+		{
+			name: "defaults",
+			want: `up{instance=~"10\\.0\\.0\\.1:9100|10\\.0\\.0\\.2:9100", job="gpu", model=~"A100|H100"}`,
+		},
+		{
+			name:  "comma separated input",
+			input: map[string]string{"Nodes": "a.b, c", "Models": "L40S"},
+			want:  `up{instance=~"a\\.b|c", job="gpu", model=~"L40S"}`,
+		},
+		{
+			name:  "json input",
+			input: map[string]string{"Nodes": `["x"]`, "Labels": `{"job": "dcgm"}`},
+			want:  `up{instance=~"x", job="dcgm", model=~"A100|H100"}`,
+		},
+		{
+			name:  "key value map input",
+			input: map[string]string{"Labels": "job=node, env=test"},
+			want:  `up{instance=~"10\\.0\\.0\\.1:9100|10\\.0\\.0\\.2:9100", job="node", model=~"A100|H100"}`,
+		},
+	}
+	for _, c := range cases {
+		got, err := tpl.RenderQueryWithVars(ind, c.input)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("%s:\n got  %s\n want %s", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRenderQueryWithVars_ListVarElementValidation(t *testing.T) {
+	tpl, err := ParseTemplateBytes([]byte(listVarsTemplateYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]map[string]string{
+		"Models[1]":    {"Models": "A100, V100"},
+		"Ports[0]":     {"Ports": `["http"]`},
+		"must be map":  {"Labels": "job"},
+		"must be list": {"Nodes": `[{"nested": true}]`},
+	}
+	for want, input := range cases {
+		_, err := tpl.RenderQueryWithVars(tpl.Indicators[0], input)
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("input %v: err = %v, want containing %q", input, err, want)
+		}
+	}
+}
+
+func TestParseListValue(t *testing.T) {
+	cases := map[string][]string{
+		"":               nil,
+		"a":              {"a"},
+		" a , b ,, c ":   {"a", "b", "c"},
+		`["a", 1, true]`: {"a", "1", "true"},
+		`[1.50, "x,y"]`:  {"1.50", "x,y"},
+	}
+	for raw, want := range cases {
+		got, err := parseListValue(raw)
+		if err != nil {
+			t.Errorf("%q: %v", raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%q = %#v, want %#v", raw, got, want)
+		}
+	}
+}
+
+func TestParseJSONValue_TrailingData(t *testing.T) {
+	for _, raw := range []string{`["a","b"], c`, `["a"] junk`, `["a"]["b"]`} {
+		if got, err := parseListValue(raw); err == nil || !strings.Contains(err.Error(), "unexpected data after JSON value") {
+			t.Errorf("parseListValue(%q) = %v, %v; want trailing data error", raw, got, err)
+		}
+	}
+	for _, raw := range []string{`{"k":"v"} junk`, `{"k":"v"}, x=y`, `{"k":"v"} {}`} {
+		if got, err := parseMapValue(raw); err == nil || !strings.Contains(err.Error(), "unexpected data after JSON value") {
+			t.Errorf("parseMapValue(%q) = %v, %v; want trailing data error", raw, got, err)
+		}
+	}
+	// 结尾的空白不算多余内容
+	if got, err := parseListValue("[\"a\"] \n"); err != nil || !reflect.DeepEqual(got, []string{"a"}) {
+		t.Errorf("trailing whitespace: %v, %v", got, err)
+	}
+}
+
+func TestInClause(t *testing.T) {
+	got, err := inClause([]string{"A100", "it's"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `('A100', 'it''s')`; got != want {
+		t.Errorf("inClause = %s, want %s", got, want)
+	}
+}
+
+func TestValidateVarType_DurationAndTimestamp(t *testing.T) {
+	cases := []struct {
+		v     Variable
+		val   string
+		valid bool
+	}{
+		{Variable{Name: "D", Type: "duration"}, "1h30m", true},
+		{Variable{Name: "D", Type: "duration"}, "7d", true},
+		{Variable{Name: "D", Type: "duration"}, "2hours", false},
+		{Variable{Name: "D", Type: "duration"}, "-5m", false},
+		{Variable{Name: "T", Type: "timestamp"}, "2026-01-02T03:04:05Z", true},
+		{Variable{Name: "T", Type: "timestamp"}, "1767323045", true},
+		{Variable{Name: "T", Type: "timestamp"}, "1767323045.5", true},
+		{Variable{Name: "T", Type: "timestamp"}, "yesterday", false},
+		{Variable{Name: "L", Type: "list", ElemType: "duration"}, "5m, 1h", true},
+		{Variable{Name: "L", Type: "list", ElemType: "duration"}, "5m, soon", false},
+	}
+	for _, c := range cases {
+		_, err := validateVarType(c.v, c.val)
+		if (err == nil) != c.valid {
+			t.Errorf("%s %q: err = %v, want valid = %v", c.v.Type, c.val, err, c.valid)
+		}
+	}
+}
+
+func TestRenderQueryWithVars_StrictRendering(t *testing.T) {
+	tpl := &Template{
+		TimeRange:  "1h",
+		DataCenter: DataCenter{ID: "dc-1"},
+		Vars: []Variable{
+			{Name: "Region", Type: "string", DefaultValue: "{{.Regoin}}"},
+		},
+	}
+	_, err := tpl.RenderQueryWithVars(&Indicator{Query: "up"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "variable Region") || !strings.Contains(err.Error(), "Regoin") {
+		t.Errorf("err = %v, want missing key error naming the variable", err)
+	}
+
+	tpl.Vars = nil
+	_, err = tpl.RenderQueryWithVars(&Indicator{Query: `up{region="{{.Regoin}}"}`}, nil)
+	if err == nil || !strings.Contains(err.Error(), "Regoin") {
+		t.Errorf("err = %v, want missing key error in query", err)
+	}
+}
+
+func TestRenderQueryWithVars_DependencyOrder(t *testing.T) {
+	tpl := &Template{
+		TimeRange:  "1h",
+		DataCenter: DataCenter{ID: "dc-1"},
+		Vars: []Variable{
+			// 声明顺序与依赖顺序相反
+			{Name: "Selector", Type: "string", Value: `{{.Label}}=~"{{.Pattern}}"`},
+			{Name: "Pattern", Type: "string", Value: "{{.Prefix}}.*"},
+			{Name: "Prefix", Type: "string", DefaultValue: "gpu-{{.DataCenterID}}"},
+			{Name: "Label", Type: "string", DefaultValue: "instance"},
+			{Name: "Optional", Type: "string"},
+			{Name: "DataCenterID", Type: "string", DefaultValue: "{{.DataCenterID}}"},
+		},
+	}
+	ind := &Indicator{
+		Query: `up{ {{.Selector}}{{if .Optional}}, opt="{{.Optional}}"{{end}} }[{{.TimeRange}}]`,
+		Vars: []Variable{
+			// 指标变量引用同名的全局变量
+			{Name: "Label", Type: "string", DefaultValue: "{{.Label}}"},
+		},
+	}
+
+	got, err := tpl.RenderQueryWithVars(ind, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `up{ instance=~"gpu-dc-1.*" }[1h]`; got != want {
+		t.Errorf("query = %s, want %s", got, want)
+	}
+
+	// 用户输入的 DataCenterID 不会被模板的数据中心覆盖
+	got, err = tpl.RenderQueryWithVars(ind, map[string]string{"DataCenterID": "dc-input", "Optional": "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `up{ instance=~"gpu-dc-input.*", opt="x" }[1h]`; got != want {
+		t.Errorf("query = %s, want %s", got, want)
+	}
+}
+
+func TestRenderQueryWithVars_Cycle(t *testing.T) {
+	tpl := &Template{
+		TimeRange: "1h",
+		Vars: []Variable{
+			{Name: "A", Type: "string", Value: "{{.B}}"},
+			{Name: "B", Type: "string", Value: "{{.C}}"},
+			{Name: "C", Type: "string", Value: "{{.A}}"},
+		},
+	}
+	_, err := tpl.RenderQueryWithVars(&Indicator{Query: "up"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "global variable cycle: A -> B -> C -> A") {
+		t.Errorf("err = %v, want cycle error", err)
+	}
+}
+
+func TestTemplateRefs(t *testing.T) {
+	refs, err := templateRefs("t", `{{if .A}}{{.B.key | default $.C}}{{end}}{{range .D}}{{.}}{{end}}{{with .E}}{{.Nested}}{{$.F}}{{end}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// with / range 内部的 . 不再指向顶层上下文，Nested 不计入
+	if want := []string{"A", "B", "C", "D", "E", "F"}; !reflect.DeepEqual(refs, want) {
+		t.Errorf("refs = %v, want %v", refs, want)
+	}
+}
@@ -0,0 +1,170 @@
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 通知渠道类型
+const (
+	TypeWebhook = "webhook"
+	TypeEmail   = "email"
+)
+
+// Config 通知配置文件，示例：
+//
+//	notifiers:
+//	  ops-dingtalk:
+//	    type: dingtalk            # webhook / email / dingtalk / wecom / feishu
+//	    url: https://oapi.dingtalk.com/robot/send?access_token=xxx
+//	    secret: ${DINGTALK_SECRET}
+//	  oncall-email:
+//	    type: email
+//	    addr: smtp.example.com:587
+//	    from: inspection@example.com
+//	    to: [oncall@example.com]
+//	    username: inspection@example.com
+//	    password: ${SMTP_PASSWORD}
+//	routes:
+//	  - templates: [daily-gpu-*]
+//	    when:
+//	      - { count: critical, operator: gte, value: 1 }
+//	    notify: [ops-dingtalk, oncall-email]
+//
+// 通知渠道的 url、headers、secret、addr、from、to、username、password 中的 ${VAR}
+// 在解析后替换为环境变量的值，密钥不必写在文件中；变量未设置时报错，其它形式的 $ 保持原样。
+type Config struct {
+	Notifiers map[string]NotifierConfig `yaml:"notifiers"`
+	Routes    []Route                   `yaml:"routes"`
+}
+
+// NotifierConfig 单个通知渠道的配置，字段按类型取用
+type NotifierConfig struct {
+	Type string `yaml:"type"`
+	// webhook 与机器人
+	URL     string            `yaml:"url"`
+	Headers map[string]string `yaml:"headers"`
+	Full    bool              `yaml:"full"`
+	Secret  string            `yaml:"secret"`
+	// Timeout 单次发送的超时时间，对所有类型生效，默认 10s
+	Timeout time.Duration `yaml:"timeout"`
+	// email
+	Addr     string   `yaml:"addr"`
+	From     string   `yaml:"from"`
+	To       []string `yaml:"to"`
+	Username string   `yaml:"username"`
+	Password string   `yaml:"password"`
+	// MaxItems 每个指标最多列出的异常项数
+	MaxItems int `yaml:"max_items"`
+}
+
+// defaultTimeout 通知的默认超时时间
+const defaultTimeout = 10 * time.Second
+
+// LoadConfig 读取配置文件并创建 Dispatcher
+func LoadConfig(path string) (*Dispatcher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	d, err := ParseConfig(data)
+	if err != nil {
+		return nil, fmt.Errorf("notify config %s: %w", path, err)
+	}
+	return d, nil
+}
+
+// ParseConfig 解析配置并创建 Dispatcher
+func ParseConfig(data []byte) (*Dispatcher, error) {
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, err
+	}
+	notifiers := make(map[string]Notifier, len(cfg.Notifiers))
+	var errs []error
+	for name, nc := range cfg.Notifiers {
+		if err := nc.expandEnv(); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %q: %w", name, err))
+			continue
+		}
+		n, err := nc.build()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("notifier %q: %w", name, err))
+			continue
+		}
+		notifiers[name] = n
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return NewDispatcher(notifiers, cfg.Routes)
+}
+
+// envPattern 配置中引用环境变量的写法 ${VAR}
+var envPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv 替换字符串字段中的 ${VAR}
+func (c *NotifierConfig) expandEnv() error {
+	var missing []string
+	expand := func(s *string) {
+		*s = envPattern.ReplaceAllStringFunc(*s, func(ref string) string {
+			name := envPattern.FindStringSubmatch(ref)[1]
+			value, ok := os.LookupEnv(name)
+			if !ok && !slices.Contains(missing, name) {
+				missing = append(missing, name)
+			}
+			return value
+		})
+	}
+	for _, field := range []*string{&c.URL, &c.Secret, &c.Addr, &c.From, &c.Username, &c.Password} {
+		expand(field)
+	}
+	for i := range c.To {
+		expand(&c.To[i])
+	}
+	for key, value := range c.Headers {
+		expand(&value)
+		c.Headers[key] = value
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variable not set: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// build 按类型创建通知渠道
+func (c NotifierConfig) build() (Notifier, error) {
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+
+	switch c.Type {
+	case TypeWebhook:
+		if c.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &Webhook{URL: c.URL, Headers: c.Headers, Full: c.Full, Client: client}, nil
+	case TypeEmail:
+		if c.Addr == "" || c.From == "" || len(c.To) == 0 {
+			return nil, errors.New("addr, from and to are required")
+		}
+		return &Email{Addr: c.Addr, From: c.From, To: c.To, Username: c.Username, Password: c.Password, MaxItems: c.MaxItems, Timeout: timeout}, nil
+	case string(RobotDingTalk), string(RobotWeCom), string(RobotFeishu):
+		if c.URL == "" {
+			return nil, errors.New("url is required")
+		}
+		return &Robot{Kind: RobotKind(c.Type), URL: c.URL, Secret: c.Secret, MaxItems: c.MaxItems, Client: client}, nil
+	default:
+		return nil, fmt.Errorf("unsupported type %q, want webhook, email, dingtalk, wecom or feishu", c.Type)
+	}
+}
@@ -0,0 +1,105 @@
+// Package notify 在巡检执行完成后发送通知：通用 Webhook（JSON）、SMTP 邮件，
+// 以及钉钉、企业微信、飞书群机器人，并按模板与汇总数量路由到不同的通知渠道。
+//
+// 通知渠道与路由规则可写在 YAML 配置中，见 LoadConfig。
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// Notifier 通知渠道
+type Notifier interface {
+	// Notify 发送一次执行的报告
+	Notify(ctx context.Context, report *inspection.Report) error
+}
+
+// NotifierFunc 将函数适配为 Notifier
+type NotifierFunc func(ctx context.Context, report *inspection.Report) error
+
+func (f NotifierFunc) Notify(ctx context.Context, report *inspection.Report) error {
+	return f(ctx, report)
+}
+
+// Payload 通知中的报告摘要，也是通用 Webhook 的请求体
+type Payload struct {
+	Template    string                        `json:"template"`
+	DisplayName string                        `json:"display_name"`
+	ExecutedAt  time.Time                     `json:"executed_at"`
+	ExecutedBy  string                        `json:"executed_by,omitempty"`
+	Summary     inspection.Summary            `json:"summary"` // 所有指标的合计
+	Indicators  []*inspection.SummaryOverview `json:"indicators"`
+	Changes     *inspection.ReportDiff        `json:"changes,omitempty"`
+	// Report 完整报告，仅在 Webhook 配置 full 时附带
+	Report *inspection.Report `json:"report,omitempty"`
+}
+
+// NewPayload 汇总报告
+func NewPayload(report *inspection.Report) *Payload {
+	return &Payload{
+		Template:    report.Template.Name,
+		DisplayName: report.Template.DisplayName,
+		ExecutedAt:  report.Template.ExecutedAt,
+		ExecutedBy:  report.Template.ExecutedBy,
+		Summary:     Totals(report),
+		Indicators:  report.SummaryOverviews,
+		Changes:     report.Changes,
+	}
+}
+
+// Totals 所有指标的汇总数量之和
+func Totals(report *inspection.Report) inspection.Summary {
+	var s inspection.Summary
+	for _, o := range report.SummaryOverviews {
+		s.Total += o.Total
+		s.Ok += o.Ok
+		s.Info += o.Info
+		s.Warning += o.Warning
+		s.Critical += o.Critical
+		s.Missing += o.Missing
+	}
+	return s
+}
+
+// postJSON 以 JSON 发送 body，响应状态码不是 2xx 时返回错误；响应体不超过 64KB，由 decode 解析
+func postJSON(ctx context.Context, client *http.Client, url string, headers map[string]string, body any, decode func([]byte) error) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(respBody))
+	}
+	if decode != nil {
+		return decode(respBody)
+	}
+	return nil
+}
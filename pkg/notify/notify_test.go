@@ -0,0 +1,231 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// newTestReport GPU 使用率 1 项严重、1 项警告、1 项缺失，GPU 温度全部正常
+func newTestReport(template string) *inspection.Report {
+	value := func(v float64) *float64 { return &v }
+	report := &inspection.Report{
+		SummaryOverviews: []*inspection.SummaryOverview{
+			{Indicator: "GPU 使用率", Unit: "%", Total: 4, Ok: 1, Warning: 1, Critical: 1, Missing: 1},
+			{Indicator: "GPU 温度", Unit: "°C", Total: 4, Ok: 4},
+		},
+		Results: []*inspection.IndicatorResult{
+			{
+				Indicator: "GPU 使用率", Unit: "%",
+				Summary: inspection.Summary{Total: 4, Ok: 1, Warning: 1, Critical: 1, Missing: 1},
+				Values: []inspection.ValueItem{
+					{Target: "10.0.0.1", Value: value(50), Status: inspection.ThresholdLevelOk},
+					{Target: "10.0.0.2", Value: value(85.456), Status: inspection.ThresholdLevelWarning},
+					{Target: "10.0.0.3", Missing: true},
+					{Target: "10.0.0.4", Value: value(99), Status: inspection.ThresholdLevelCritical},
+				},
+			},
+			{Indicator: "GPU 温度", Unit: "°C", Summary: inspection.Summary{Total: 4, Ok: 4}},
+		},
+	}
+	report.Template.Name = template
+	report.Template.DisplayName = "GPU 节点每日巡检"
+	report.Template.ExecutedAt = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	return report
+}
+
+func TestWebhook(t *testing.T) {
+	var got Payload
+	var header string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get("X-Token")
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	report := newTestReport("daily-gpu")
+	w := &Webhook{URL: srv.URL, Headers: map[string]string{"X-Token": "secret"}}
+	if err := w.Notify(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	want := inspection.Summary{Total: 8, Ok: 5, Warning: 1, Critical: 1, Missing: 1}
+	if got.Template != "daily-gpu" || got.Summary != want || len(got.Indicators) != 2 || got.Report != nil {
+		t.Errorf("unexpected payload: %+v", got)
+	}
+	if header != "secret" {
+		t.Errorf("X-Token header = %q", header)
+	}
+
+	w.Full = true
+	if err := w.Notify(context.Background(), report); err != nil {
+		t.Fatal(err)
+	}
+	if got.Report == nil || len(got.Report.Results) != 2 {
+		t.Errorf("full payload should include the report, got %+v", got.Report)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusBadGateway)
+	}))
+	defer failing.Close()
+	err := (&Webhook{URL: failing.URL}).Notify(context.Background(), report)
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("got %v, want status error", err)
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	var sent []string
+	recorder := func(name string) Notifier {
+		return NotifierFunc(func(context.Context, *inspection.Report) error {
+			sent = append(sent, name)
+			if name == "broken" {
+				return errors.New("unavailable")
+			}
+			return nil
+		})
+	}
+	notifiers := map[string]Notifier{"gpu": recorder("gpu"), "all": recorder("all"), "broken": recorder("broken")}
+	routes := []Route{
+		{Templates: []string{"daily-gpu-*"}, When: []CountCondition{{Count: CountCritical, Operator: inspection.OpGte, Value: 1}}, Notify: []string{"gpu"}, Continue: true},
+		{Logic: inspection.LogicAnd, When: []CountCondition{
+			{Count: CountWarning, Operator: inspection.OpGte, Value: 1},
+			{Count: CountNewIssues, Operator: inspection.OpGt, Value: 0},
+		}, Notify: []string{"gpu", "broken"}},
+		{Notify: []string{"all"}},
+	}
+	d, err := NewDispatcher(notifiers, routes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		report  *inspection.Report
+		want    []string
+		wantErr bool
+	}{
+		// 第一条规则匹配后继续，第二条需要新增问题不匹配，第三条兜底
+		{"critical", newTestReport("daily-gpu-a"), []string{"gpu", "all"}, false},
+		{"other template", newTestReport("daily-node"), []string{"all"}, false},
+		{"new issues", withNewIssue(newTestReport("daily-node")), []string{"gpu", "broken"}, true},
+	}
+	for _, tt := range tests {
+		sent = nil
+		err := d.Dispatch(context.Background(), tt.report)
+		if !slices.Equal(sent, tt.want) {
+			t.Errorf("%s: sent to %v, want %v", tt.name, sent, tt.want)
+		}
+		if (err != nil) != tt.wantErr || (err != nil && !strings.Contains(err.Error(), `notifier "broken"`)) {
+			t.Errorf("%s: got error %v", tt.name, err)
+		}
+	}
+}
+
+func withNewIssue(report *inspection.Report) *inspection.Report {
+	report.Changes = &inspection.ReportDiff{NewIssues: []inspection.Issue{{
+		Indicator:    "GPU 使用率",
+		TargetChange: inspection.TargetChange{Target: "10.0.0.4", Change: inspection.ChangeWorsened, PreviousStatus: "ok", Status: "critical"},
+	}}}
+	return report
+}
+
+func TestParseConfig(t *testing.T) {
+	t.Setenv("TEST_ROBOT_SECRET", "s3cret")
+	t.Setenv("TEST_HOOK_TOKEN", "a\"b\nc: d") // 展开的值不会被当作 YAML 解析
+	d, err := ParseConfig([]byte(`
+notifiers:
+  hook: { type: webhook, url: "http://127.0.0.1/hook", full: true, timeout: 3s, headers: { X-Token: "${TEST_HOOK_TOKEN}" } }
+  robot: { type: dingtalk, url: "http://127.0.0.1/robot", secret: "${TEST_ROBOT_SECRET}" }
+  mail: { type: email, addr: "127.0.0.1:25", from: a@example.com, to: [b@example.com], password: "pa$$w0rd$HOME", timeout: 5s }
+routes:
+  - templates: [daily-*]
+    when: [{ count: critical, operator: gt, value: 0 }]
+    notify: [robot, mail]
+  - notify: [hook]
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if robot := d.notifiers["robot"].(*Robot); robot.Secret != "s3cret" || robot.Kind != RobotDingTalk {
+		t.Errorf("robot: %+v", robot)
+	}
+	if hook := d.notifiers["hook"].(*Webhook); !hook.Full || hook.Client.Timeout != 3*time.Second || hook.Headers["X-Token"] != "a\"b\nc: d" {
+		t.Errorf("hook: %+v", hook)
+	}
+	// 只展开 ${VAR}，其它形式的 $ 保持原样
+	if mail := d.notifiers["mail"].(*Email); mail.Password != "pa$$w0rd$HOME" || mail.Timeout != 5*time.Second {
+		t.Errorf("mail: %+v", mail)
+	}
+	if got := d.Match(newTestReport("daily-gpu")); !slices.Equal(got, []string{"robot", "mail"}) {
+		t.Errorf("Match = %v", got)
+	}
+
+	_, err = ParseConfig([]byte(`
+notifiers:
+  sms: { type: sms }
+  hook: { type: webhook }
+  robot: { type: wecom, url: "http://127.0.0.1/robot?key=${TEST_UNSET_KEY}" }
+routes:
+  - when: [{ count: errors, operator: ge, value: 1 }]
+    notify: [pager]
+`))
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{`notifier "sms": unsupported type`, `notifier "hook": url is required`, `notifier "robot": environment variable not set: TEST_UNSET_KEY`} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err, want)
+		}
+	}
+
+	_, err = ParseConfig([]byte(`
+routes:
+  - when: [{ count: errors, operator: ge, value: 1 }]
+    notify: [pager]
+`))
+	for _, want := range []string{`invalid count "errors"`, `invalid operator "ge"`, `unknown notifier "pager"`} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("error %v does not mention %q", err, want)
+		}
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	report := withNewIssue(newTestReport("daily-gpu"))
+	md := RenderMarkdown(report, 0)
+	for _, want := range []string{
+		"[巡检] GPU 节点每日巡检：严重 1，警告 1，缺失 1",
+		"**GPU 使用率**（严重 1，警告 1，缺失 1）",
+		"- 🔴 10.0.0.4：99 %",
+		"- 🟡 10.0.0.2：85.46 %",
+		"- ⚪ 10.0.0.3",
+		"**新增问题**（1）",
+		"- GPU 使用率 10.0.0.4：正常 → 严重",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown does not contain %q:\n%s", want, md)
+		}
+	}
+	// critical 排在前面，正常的指标不列出
+	if strings.Index(md, "10.0.0.4") > strings.Index(md, "10.0.0.2") || strings.Contains(md, "GPU 温度") {
+		t.Errorf("unexpected markdown:\n%s", md)
+	}
+
+	if md := RenderMarkdown(report, 1); !strings.Contains(md, "另有 2 项") {
+		t.Errorf("expected truncated item list:\n%s", md)
+	}
+	if got := truncateUTF8(strings.Repeat("巡检", 10), 20); len(got) > 20 || !strings.HasSuffix(got, "……") {
+		t.Errorf("truncateUTF8 = %q", got)
+	}
+}
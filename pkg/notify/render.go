@@ -0,0 +1,235 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// defaultMaxItems 每个指标最多列出的异常项数
+const defaultMaxItems = 10
+
+// statusText 状态的中文名称
+var statusText = map[string]string{
+	inspection.ThresholdLevelCritical: "严重",
+	inspection.ThresholdLevelWarning:  "警告",
+	inspection.ThresholdLevelInfo:     "提示",
+	inspection.ThresholdLevelOk:       "正常",
+	inspection.StatusMissing:          "缺失",
+}
+
+// statusIcon 机器人消息中状态的图标
+var statusIcon = map[string]string{
+	inspection.ThresholdLevelCritical: "🔴",
+	inspection.ThresholdLevelWarning:  "🟡",
+	inspection.StatusMissing:          "⚪",
+}
+
+// Subject 通知标题，如 “[巡检] GPU 节点每日巡检：严重 2，警告 3”
+func Subject(report *inspection.Report) string {
+	name := report.Template.DisplayName
+	if name == "" {
+		name = report.Template.Name
+	}
+	return fmt.Sprintf("[巡检] %s：%s", name, countsText(Totals(report)))
+}
+
+// countsText 汇总数量的文字描述，只列出异常数量
+func countsText(s inspection.Summary) string {
+	var parts []string
+	for _, c := range []struct {
+		status string
+		n      int
+	}{
+		{inspection.ThresholdLevelCritical, s.Critical},
+		{inspection.ThresholdLevelWarning, s.Warning},
+		{inspection.StatusMissing, s.Missing},
+	} {
+		if c.n > 0 {
+			parts = append(parts, fmt.Sprintf("%s %d", statusText[c.status], c.n))
+		}
+	}
+	if len(parts) == 0 {
+		return fmt.Sprintf("全部正常（%d 项）", s.Total)
+	}
+	return strings.Join(parts, "，")
+}
+
+// issue 报告中的一个异常项
+type issue struct {
+	Target string
+	Status string
+	Value  string
+}
+
+// indicatorIssues 单个指标的异常项，最多 max 项，More 为未列出的数量
+type indicatorIssues struct {
+	Indicator string
+	Counts    string
+	Issues    []issue
+	More      int
+}
+
+// collectIssues 按报告中指标的顺序收集异常项（warning、critical、缺失），critical 在前
+func collectIssues(report *inspection.Report, max int) []indicatorIssues {
+	var result []indicatorIssues
+	for _, r := range report.Results {
+		var critical, others []issue
+		for _, item := range r.Values {
+			it := issue{Target: item.Target, Status: item.Status}
+			switch {
+			case item.Missing:
+				it.Status = inspection.StatusMissing
+				others = append(others, it)
+			case item.Status == inspection.ThresholdLevelCritical:
+				it.Value = formatValue(item.Value, r.Unit)
+				critical = append(critical, it)
+			case item.Status == inspection.ThresholdLevelWarning:
+				it.Value = formatValue(item.Value, r.Unit)
+				others = append(others, it)
+			}
+		}
+		all := append(critical, others...)
+		if len(all) == 0 {
+			continue
+		}
+		ind := indicatorIssues{Indicator: r.Indicator, Counts: countsText(r.Summary), Issues: all}
+		if max > 0 && len(all) > max {
+			ind.Issues, ind.More = all[:max], len(all)-max
+		}
+		result = append(result, ind)
+	}
+	return result
+}
+
+// formatValue 保留两位小数并附带单位
+func formatValue(v *float64, unit string) string {
+	if v == nil {
+		return ""
+	}
+	s := strconv.FormatFloat(math.Round(*v*100)/100, 'f', -1, 64)
+	if unit != "" {
+		s += " " + unit
+	}
+	return s
+}
+
+// RenderMarkdown 将报告渲染为机器人消息使用的 Markdown：汇总、每个指标的异常项（最多 maxItems 项，
+// 不大于 0 时使用默认值 10）与相比上次报告的新增、已解决问题。钉钉与企业微信不支持表格，只使用标题与列表
+func RenderMarkdown(report *inspection.Report, maxItems int) string {
+	if maxItems <= 0 {
+		maxItems = defaultMaxItems
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "#### %s\n\n", Subject(report))
+	fmt.Fprintf(&b, "> 执行时间：%s\n\n", report.Template.ExecutedAt.Format("2006-01-02 15:04:05"))
+
+	for _, ind := range collectIssues(report, maxItems) {
+		fmt.Fprintf(&b, "**%s**（%s）\n\n", ind.Indicator, ind.Counts)
+		for _, it := range ind.Issues {
+			fmt.Fprintf(&b, "- %s %s", statusIcon[it.Status], it.Target)
+			if it.Value != "" {
+				fmt.Fprintf(&b, "：%s", it.Value)
+			}
+			b.WriteString("\n")
+		}
+		if ind.More > 0 {
+			fmt.Fprintf(&b, "- …… 另有 %d 项\n", ind.More)
+		}
+		b.WriteString("\n")
+	}
+
+	if c := report.Changes; c != nil {
+		writeIssues := func(title string, issues []inspection.Issue) {
+			if len(issues) == 0 {
+				return
+			}
+			fmt.Fprintf(&b, "**%s**（%d）\n\n", title, len(issues))
+			for i, is := range issues {
+				if i == maxItems {
+					fmt.Fprintf(&b, "- …… 另有 %d 项\n", len(issues)-maxItems)
+					break
+				}
+				fmt.Fprintf(&b, "- %s %s：%s → %s\n", is.Indicator, is.Target, changeStatus(is.PreviousStatus), changeStatus(is.Status))
+			}
+			b.WriteString("\n")
+		}
+		writeIssues("新增问题", c.NewIssues)
+		writeIssues("已解决问题", c.Resolved)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// changeStatus 差异中的状态，目标不存在时为“无”
+func changeStatus(status string) string {
+	if status == "" {
+		return "无"
+	}
+	if text, ok := statusText[status]; ok {
+		return text
+	}
+	return status
+}
+
+var htmlReport = template.Must(template.New("report").Funcs(template.FuncMap{
+	"status": changeStatus,
+}).Parse(`<!DOCTYPE html>
+<html><head><meta charset="utf-8"><title>{{.Subject}}</title></head>
+<body style="font-family: sans-serif">
+<h2>{{.Subject}}</h2>
+<p>执行时间：{{.Report.Template.ExecutedAt.Format "2006-01-02 15:04:05"}}{{with .Report.Template.ExecutedBy}}，执行人：{{.}}{{end}}</p>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>指标</th><th>总数</th><th>正常</th><th>提示</th><th>警告</th><th>严重</th><th>缺失</th></tr>
+{{- range .Report.SummaryOverviews}}
+<tr><td>{{.Indicator}}</td><td>{{.Total}}</td><td>{{.Ok}}</td><td>{{.Info}}</td><td>{{.Warning}}</td><td>{{.Critical}}</td><td>{{.Missing}}</td></tr>
+{{- end}}
+</table>
+{{- range .Issues}}
+<h3>{{.Indicator}}（{{.Counts}}）</h3>
+<table border="1" cellspacing="0" cellpadding="4">
+<tr><th>目标</th><th>状态</th><th>值</th></tr>
+{{- range .Issues}}
+<tr><td>{{.Target}}</td><td>{{status .Status}}</td><td>{{.Value}}</td></tr>
+{{- end}}
+{{- if .More}}
+<tr><td colspan="3">另有 {{.More}} 项</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- with .Report.Changes}}
+{{- if .NewIssues}}
+<h3>新增问题（{{len .NewIssues}}）</h3>
+<ul>
+{{- range .NewIssues}}
+<li>{{.Indicator}} {{.Target}}：{{status .PreviousStatus}} → {{status .Status}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Resolved}}
+<h3>已解决问题（{{len .Resolved}}）</h3>
+<ul>
+{{- range .Resolved}}
+<li>{{.Indicator}} {{.Target}}：{{status .PreviousStatus}} → {{status .Status}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- end}}
+</body></html>
+`))
+
+// RenderHTML 将报告渲染为邮件使用的 HTML：各指标汇总表、异常项（每个指标最多 maxItems 项，
+// 不大于 0 时不限制）与相比上次报告的新增、已解决问题
+func RenderHTML(report *inspection.Report, maxItems int) (string, error) {
+	var buf bytes.Buffer
+	err := htmlReport.Execute(&buf, map[string]any{
+		"Subject": Subject(report),
+		"Report":  report,
+		"Issues":  collectIssues(report, maxItems),
+	})
+	return buf.String(), err
+}
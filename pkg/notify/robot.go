@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// RobotKind 群机器人类型
+type RobotKind string
+
+const (
+	RobotDingTalk RobotKind = "dingtalk" // 钉钉自定义机器人
+	RobotWeCom    RobotKind = "wecom"    // 企业微信群机器人
+	RobotFeishu   RobotKind = "feishu"   // 飞书自定义机器人
+)
+
+// 各机器人 Markdown 消息的长度上限（字节），超出时截断
+var robotMaxBytes = map[RobotKind]int{
+	RobotDingTalk: 20000,
+	RobotWeCom:    4096,
+	RobotFeishu:   30000,
+}
+
+// Robot 群机器人：以 Markdown 消息发送汇总与异常项
+type Robot struct {
+	Kind RobotKind
+	// URL 机器人的 Webhook 地址，包含 access_token / key
+	URL string
+	// Secret 加签密钥，钉钉与飞书开启“加签”安全设置时需要；企业微信不使用
+	Secret string
+	// MaxItems 每个指标最多列出的异常项数，不大于 0 时为 10
+	MaxItems int
+	Client   *http.Client // 为 nil 时使用 http.DefaultClient
+
+	now func() time.Time // 测试时固定签名时间
+}
+
+var _ Notifier = (*Robot)(nil)
+
+// Notify 发送 Markdown 消息，机器人返回错误码时返回错误
+func (r *Robot) Notify(ctx context.Context, report *inspection.Report) error {
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	text := truncateUTF8(RenderMarkdown(report, r.MaxItems), robotMaxBytes[r.Kind])
+	title := Subject(report)
+
+	target, body := r.URL, any(nil)
+	switch r.Kind {
+	case RobotDingTalk:
+		// https://open.dingtalk.com/document/robots/custom-robot-access
+		if r.Secret != "" {
+			var err error
+			if target, err = dingTalkSignedURL(r.URL, r.Secret, now()); err != nil {
+				return fmt.Errorf("dingtalk robot: %w", err)
+			}
+		}
+		body = map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"title": title, "text": text},
+		}
+	case RobotWeCom:
+		// https://developer.work.weixin.qq.com/document/path/91770
+		body = map[string]any{
+			"msgtype":  "markdown",
+			"markdown": map[string]string{"content": text},
+		}
+	case RobotFeishu:
+		// https://open.feishu.cn/document/client-docs/bot-v3/add-custom-bot
+		msg := map[string]any{
+			"msg_type": "interactive",
+			"card": map[string]any{
+				"header":   map[string]any{"title": map[string]string{"tag": "plain_text", "content": title}},
+				"elements": []any{map[string]string{"tag": "markdown", "content": text}},
+			},
+		}
+		if r.Secret != "" {
+			ts := now().Unix()
+			msg["timestamp"] = strconv.FormatInt(ts, 10)
+			msg["sign"] = feishuSign(r.Secret, ts)
+		}
+		body = msg
+	default:
+		return fmt.Errorf("unsupported robot kind %q", r.Kind)
+	}
+
+	if err := postJSON(ctx, r.Client, target, nil, body, checkRobotResponse); err != nil {
+		return fmt.Errorf("%s robot: %w", r.Kind, err)
+	}
+	return nil
+}
+
+// checkRobotResponse 检查机器人的响应：钉钉与企业微信为 errcode，飞书为 code，非 0 表示失败
+func checkRobotResponse(data []byte) error {
+	var resp struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("parse response: %w", err)
+	}
+	if resp.ErrCode != nil && *resp.ErrCode != 0 {
+		return fmt.Errorf("errcode %d: %s", *resp.ErrCode, resp.ErrMsg)
+	}
+	if resp.Code != nil && *resp.Code != 0 {
+		return fmt.Errorf("code %d: %s", *resp.Code, resp.Msg)
+	}
+	return nil
+}
+
+// dingTalkSignedURL 钉钉加签：对 “毫秒时间戳\n密钥” 以密钥做 HmacSHA256，Base64 后作为 sign 参数
+func dingTalkSignedURL(rawURL, secret string, now time.Time) (string, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+	ts := strconv.FormatInt(now.UnixMilli(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "\n" + secret))
+	q := u.Query()
+	q.Set("timestamp", ts)
+	q.Set("sign", base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// feishuSign 飞书加签：以 “秒级时间戳\n密钥” 为密钥对空串做 HmacSHA256，再 Base64
+func feishuSign(secret string, ts int64) string {
+	mac := hmac.New(sha256.New, []byte(strconv.FormatInt(ts, 10)+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// truncateUTF8 将 s 截断到不超过 max 字节，不截断多字节字符，max 不大于 0 时不截断
+func truncateUTF8(s string, max int) string {
+	const ellipsis = "\n……"
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	cut := max - len(ellipsis)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + ellipsis
+}
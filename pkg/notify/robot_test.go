@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// robotServer 模拟机器人 Webhook，记录请求的查询参数与请求体，以 response 响应
+func robotServer(t *testing.T, response string) (*httptest.Server, *http.Request, map[string]any) {
+	t.Helper()
+	req := &http.Request{}
+	body := map[string]any{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*req = *r.Clone(context.Background())
+		data, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(data, &body); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		io.WriteString(w, response)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, req, body
+}
+
+func TestRobot(t *testing.T) {
+	now := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+	report := newTestReport("daily-gpu")
+
+	t.Run("dingtalk", func(t *testing.T) {
+		srv, req, body := robotServer(t, `{"errcode":0,"errmsg":"ok"}`)
+		r := &Robot{Kind: RobotDingTalk, URL: srv.URL + "/robot/send?access_token=abc", Secret: "SEC", now: func() time.Time { return now }}
+		if err := r.Notify(context.Background(), report); err != nil {
+			t.Fatal(err)
+		}
+		q := req.URL.Query()
+		mac := hmac.New(sha256.New, []byte("SEC"))
+		mac.Write([]byte("1767258000000\nSEC"))
+		if q.Get("access_token") != "abc" || q.Get("timestamp") != "1767258000000" ||
+			q.Get("sign") != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("unexpected query %v", q)
+		}
+		md := body["markdown"].(map[string]any)
+		if body["msgtype"] != "markdown" || !strings.HasPrefix(md["title"].(string), "[巡检]") ||
+			!strings.Contains(md["text"].(string), "10.0.0.4") {
+			t.Errorf("unexpected body %v", body)
+		}
+	})
+
+	t.Run("wecom", func(t *testing.T) {
+		srv, _, body := robotServer(t, `{"errcode":0,"errmsg":"ok"}`)
+		r := &Robot{Kind: RobotWeCom, URL: srv.URL + "/cgi-bin/webhook/send?key=abc"}
+		if err := r.Notify(context.Background(), report); err != nil {
+			t.Fatal(err)
+		}
+		md := body["markdown"].(map[string]any)
+		if body["msgtype"] != "markdown" || !strings.Contains(md["content"].(string), "10.0.0.4") {
+			t.Errorf("unexpected body %v", body)
+		}
+	})
+
+	t.Run("feishu", func(t *testing.T) {
+		srv, _, body := robotServer(t, `{"code":0,"msg":"success"}`)
+		r := &Robot{Kind: RobotFeishu, URL: srv.URL + "/open-apis/bot/v2/hook/abc", Secret: "SEC", now: func() time.Time { return now }}
+		if err := r.Notify(context.Background(), report); err != nil {
+			t.Fatal(err)
+		}
+		mac := hmac.New(sha256.New, []byte("1767258000\nSEC"))
+		if body["msg_type"] != "interactive" || body["timestamp"] != "1767258000" ||
+			body["sign"] != base64.StdEncoding.EncodeToString(mac.Sum(nil)) {
+			t.Errorf("unexpected body %v", body)
+		}
+		elements := body["card"].(map[string]any)["elements"].([]any)
+		if content := elements[0].(map[string]any)["content"].(string); !strings.Contains(content, "10.0.0.4") {
+			t.Errorf("unexpected card content %q", content)
+		}
+	})
+
+	t.Run("error code", func(t *testing.T) {
+		for kind, response := range map[RobotKind]string{
+			RobotDingTalk: `{"errcode":310000,"errmsg":"sign not match"}`,
+			RobotFeishu:   `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`,
+		} {
+			srv, _, _ := robotServer(t, response)
+			err := (&Robot{Kind: kind, URL: srv.URL}).Notify(context.Background(), report)
+			if err == nil || !strings.Contains(err.Error(), string(kind)+" robot") {
+				t.Errorf("%s: got %v, want error code", kind, err)
+			}
+		}
+	})
+
+	t.Run("wecom length limit", func(t *testing.T) {
+		srv, _, body := robotServer(t, `{"errcode":0}`)
+		big := newTestReport("daily-gpu")
+		big.Results[0].Values = nil
+		for range 500 {
+			big.Results[0].Values = append(big.Results[0].Values, report.Results[0].Values[3])
+		}
+		r := &Robot{Kind: RobotWeCom, URL: srv.URL, MaxItems: 500}
+		if err := r.Notify(context.Background(), big); err != nil {
+			t.Fatal(err)
+		}
+		if content := body["markdown"].(map[string]any)["content"].(string); len(content) > 4096 {
+			t.Errorf("content length %d exceeds 4096", len(content))
+		}
+	})
+}
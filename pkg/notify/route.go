@@ -0,0 +1,198 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// 路由条件可比较的数量：报告汇总中各状态的合计，以及相比上次报告的新增、已解决问题数
+const (
+	CountTotal     = "total"
+	CountOk        = "ok"
+	CountInfo      = "info"
+	CountWarning   = "warning"
+	CountCritical  = "critical"
+	CountMissing   = "missing"
+	CountNewIssues = "new_issues" // 报告没有 Changes 时为 0
+	CountResolved  = "resolved"
+)
+
+// Route 路由规则：模板匹配且汇总数量满足条件时，发送到 Notify 中的通知渠道
+//
+//	routes:
+//	  - templates: [daily-gpu-*]
+//	    when:
+//	      - { count: critical, operator: gte, value: 1 }
+//	      - { count: new_issues, operator: gt, value: 0 }
+//	    notify: [ops-dingtalk, oncall-email]
+type Route struct {
+	// Templates 模板名，支持 path.Match 通配符；为空时匹配所有模板
+	Templates []string `yaml:"templates"`
+	// Logic 多个条件的关系：and / or，默认 or
+	Logic string `yaml:"logic"`
+	// When 为空时只按模板匹配
+	When   []CountCondition `yaml:"when"`
+	Notify []string         `yaml:"notify"`
+	// Continue 匹配后是否继续匹配后续规则，默认在第一条匹配的规则处停止
+	Continue bool `yaml:"continue"`
+}
+
+// CountCondition 对汇总数量的条件，如 critical >= 1
+type CountCondition struct {
+	Count    string `yaml:"count"`
+	Operator string `yaml:"operator"` // gt/gte/lt/lte/eq
+	Value    int    `yaml:"value"`
+}
+
+// validate 检查规则，notifiers 为已配置的通知渠道
+func (r *Route) validate(notifiers map[string]Notifier) error {
+	var errs []error
+	for _, pattern := range r.Templates {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("invalid template pattern %q", pattern))
+		}
+	}
+	if r.Logic != "" && r.Logic != inspection.LogicAnd && r.Logic != inspection.LogicOr {
+		errs = append(errs, fmt.Errorf("invalid logic %q, want and or or", r.Logic))
+	}
+	for i, c := range r.When {
+		if _, ok := countOf(inspection.Summary{}, nil, c.Count); !ok {
+			errs = append(errs, fmt.Errorf("when[%d]: invalid count %q", i, c.Count))
+		}
+		if _, ok := compare[c.Operator]; !ok {
+			errs = append(errs, fmt.Errorf("when[%d]: invalid operator %q", i, c.Operator))
+		}
+	}
+	if len(r.Notify) == 0 {
+		errs = append(errs, errors.New("no notifiers"))
+	}
+	for _, name := range r.Notify {
+		if _, ok := notifiers[name]; !ok {
+			errs = append(errs, fmt.Errorf("unknown notifier %q", name))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// match 报告是否匹配规则
+func (r *Route) match(report *inspection.Report, totals inspection.Summary) bool {
+	if len(r.Templates) > 0 {
+		matched := false
+		for _, pattern := range r.Templates {
+			if ok, _ := path.Match(pattern, report.Template.Name); ok {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.When) == 0 {
+		return true
+	}
+	for _, c := range r.When {
+		n, _ := countOf(totals, report.Changes, c.Count)
+		met := compare[c.Operator](n, c.Value)
+		if r.Logic == inspection.LogicAnd && !met {
+			return false
+		}
+		if r.Logic != inspection.LogicAnd && met {
+			return true
+		}
+	}
+	return r.Logic == inspection.LogicAnd
+}
+
+var compare = map[string]func(a, b int) bool{
+	inspection.OpGt:  func(a, b int) bool { return a > b },
+	inspection.OpGte: func(a, b int) bool { return a >= b },
+	inspection.OpLt:  func(a, b int) bool { return a < b },
+	inspection.OpLte: func(a, b int) bool { return a <= b },
+	inspection.OpEq:  func(a, b int) bool { return a == b },
+}
+
+// countOf 取条件比较的数量
+func countOf(s inspection.Summary, changes *inspection.ReportDiff, count string) (int, bool) {
+	switch count {
+	case CountTotal:
+		return s.Total, true
+	case CountOk:
+		return s.Ok, true
+	case CountInfo:
+		return s.Info, true
+	case CountWarning:
+		return s.Warning, true
+	case CountCritical:
+		return s.Critical, true
+	case CountMissing:
+		return s.Missing, true
+	case CountNewIssues, CountResolved:
+		if changes == nil {
+			return 0, true
+		}
+		if count == CountNewIssues {
+			return len(changes.NewIssues), true
+		}
+		return len(changes.Resolved), true
+	}
+	return 0, false
+}
+
+// Dispatcher 按路由规则将报告发送到通知渠道
+type Dispatcher struct {
+	notifiers map[string]Notifier
+	routes    []Route
+}
+
+// NewDispatcher 创建 Dispatcher，规则引用的通知渠道必须存在
+func NewDispatcher(notifiers map[string]Notifier, routes []Route) (*Dispatcher, error) {
+	var errs []error
+	for i := range routes {
+		if err := routes[i].validate(notifiers); err != nil {
+			errs = append(errs, fmt.Errorf("routes[%d]: %w", i, err))
+		}
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return &Dispatcher{notifiers: notifiers, routes: routes}, nil
+}
+
+// Match 返回报告匹配的通知渠道名称，按规则顺序去重
+func (d *Dispatcher) Match(report *inspection.Report) []string {
+	totals := Totals(report)
+	var names []string
+	seen := make(map[string]bool)
+	for i := range d.routes {
+		r := &d.routes[i]
+		if !r.match(report, totals) {
+			continue
+		}
+		for _, name := range r.Notify {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+		if !r.Continue {
+			break
+		}
+	}
+	return names
+}
+
+// Dispatch 将报告发送到所有匹配的通知渠道；单个渠道失败不影响其它渠道，返回汇总的错误
+func (d *Dispatcher) Dispatch(ctx context.Context, report *inspection.Report) error {
+	var errs []error
+	for _, name := range d.Match(report) {
+		if err := d.notifiers[name].Notify(ctx, report); err != nil {
+			errs = append(errs, fmt.Errorf("notifier %q: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
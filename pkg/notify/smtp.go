@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// Email 通过 SMTP 发送 HTML 格式的报告
+type Email struct {
+	// Addr SMTP 服务器地址，host:port；服务器支持 STARTTLS 时自动启用
+	Addr string
+	From string
+	To   []string
+	// Username、Password 为空时不认证
+	Username string
+	Password string
+	// MaxItems 每个指标最多列出的异常项数，不大于 0 时不限制
+	MaxItems int
+	// Timeout 连接与发送的总超时时间，默认 10s
+	Timeout time.Duration
+}
+
+var _ Notifier = (*Email)(nil)
+
+// Notify 发送邮件；超时或 ctx 结束时关闭连接，中断阻塞的读写
+func (e *Email) Notify(ctx context.Context, report *inspection.Report) error {
+	if len(e.To) == 0 {
+		return errors.New("email: no recipients")
+	}
+	html, err := RenderHTML(report, e.MaxItems)
+	if err != nil {
+		return fmt.Errorf("email: render report: %w", err)
+	}
+	msg, err := buildMessage(e.From, e.To, Subject(report), html, time.Now())
+	if err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := e.send(ctx, msg); err != nil {
+		return fmt.Errorf("email: %w", err)
+	}
+	return nil
+}
+
+// send 与 smtp.SendMail 相同，但连接受 ctx 控制
+func (e *Email) send(ctx context.Context, msg []byte) error {
+	host, _, err := net.SplitHostPort(e.Addr)
+	if err != nil {
+		return err
+	}
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", e.Addr)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			conn.Close()
+			return err
+		}
+	}
+	stop := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return contextError(ctx, err)
+	}
+	defer c.Close()
+	if err := e.deliver(c, host, msg); err != nil {
+		return contextError(ctx, err)
+	}
+	return nil
+}
+
+// deliver 在已建立的会话上认证并发送邮件
+func (e *Email) deliver(c *smtp.Client, host string, msg []byte) error {
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		if ok, _ := c.Extension("AUTH"); !ok {
+			return errors.New("server does not support AUTH")
+		}
+		if err := c.Auth(smtp.PlainAuth("", e.Username, e.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, addr := range e.To {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// contextError 连接因 ctx 结束被关闭或到达截止时间时，错误中带上 ctx 的错误；
+// 连接的截止时间与 ctx 相同，可能先于 ctx 触发
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+	if errors.Is(err, os.ErrDeadlineExceeded) {
+		return fmt.Errorf("%w: %w", context.DeadlineExceeded, err)
+	}
+	return err
+}
+
+// buildMessage 生成 HTML 邮件，标题按 RFC 2047 编码，正文使用 quoted-printable
+func buildMessage(from string, to []string, subject, html string, date time.Time) ([]byte, error) {
+	for _, addr := range append([]string{from}, to...) {
+		if strings.ContainsAny(addr, "\r\n") {
+			return nil, fmt.Errorf("invalid address %q", addr)
+		}
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", from)
+	fmt.Fprintf(&buf, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.BEncoding.Encode("UTF-8", subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+	w := quotedprintable.NewWriter(&buf)
+	if _, err := w.Write([]byte(html)); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,155 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpMessage 模拟 SMTP 服务器收到的邮件
+type smtpMessage struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer 启动只支持 EHLO/MAIL/RCPT/DATA/QUIT 的 SMTP 服务器，收到的邮件写入返回的 channel
+func smtpServer(t *testing.T) (string, <-chan smtpMessage) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan smtpMessage, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(line string) { io.WriteString(conn, line+"\r\n") }
+		reply("220 localhost ESMTP")
+		var msg smtpMessage
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.TrimRight(line, "\r\n")
+			switch upper := strings.ToUpper(cmd); {
+			case strings.HasPrefix(upper, "EHLO"), strings.HasPrefix(upper, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(upper, "MAIL FROM:"):
+				msg.from = strings.Trim(cmd[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(upper, "RCPT TO:"):
+				msg.to = append(msg.to, strings.Trim(cmd[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case upper == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if line == ".\r\n" {
+						break
+					}
+					data.WriteString(strings.TrimPrefix(line, "."))
+				}
+				msg.data = data.String()
+				reply("250 OK")
+			case upper == "QUIT":
+				reply("221 Bye")
+				messages <- msg
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().String(), messages
+}
+
+func TestEmail(t *testing.T) {
+	addr, messages := smtpServer(t)
+	e := &Email{Addr: addr, From: "inspection@example.com", To: []string{"ops@example.com", "oncall@example.com"}}
+	if err := e.Notify(context.Background(), newTestReport("daily-gpu")); err != nil {
+		t.Fatal(err)
+	}
+	msg := <-messages
+	if msg.from != "inspection@example.com" || strings.Join(msg.to, ",") != "ops@example.com,oncall@example.com" {
+		t.Errorf("envelope from %q to %v", msg.from, msg.to)
+	}
+
+	m, err := mail.ReadMessage(strings.NewReader(msg.data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(m.Header.Get("Subject"))
+	if err != nil || subject != "[巡检] GPU 节点每日巡检：严重 1，警告 1，缺失 1" {
+		t.Errorf("subject = %q, %v", subject, err)
+	}
+	if ct := m.Header.Get("Content-Type"); ct != "text/html; charset=UTF-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	body, err := io.ReadAll(quotedprintable.NewReader(m.Body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"<html", "GPU 使用率", "10.0.0.4"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("body does not contain %q:\n%s", want, body)
+		}
+	}
+
+	if _, err := buildMessage("a@example.com\r\nBcc: x@example.com", []string{"b@example.com"}, "s", "", time.Now()); err == nil {
+		t.Error("expected error for header injection")
+	}
+}
+
+func TestEmailTimeout(t *testing.T) {
+	// 接受连接但不响应的服务器
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	report := newTestReport("daily-gpu")
+	e := &Email{Addr: ln.Addr().String(), From: "a@example.com", To: []string{"b@example.com"}, Timeout: 50 * time.Millisecond}
+	start := time.Now()
+	if err := e.Notify(context.Background(), report); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("got %v, want deadline exceeded", err)
+	}
+
+	e.Timeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if err := e.Notify(ctx, report); !errors.Is(err, context.Canceled) {
+		t.Errorf("got %v, want canceled", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Notify blocked for %s", elapsed)
+	}
+}
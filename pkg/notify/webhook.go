@@ -0,0 +1,32 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// Webhook 通用 Webhook：以 JSON POST Payload
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+	// Full 为 true 时 Payload 中附带完整报告
+	Full   bool
+	Client *http.Client // 为 nil 时使用 http.DefaultClient
+}
+
+var _ Notifier = (*Webhook)(nil)
+
+// Notify 发送报告摘要
+func (w *Webhook) Notify(ctx context.Context, report *inspection.Report) error {
+	payload := NewPayload(report)
+	if w.Full {
+		payload.Report = report
+	}
+	if err := postJSON(ctx, w.Client, w.URL, w.Headers, payload, nil); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
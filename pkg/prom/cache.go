@@ -1,6 +1,7 @@
 package prom
 
 import (
+	"context"
 	"fmt"
 	"sync"
 	"time"
@@ -11,223 +12,269 @@ import (
 // ActiveTargetsByPool 使用 map 结构存储按 scrapePool 分组的 targets
 type ActiveTargetsByPool map[string][]v1.ActiveTarget
 
-// TargetIndex 多维度索引
+// TargetIndex 多维度倒排索引，值为目标在缓存目标切片中的下标，按升序排列
+//
+// ByJob、ByInstance 与 ByCustomLabel 中对应标签的内层 map 是同一个，不额外占用内存。
 type TargetIndex struct {
-	ByJob         map[string][]v1.ActiveTarget
-	ByInstance    map[string][]v1.ActiveTarget
-	ByHealth      map[string][]v1.ActiveTarget
-	ByPool        map[string][]v1.ActiveTarget
-	ByCustomLabel map[string]map[string][]v1.ActiveTarget // 支持任意标签索引
+	ByJob         map[string][]int
+	ByInstance    map[string][]int
+	ByHealth      map[string][]int
+	ByPool        map[string][]int
+	ByCustomLabel map[string]map[string][]int // 支持任意标签索引
 }
 
 // IndexedTargetCache 带索引的目标缓存
+//
+// 每个目标只在 targets 中存储一份，索引只保存下标；刷新时目标集合不变则原地更新，
+// 只有新增目标时追加索引，有目标消失时才全量重建。
 type IndexedTargetCache struct {
-	client     *Client
-	allTargets ActiveTargetsByPool
-	index      TargetIndex
-	cacheTime  time.Time
-	mutex      sync.RWMutex
-	ttl        time.Duration
-	stopChan   chan struct{}
+	fetch     fetchFunc       // 获取全部目标，静态缓存为 nil
+	fetchCtx  context.Context // 后台刷新使用的 context
+	targets   []v1.ActiveTarget
+	ids       map[targetRef]int // 目标 → targets 中的下标
+	index     TargetIndex
+	cacheTime time.Time
+	mutex     sync.RWMutex
+	ttl       time.Duration
+	stopChan  chan struct{}
+	state     *refreshState
+
+	refreshes       uint64
+	refreshFailures uint64
+	subscribers     []chan TargetChangeEvent
+	closed          bool
+}
+
+// CacheStats 缓存的刷新统计，用于暴露自监控指标
+type CacheStats struct {
+	LastRefresh     time.Time      // 最近一次成功刷新的时间
+	Refreshes       uint64         // 刷新总次数，包含失败
+	RefreshFailures uint64         // 刷新失败次数
+	TargetsByPool   map[string]int // 各 scrapePool 的目标数
 }
 
+// fetchFunc 获取缓存的全部目标
+type fetchFunc func(ctx context.Context) ([]v1.ActiveTarget, error)
+
 // NewIndexedTargetCache 创建带索引的目标缓存
-func NewIndexedTargetCache(client *Client, ttl time.Duration) *IndexedTargetCache {
+//
+// 创建时同步刷新一次；失败时缓存未就绪，后台按退避间隔重试，可通过 LastError 查看原因、
+// WaitReady 等待首次成功刷新。
+func NewIndexedTargetCache(client *Client, ttl time.Duration, opts ...CacheOption) *IndexedTargetCache {
+	return newIndexedTargetCache(client.ctx, func(ctx context.Context) ([]v1.ActiveTarget, error) {
+		c := client.WithContext(ctx)
+		defer c.Close()
+		pools, err := c.GetActiveTargetsByPool()
+		if err != nil {
+			return nil, err
+		}
+		var targets []v1.ActiveTarget
+		for _, pool := range pools {
+			targets = append(targets, pool.Targets...)
+		}
+		return targets, nil
+	}, ttl, opts)
+}
+
+// newIndexedTargetCache 创建从 fetch 获取目标的缓存，ctx 用于创建时与后台的刷新
+func newIndexedTargetCache(ctx context.Context, fetch fetchFunc, ttl time.Duration, opts []CacheOption) *IndexedTargetCache {
 	if ttl <= 0 {
 		ttl = 30 * time.Second
 	}
 
 	tc := &IndexedTargetCache{
-		client:     client,
-		allTargets: make(ActiveTargetsByPool),
-		index: TargetIndex{
-			ByJob:         make(map[string][]v1.ActiveTarget),
-			ByInstance:    make(map[string][]v1.ActiveTarget),
-			ByHealth:      make(map[string][]v1.ActiveTarget),
-			ByPool:        make(map[string][]v1.ActiveTarget),
-			ByCustomLabel: make(map[string]map[string][]v1.ActiveTarget),
-		},
+		fetch:    fetch,
+		fetchCtx: ctx,
 		ttl:      ttl,
 		stopChan: make(chan struct{}),
+		state:    newRefreshState(ttl, opts),
 	}
-	// 立即刷新缓存，确保缓存有初始数据
+	tc.buildIndex()
+	// 立即刷新缓存，确保缓存有初始数据；失败原因记录在 LastError 中
 	tc.mutex.Lock()
 	_ = tc.refreshCacheUnsafe()
 	tc.mutex.Unlock()
 
-	go tc.backgroundRefresh()
+	go tc.state.loop(tc.stopChan, &tc.mutex, tc.ttl, tc.refreshCacheUnsafe)
 	return tc
 }
 
-// refreshCacheUnsafe 刷新缓存并重建索引
-func (tc *IndexedTargetCache) refreshCacheUnsafe() error {
-	// 通过 Client 获取原始数据
-	allTargets, err := tc.client.GetActiveTargetsByPool()
-	if err != nil {
-		return fmt.Errorf("failed to get targets: %w", err)
-	}
-
-	// 转换为内部存储格式
-	newTargets := make(ActiveTargetsByPool)
-	for _, pool := range allTargets {
-		newTargets[pool.ScrapePool] = pool.Targets
+// NewStaticIndexedTargetCache 使用固定的目标列表创建带索引的缓存，不访问 Prometheus，也不后台刷新，
+// 用于离线测试等由外部提供目标列表的场景
+func NewStaticIndexedTargetCache(targets []v1.ActiveTarget) *IndexedTargetCache {
+	tc := &IndexedTargetCache{
+		targets:  append([]v1.ActiveTarget(nil), targets...),
+		stopChan: make(chan struct{}),
+		state:    newRefreshState(time.Hour, nil),
 	}
-
-	// 更新缓存
-	tc.allTargets = newTargets
-
-	// 重建索引
 	tc.buildIndex()
-
 	tc.cacheTime = time.Now()
-	return nil
+	tc.state.recordUnsafe(nil)
+	return tc
 }
 
-// buildIndex 构建多维度索引
-func (tc *IndexedTargetCache) buildIndex() {
-	// 清空现有索引
-	tc.index.ByJob = make(map[string][]v1.ActiveTarget)
-	tc.index.ByInstance = make(map[string][]v1.ActiveTarget)
-	tc.index.ByHealth = make(map[string][]v1.ActiveTarget)
-	tc.index.ByPool = make(map[string][]v1.ActiveTarget)
-	tc.index.ByCustomLabel = make(map[string]map[string][]v1.ActiveTarget)
-
-	// 重建索引
-	for _, targets := range tc.allTargets {
-		for _, target := range targets {
-			// 按 job 索引
-			if job, exists := target.Labels["job"]; exists {
-				_job := string(job)
-				tc.index.ByJob[_job] = append(tc.index.ByJob[_job], target)
-			}
+// Refresh 立即刷新缓存，不等待后台刷新周期；ctx 控制本次请求，
+// 目标的变化会通知给订阅者。静态缓存没有数据源，调用不做任何事
+func (tc *IndexedTargetCache) Refresh(ctx context.Context) error {
+	if tc.fetch == nil {
+		return nil
+	}
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	return tc.refreshWithContextUnsafe(ctx)
+}
 
-			// 按 instance 索引
-			if instance, exists := target.Labels["instance"]; exists {
-				_instance := string(instance)
-				tc.index.ByInstance[_instance] = append(tc.index.ByInstance[_instance], target)
-			}
+// refreshCacheUnsafe 刷新缓存并重建索引
+func (tc *IndexedTargetCache) refreshCacheUnsafe() error {
+	return tc.refreshWithContextUnsafe(tc.fetchCtx)
+}
 
-			// 按健康状态索引
-			tc.index.ByHealth[string(target.Health)] = append(tc.index.ByHealth[string(target.Health)], target)
-
-			// 按 scrapePool 索引
-			tc.index.ByPool[target.ScrapePool] = append(tc.index.ByPool[target.ScrapePool], target)
-
-			// 为所有自定义标签建立索引
-			for labelName, labelValue := range target.Labels {
-				_label := string(labelName)
-				_value := string(labelValue)
-				if _, exists := tc.index.ByCustomLabel[_label]; !exists {
-					tc.index.ByCustomLabel[_label] = make(map[string][]v1.ActiveTarget)
-				}
-				tc.index.ByCustomLabel[_label][_value] = append(
-					tc.index.ByCustomLabel[_label][_value], target)
-			}
-		}
+// refreshWithContextUnsafe 获取目标、更新索引并通知目标变化
+func (tc *IndexedTargetCache) refreshWithContextUnsafe(ctx context.Context) error {
+	tc.refreshes++
+	next, err := tc.fetch(ctx)
+	if err != nil {
+		tc.refreshFailures++
+		err = fmt.Errorf("failed to get targets: %w", err)
+		tc.state.recordUnsafe(err)
+		return err
 	}
+
+	// 增量更新缓存与索引，并通知目标变化
+	tc.publishUnsafe(tc.updateUnsafe(next))
+
+	tc.cacheTime = time.Now()
+	tc.state.recordUnsafe(nil)
+	return nil
 }
 
+// GetTargetsByJob 按 job 查询目标；
+// 不检查缓存是否就绪与最大陈旧时间，需要区分"没有目标"与"目标未知"时使用 TargetsFor
 func (tc *IndexedTargetCache) GetTargetsByJob(jobName string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
-	return tc.getCopy(tc.index.ByJob[jobName])
+	return tc.collect(tc.index.ByJob[jobName])
 }
 
+// GetTargetsByInstance 按 instance 查询目标；
+// 不检查缓存是否就绪与最大陈旧时间，需要区分"没有目标"与"目标未知"时使用 TargetsFor
 func (tc *IndexedTargetCache) GetTargetsByInstance(instanceName string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
-	return tc.getCopy(tc.index.ByInstance[instanceName])
+	return tc.collect(tc.index.ByInstance[instanceName])
 }
 
+// GetTargetsByHealth 按健康状态查询目标；
+// 不检查缓存是否就绪与最大陈旧时间，需要区分"没有目标"与"目标未知"时使用 TargetsFor
 func (tc *IndexedTargetCache) GetTargetsByHealth(health string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
-	return tc.getCopy(tc.index.ByHealth[health])
+	return tc.collect(tc.index.ByHealth[health])
 }
 
+// GetTargetsByPool 按 scrapePool 查询目标；
+// 不检查缓存是否就绪与最大陈旧时间，需要区分"没有目标"与"目标未知"时使用 TargetsFor 或 TargetsByPool
 func (tc *IndexedTargetCache) GetTargetsByPool(poolName string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
-	return tc.getCopy(tc.index.ByPool[poolName])
+	return tc.collect(tc.index.ByPool[poolName])
 }
 
+// TargetsByPool 与 GetTargetsByPool 相同，但缓存未就绪或数据超过最大陈旧时间时返回错误，
+// 用于需要区分"没有目标"与"目标未知"的场景
+func (tc *IndexedTargetCache) TargetsByPool(poolName string) ([]v1.ActiveTarget, error) {
+	return tc.TargetsFor(poolName)
+}
+
+// GetTargetsByLabel 按目标标签查询目标；
+// 不检查缓存是否就绪与最大陈旧时间，需要区分"没有目标"与"目标未知"时使用 TargetsFor
 func (tc *IndexedTargetCache) GetTargetsByLabel(labelName, labelValue string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
 
-	if labelMap, exists := tc.index.ByCustomLabel[labelName]; exists {
-		return tc.getCopy(labelMap[labelValue])
-	}
-	return []v1.ActiveTarget{}
+	return tc.collect(tc.index.ByCustomLabel[labelName][labelValue])
 }
 
-// getCopy 返回切片的副本，避免并发问题
-func (tc *IndexedTargetCache) getCopy(targets []v1.ActiveTarget) []v1.ActiveTarget {
-	if len(targets) == 0 {
-		return []v1.ActiveTarget{}
+// collect 按下标返回目标的副本，避免并发问题
+func (tc *IndexedTargetCache) collect(ids []int) []v1.ActiveTarget {
+	result := make([]v1.ActiveTarget, len(ids))
+	for i, id := range ids {
+		result[i] = tc.targets[id]
 	}
-	// 创建副本
-	result := make([]v1.ActiveTarget, len(targets))
-	copy(result, targets)
 	return result
 }
 
-// GetTargetsByJobAndHealth 组合查询方法
+// GetTargetsByJobAndHealth 组合查询方法，不检查缓存是否就绪与最大陈旧时间
 func (tc *IndexedTargetCache) GetTargetsByJobAndHealth(jobName, health string) []v1.ActiveTarget {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
 
 	var result []v1.ActiveTarget
-	if jobTargets, exists := tc.index.ByJob[jobName]; exists {
-		for _, target := range jobTargets {
-			if string(target.Health) == health {
-				result = append(result, target)
-			}
+	for _, id := range tc.index.ByJob[jobName] {
+		if string(tc.targets[id].Health) == health {
+			result = append(result, tc.targets[id])
 		}
 	}
 	return result
 }
 
-// GetAllTargetsByPool 获取所有按 pool 分组的 targets
+// GetAllTargetsByPool 获取所有按 pool 分组的 targets，不检查缓存是否就绪与最大陈旧时间
 func (tc *IndexedTargetCache) GetAllTargetsByPool() []ActiveTargetByPool {
 	tc.mutex.RLock()
 	defer tc.mutex.RUnlock()
 
 	var result []ActiveTargetByPool
-	for poolName, targets := range tc.allTargets {
-		// 创建 targets 副本
-		targetsCopy := make([]v1.ActiveTarget, len(targets))
-		copy(targetsCopy, targets)
-
+	for poolName, ids := range tc.index.ByPool {
 		result = append(result, ActiveTargetByPool{
 			ScrapePool: poolName,
-			Targets:    targetsCopy,
+			Targets:    tc.collect(ids),
 		})
 	}
 	return result
 }
 
-// 后台刷新
-func (tc *IndexedTargetCache) backgroundRefresh() {
-	ticker := time.NewTicker(tc.ttl)
-	defer ticker.Stop()
+// Stats 返回缓存的刷新统计
+func (tc *IndexedTargetCache) Stats() CacheStats {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
 
-	for {
-		select {
-		case <-ticker.C:
-			tc.mutex.Lock()
-			err := tc.refreshCacheUnsafe()
-			tc.mutex.Unlock()
-			if err != nil {
-				fmt.Printf("Background cache refresh failed: %v\n", err)
-			}
-		case <-tc.stopChan:
-			return
-		}
+	stats := CacheStats{
+		LastRefresh:     tc.cacheTime,
+		Refreshes:       tc.refreshes,
+		RefreshFailures: tc.refreshFailures,
+		TargetsByPool:   make(map[string]int, len(tc.index.ByPool)),
 	}
+	for pool, ids := range tc.index.ByPool {
+		stats.TargetsByPool[pool] = len(ids)
+	}
+	return stats
 }
 
-// Close 关闭缓存，停止后台刷新
+// LastRefresh 返回上次成功刷新的时间，从未成功时为零值
+func (tc *IndexedTargetCache) LastRefresh() time.Time {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return tc.cacheTime
+}
+
+// LastError 返回最近一次刷新的错误，成功时为 nil
+func (tc *IndexedTargetCache) LastError() error {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return tc.state.lastError
+}
+
+// Ready 缓存是否已成功刷新过
+func (tc *IndexedTargetCache) Ready() bool {
+	return tc.state.isReady()
+}
+
+// WaitReady 等待缓存首次成功刷新，ctx 结束时返回其错误
+func (tc *IndexedTargetCache) WaitReady(ctx context.Context) error {
+	return tc.state.waitReady(ctx)
+}
+
+// Close 关闭缓存，停止后台刷新并关闭所有订阅通道
 func (tc *IndexedTargetCache) Close() {
 	select {
 	case <-tc.stopChan:
@@ -235,6 +282,16 @@ func (tc *IndexedTargetCache) Close() {
 	default:
 		close(tc.stopChan)
 	}
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if !tc.closed {
+		tc.closed = true
+		for _, ch := range tc.subscribers {
+			close(ch)
+		}
+		tc.subscribers = nil
+	}
 }
 
 // TargetCache 普通缓存（不带索引）
@@ -245,10 +302,11 @@ type TargetCache struct {
 	mutex     sync.RWMutex
 	ttl       time.Duration
 	stopChan  chan struct{}
+	state     *refreshState
 }
 
-// NewTargetCache 创建新的目标缓存实例
-func NewTargetCache(client *Client, ttl time.Duration) *TargetCache {
+// NewTargetCache 创建新的目标缓存实例，刷新失败的处理与 NewIndexedTargetCache 相同
+func NewTargetCache(client *Client, ttl time.Duration, opts ...CacheOption) *TargetCache {
 	if ttl <= 0 {
 		ttl = 30 * time.Second
 	}
@@ -258,6 +316,7 @@ func NewTargetCache(client *Client, ttl time.Duration) *TargetCache {
 		cache:    make(map[string][]ActiveTargetByPool),
 		ttl:      ttl,
 		stopChan: make(chan struct{}),
+		state:    newRefreshState(ttl, opts),
 	}
 
 	// 立即刷新缓存，确保缓存有初始数据
@@ -265,7 +324,7 @@ func NewTargetCache(client *Client, ttl time.Duration) *TargetCache {
 	_ = tc.refreshCacheUnsafe()
 	tc.mutex.Unlock()
 
-	go tc.backgroundRefresh()
+	go tc.state.loop(tc.stopChan, &tc.mutex, tc.ttl, tc.refreshCacheUnsafe)
 	return tc
 }
 
@@ -274,7 +333,9 @@ func (tc *TargetCache) refreshCacheUnsafe() error {
 	// 只调用一次接口获取所有活跃目标
 	allTargets, err := tc.client.GetActiveTargetsByPool()
 	if err != nil {
-		return fmt.Errorf("failed to refresh targets cache: %w", err)
+		err = fmt.Errorf("failed to refresh targets cache: %w", err)
+		tc.state.recordUnsafe(err)
+		return err
 	}
 
 	// 在内存中过滤在线和离线目标，而不是再次调用接口
@@ -314,48 +375,46 @@ func (tc *TargetCache) refreshCacheUnsafe() error {
 	tc.cache["online"] = onlineTargets
 	tc.cache["offline"] = offlineTargets
 	tc.cacheTime = time.Now()
+	tc.state.recordUnsafe(nil)
 
 	return nil
 }
 
-// 后台刷新
-func (tc *TargetCache) backgroundRefresh() {
-	ticker := time.NewTicker(tc.ttl)
-	defer ticker.Stop()
+// LastRefresh 返回上次成功刷新的时间，从未成功时为零值
+func (tc *TargetCache) LastRefresh() time.Time {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return tc.cacheTime
+}
 
-	for {
-		select {
-		case <-ticker.C:
-			tc.mutex.Lock()
-			err := tc.refreshCacheUnsafe()
-			tc.mutex.Unlock()
-			if err != nil {
-				fmt.Printf("Background cache refresh failed: %v\n", err)
-			}
-		case <-tc.stopChan:
-			return
-		}
-	}
+// LastError 返回最近一次刷新的错误，成功时为 nil
+func (tc *TargetCache) LastError() error {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return tc.state.lastError
+}
+
+// Ready 缓存是否已成功刷新过
+func (tc *TargetCache) Ready() bool {
+	return tc.state.isReady()
 }
 
-// GetTargetsByType 获取指定类型的 targets
+// WaitReady 等待缓存首次成功刷新，ctx 结束时返回其错误
+func (tc *TargetCache) WaitReady(ctx context.Context) error {
+	return tc.state.waitReady(ctx)
+}
+
+// GetTargetsByType 获取指定类型的 targets：all / online / offline
+//
+// 缓存未就绪时返回 ErrCacheNotReady（附带最近一次刷新的错误），不同步刷新，由后台按退避间隔重试；
+// 数据超过最大陈旧时间时返回 ErrCacheStale。
 func (tc *TargetCache) GetTargetsByType(targetType string) ([]ActiveTargetByPool, error) {
 	tc.mutex.RLock()
-	cached, exists := tc.cache[targetType]
-	tc.mutex.RUnlock()
-
-	if !exists {
-		tc.mutex.Lock()
-		err := tc.refreshCacheUnsafe()
-		if err != nil {
-			tc.mutex.Unlock()
-			return nil, err
-		}
-		cached = tc.cache[targetType]
-		tc.mutex.Unlock()
+	defer tc.mutex.RUnlock()
+	if err := tc.state.checkUnsafe(tc.cacheTime); err != nil {
+		return nil, err
 	}
-
-	return cached, nil
+	return tc.cache[targetType], nil
 }
 
 // Close 关闭缓存，停止后台刷新
@@ -0,0 +1,70 @@
+package prom
+
+import (
+	"sort"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+)
+
+// TargetChangeType 目标变化类型
+type TargetChangeType string
+
+const (
+	TargetAdded         TargetChangeType = "added"          // 新出现的目标
+	TargetRemoved       TargetChangeType = "removed"        // 消失的目标
+	TargetHealthChanged TargetChangeType = "health_changed" // 健康状态变化，如 up→down、down→up
+)
+
+// targetEventBuffer 每个订阅通道的缓冲大小
+const targetEventBuffer = 256
+
+// TargetChangeEvent 两次刷新之间单个目标的变化
+type TargetChangeEvent struct {
+	Type TargetChangeType
+	// Target 变化后的目标；TargetRemoved 时为消失前的目标
+	Target v1.ActiveTarget
+	// PreviousHealth 变化前的健康状态，仅 TargetHealthChanged 时有值
+	PreviousHealth v1.HealthStatus
+}
+
+// Subscribe 订阅目标变化，每次刷新（后台刷新或 Refresh）后推送与上次刷新相比的变化
+//
+// 通道带缓冲，订阅者消费过慢导致缓冲写满时丢弃新事件，不阻塞刷新；
+// 缓存关闭时通道随之关闭。
+func (tc *IndexedTargetCache) Subscribe() <-chan TargetChangeEvent {
+	ch := make(chan TargetChangeEvent, targetEventBuffer)
+
+	tc.mutex.Lock()
+	defer tc.mutex.Unlock()
+	if tc.closed {
+		close(ch)
+		return ch
+	}
+	tc.subscribers = append(tc.subscribers, ch)
+	return ch
+}
+
+// publishUnsafe 向所有订阅者推送事件，调用方需持有写锁
+func (tc *IndexedTargetCache) publishUnsafe(events []TargetChangeEvent) {
+	for _, ch := range tc.subscribers {
+		for _, event := range events {
+			select {
+			case ch <- event:
+			default:
+				// 订阅者缓冲已满，丢弃事件
+			}
+		}
+	}
+}
+
+// targetKey 目标的唯一标识：同一 scrapePool 中目标由标签区分
+func targetKey(target v1.ActiveTarget) string {
+	return target.ScrapePool + "/" + target.Labels.String()
+}
+
+// sortEvents 按 scrapePool 与标签排序事件，使推送顺序稳定
+func sortEvents(events []TargetChangeEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		return targetKey(events[i].Target) < targetKey(events[j].Target)
+	})
+}
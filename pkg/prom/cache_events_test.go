@@ -0,0 +1,95 @@
+package prom
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+func TestIndexedTargetCacheSubscribe(t *testing.T) {
+	srv, client := newTestServer(t)
+	cache := NewIndexedTargetCache(client, time.Hour)
+	defer cache.Close()
+	events := cache.Subscribe()
+
+	// 10.0.0.2 恢复，10.0.0.1 下线，gpu_exporter 消失，新增 10.0.0.3
+	srv.SetTargets(
+		v1.ActiveTarget{ScrapePool: "prometheus", Health: v1.HealthGood,
+			Labels: model.LabelSet{"job": "prometheus", "instance": "localhost:9090"}},
+		v1.ActiveTarget{ScrapePool: "node_exporter", Health: v1.HealthBad,
+			Labels: model.LabelSet{"job": "node_exporter", "instance": "10.0.0.1:9100", "data_center_id": "dc-1"}},
+		v1.ActiveTarget{ScrapePool: "node_exporter", Health: v1.HealthGood,
+			Labels: model.LabelSet{"job": "node_exporter", "instance": "10.0.0.2:9100", "data_center_id": "dc-1"}},
+		v1.ActiveTarget{ScrapePool: "node_exporter", Health: v1.HealthGood,
+			Labels: model.LabelSet{"job": "node_exporter", "instance": "10.0.0.3:9100", "data_center_id": "dc-1"}},
+	)
+	if err := cache.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ      TargetChangeType
+		instance string
+		prev     v1.HealthStatus
+	}{
+		{TargetRemoved, "10.0.0.1:9400", ""},
+		{TargetHealthChanged, "10.0.0.1:9100", v1.HealthGood},
+		{TargetHealthChanged, "10.0.0.2:9100", v1.HealthBad},
+		{TargetAdded, "10.0.0.3:9100", ""},
+	}
+	for _, w := range want {
+		select {
+		case event := <-events:
+			if event.Type != w.typ || string(event.Target.Labels["instance"]) != w.instance || event.PreviousHealth != w.prev {
+				t.Errorf("got event %s %s (prev %q), want %s %s (prev %q)",
+					event.Type, event.Target.Labels["instance"], event.PreviousHealth, w.typ, w.instance, w.prev)
+			}
+		default:
+			t.Fatalf("missing event %s %s", w.typ, w.instance)
+		}
+	}
+
+	// 没有变化时不推送事件
+	if err := cache.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected event without changes: %+v", event)
+	default:
+	}
+
+	// 关闭缓存后通道关闭，之后的订阅立即得到已关闭的通道
+	cache.Close()
+	if _, ok := <-events; ok {
+		t.Error("subscription channel should be closed")
+	}
+	if _, ok := <-cache.Subscribe(); ok {
+		t.Error("subscribe after close should return a closed channel")
+	}
+}
+
+func TestIndexedTargetCacheRefreshContext(t *testing.T) {
+	_, client := newTestServer(t)
+	cache := NewIndexedTargetCache(client, time.Hour)
+	defer cache.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := cache.Refresh(ctx); err == nil {
+		t.Error("expected error with canceled context")
+	}
+	// 失败的刷新保留原有数据
+	if targets := cache.GetTargetsByPool("node_exporter"); len(targets) != 2 {
+		t.Errorf("expected 2 node_exporter targets after failed refresh, got %d", len(targets))
+	}
+
+	static := NewStaticIndexedTargetCache(nil)
+	defer static.Close()
+	if err := static.Refresh(context.Background()); err != nil {
+		t.Errorf("static cache refresh: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package prom
+
+import (
+	"sort"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+)
+
+// targetRef 目标在 ids 中的键：scrapePool 与标签指纹，比 targetKey 的字符串拼接开销小
+type targetRef struct {
+	pool        string
+	fingerprint model.Fingerprint
+}
+
+func refOf(target v1.ActiveTarget) targetRef {
+	return targetRef{pool: target.ScrapePool, fingerprint: target.Labels.Fingerprint()}
+}
+
+// buildIndex 根据 targets 全量重建索引
+func (tc *IndexedTargetCache) buildIndex() {
+	tc.ids = make(map[targetRef]int, len(tc.targets))
+	tc.index = TargetIndex{
+		ByHealth:      make(map[string][]int),
+		ByPool:        make(map[string][]int),
+		ByCustomLabel: make(map[string]map[string][]int),
+	}
+	for id := range tc.targets {
+		tc.ids[refOf(tc.targets[id])] = id
+		tc.indexTarget(id)
+	}
+	tc.linkLabelIndex()
+}
+
+// indexTarget 将下标为 id 的目标加入索引；id 大于已有下标，各倒排列表保持升序
+func (tc *IndexedTargetCache) indexTarget(id int) {
+	target := &tc.targets[id]
+	health := string(target.Health)
+	tc.index.ByHealth[health] = append(tc.index.ByHealth[health], id)
+	tc.index.ByPool[target.ScrapePool] = append(tc.index.ByPool[target.ScrapePool], id)
+
+	// 为所有标签建立索引，job 与 instance 也在其中
+	for labelName, labelValue := range target.Labels {
+		name, value := string(labelName), string(labelValue)
+		values, exists := tc.index.ByCustomLabel[name]
+		if !exists {
+			values = make(map[string][]int)
+			tc.index.ByCustomLabel[name] = values
+		}
+		values[value] = append(values[value], id)
+	}
+}
+
+// linkLabelIndex 让 ByJob、ByInstance 指向 ByCustomLabel 中对应标签的索引
+func (tc *IndexedTargetCache) linkLabelIndex() {
+	tc.index.ByJob = tc.index.ByCustomLabel["job"]
+	tc.index.ByInstance = tc.index.ByCustomLabel["instance"]
+}
+
+// rebuildHealthIndex 只重建健康状态索引，用于目标集合不变而健康状态变化的刷新
+func (tc *IndexedTargetCache) rebuildHealthIndex() {
+	byHealth := make(map[string][]int, len(tc.index.ByHealth))
+	for id := range tc.targets {
+		health := string(tc.targets[id].Health)
+		byHealth[health] = append(byHealth[health], id)
+	}
+	tc.index.ByHealth = byHealth
+}
+
+// updateUnsafe 用新一轮的目标更新缓存与索引，返回与上一轮相比的变化
+//
+// 已有目标原地更新（标签相同，只有健康状态、抓取信息等变化），健康状态变化时只重建 ByHealth；
+// 新增目标追加到末尾并加入索引；有目标消失时全量重建，以保持下标连续。
+func (tc *IndexedTargetCache) updateUnsafe(next []v1.ActiveTarget) []TargetChangeEvent {
+	var events []TargetChangeEvent
+	seen := make([]bool, len(tc.targets))
+	var added []v1.ActiveTarget
+	healthChanged := false
+
+	for _, target := range next {
+		id, exists := tc.ids[refOf(target)]
+		if !exists {
+			events = append(events, TargetChangeEvent{Type: TargetAdded, Target: target})
+			added = append(added, target)
+			continue
+		}
+		seen[id] = true
+		if prev := tc.targets[id].Health; prev != target.Health {
+			events = append(events, TargetChangeEvent{Type: TargetHealthChanged, Target: target, PreviousHealth: prev})
+			healthChanged = true
+		}
+		tc.targets[id] = target
+	}
+
+	removed := false
+	for id, ok := range seen {
+		if !ok {
+			events = append(events, TargetChangeEvent{Type: TargetRemoved, Target: tc.targets[id]})
+			removed = true
+		}
+	}
+	sortEvents(events)
+
+	if removed {
+		tc.targets = next
+		tc.buildIndex()
+		return events
+	}
+	if healthChanged {
+		tc.rebuildHealthIndex()
+	}
+	for _, target := range added {
+		tc.targets = append(tc.targets, target)
+		id := len(tc.targets) - 1
+		tc.ids[refOf(target)] = id
+		tc.indexTarget(id)
+	}
+	if len(added) > 0 {
+		tc.linkLabelIndex()
+	}
+	return events
+}
+
+// intersectPostings 求多个升序倒排列表的交集
+func intersectPostings(postings [][]int) []int {
+	sort.Slice(postings, func(i, j int) bool { return len(postings[i]) < len(postings[j]) })
+	result := postings[0]
+	for _, p := range postings[1:] {
+		result = intersectSorted(result, p)
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+// intersectSorted 合并求两个升序列表的交集
+func intersectSorted(a, b []int) []int {
+	var result []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			result = append(result, a[i])
+			i++
+			j++
+		}
+	}
+	return result
+}
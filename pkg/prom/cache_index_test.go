@@ -0,0 +1,204 @@
+package prom
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// assertIndexConsistent 增量更新后的索引应与按当前目标全量重建的结果一致
+func assertIndexConsistent(t *testing.T, tc *IndexedTargetCache) {
+	t.Helper()
+	rebuilt := &IndexedTargetCache{targets: tc.targets}
+	rebuilt.buildIndex()
+	if !reflect.DeepEqual(tc.index, rebuilt.index) {
+		t.Errorf("incremental index differs from rebuild:\n got  %+v\n want %+v", tc.index, rebuilt.index)
+	}
+	if !reflect.DeepEqual(tc.ids, rebuilt.ids) {
+		t.Errorf("incremental ids differ from rebuild:\n got  %v\n want %v", tc.ids, rebuilt.ids)
+	}
+}
+
+func nodeTarget(instance string, health v1.HealthStatus) v1.ActiveTarget {
+	return v1.ActiveTarget{
+		ScrapePool: "node_exporter",
+		Health:     health,
+		Labels:     model.LabelSet{"job": "node", "instance": model.LabelValue(instance)},
+	}
+}
+
+func TestIndexedTargetCacheIncrementalUpdate(t *testing.T) {
+	tc := NewStaticIndexedTargetCache([]v1.ActiveTarget{
+		nodeTarget("a:9100", v1.HealthGood),
+		nodeTarget("b:9100", v1.HealthGood),
+	})
+	defer tc.Close()
+
+	// 健康状态变化：原地更新，下标不变
+	tc.mutex.Lock()
+	events := tc.updateUnsafe([]v1.ActiveTarget{nodeTarget("b:9100", v1.HealthBad), nodeTarget("a:9100", v1.HealthGood)})
+	tc.mutex.Unlock()
+	if len(events) != 1 || events[0].Type != TargetHealthChanged {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	if tc.ids[refOf(nodeTarget("b:9100", ""))] != 1 {
+		t.Errorf("existing target should keep its id, ids %v", tc.ids)
+	}
+	assertIndexConsistent(t, tc)
+	if targets := tc.GetTargetsByJobAndHealth("node", "down"); len(targets) != 1 || targets[0].Labels["instance"] != "b:9100" {
+		t.Errorf("unexpected down targets: %+v", targets)
+	}
+
+	// 新增目标：追加到末尾
+	tc.mutex.Lock()
+	tc.updateUnsafe([]v1.ActiveTarget{
+		nodeTarget("a:9100", v1.HealthGood),
+		nodeTarget("b:9100", v1.HealthBad),
+		{ScrapePool: "gpu_exporter", Health: v1.HealthGood, Labels: model.LabelSet{"job": "gpu", "instance": "a:9400"}},
+	})
+	tc.mutex.Unlock()
+	assertIndexConsistent(t, tc)
+	if targets := tc.GetTargetsByJob("gpu"); len(targets) != 1 {
+		t.Errorf("expected new gpu target, got %+v", targets)
+	}
+
+	// 目标消失：全量重建
+	tc.mutex.Lock()
+	events = tc.updateUnsafe([]v1.ActiveTarget{
+		{ScrapePool: "gpu_exporter", Health: v1.HealthGood, Labels: model.LabelSet{"job": "gpu", "instance": "a:9400"}},
+		nodeTarget("b:9100", v1.HealthGood),
+	})
+	tc.mutex.Unlock()
+	if len(events) != 2 || events[0].Type != TargetRemoved || events[1].Type != TargetHealthChanged {
+		t.Errorf("unexpected events: %+v", events)
+	}
+	assertIndexConsistent(t, tc)
+	if len(tc.targets) != 2 || len(tc.GetTargetsByInstance("a:9100")) != 0 {
+		t.Errorf("removed target still cached: %+v", tc.targets)
+	}
+	if targets := tc.Select(labels.MustNewMatcher(labels.MatchEqual, "job", "node")); len(targets) != 1 {
+		t.Errorf("unexpected node targets: %+v", targets)
+	}
+}
+
+// benchTargets 生成 n 个目标，每个目标 labelCount 个标签
+func benchTargets(n, labelCount int) []v1.ActiveTarget {
+	targets := make([]v1.ActiveTarget, n)
+	for i := range targets {
+		ls := model.LabelSet{
+			"job":      model.LabelValue(fmt.Sprintf("job-%d", i%10)),
+			"instance": model.LabelValue(fmt.Sprintf("10.%d.%d.%d:9100", i/65536, i/256%256, i%256)),
+		}
+		for l := len(ls); l < labelCount; l++ {
+			ls[model.LabelName(fmt.Sprintf("label_%d", l))] = model.LabelValue(fmt.Sprintf("value-%d", i%(l*5)))
+		}
+		targets[i] = v1.ActiveTarget{
+			ScrapePool: fmt.Sprintf("pool-%d", i%10),
+			Health:     v1.HealthGood,
+			Labels:     ls,
+		}
+	}
+	return targets
+}
+
+// legacyTargetIndex 改造前按目标副本建立的索引，作为基准测试的对照
+type legacyTargetIndex struct {
+	ByJob         map[string][]v1.ActiveTarget
+	ByInstance    map[string][]v1.ActiveTarget
+	ByHealth      map[string][]v1.ActiveTarget
+	ByPool        map[string][]v1.ActiveTarget
+	ByCustomLabel map[string]map[string][]v1.ActiveTarget
+}
+
+func legacyBuildIndex(targets []v1.ActiveTarget) legacyTargetIndex {
+	idx := legacyTargetIndex{
+		ByJob:         make(map[string][]v1.ActiveTarget),
+		ByInstance:    make(map[string][]v1.ActiveTarget),
+		ByHealth:      make(map[string][]v1.ActiveTarget),
+		ByPool:        make(map[string][]v1.ActiveTarget),
+		ByCustomLabel: make(map[string]map[string][]v1.ActiveTarget),
+	}
+	for _, target := range targets {
+		job, instance := string(target.Labels["job"]), string(target.Labels["instance"])
+		idx.ByJob[job] = append(idx.ByJob[job], target)
+		idx.ByInstance[instance] = append(idx.ByInstance[instance], target)
+		idx.ByHealth[string(target.Health)] = append(idx.ByHealth[string(target.Health)], target)
+		idx.ByPool[target.ScrapePool] = append(idx.ByPool[target.ScrapePool], target)
+		for name, value := range target.Labels {
+			if _, exists := idx.ByCustomLabel[string(name)]; !exists {
+				idx.ByCustomLabel[string(name)] = make(map[string][]v1.ActiveTarget)
+			}
+			idx.ByCustomLabel[string(name)][string(value)] = append(idx.ByCustomLabel[string(name)][string(value)], target)
+		}
+	}
+	return idx
+}
+
+// 10k 目标、20 个标签：bytes/op 即索引占用的内存
+func BenchmarkBuildIndex(b *testing.B) {
+	targets := benchTargets(10000, 20)
+	b.Run("copies", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_ = legacyBuildIndex(targets)
+		}
+	})
+	b.Run("postings", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			tc := &IndexedTargetCache{targets: targets}
+			tc.buildIndex()
+		}
+	})
+}
+
+func BenchmarkRefresh(b *testing.B) {
+	targets := benchTargets(10000, 20)
+	flapped := append([]v1.ActiveTarget(nil), targets...)
+	for i := 0; i < len(flapped); i += 100 {
+		flapped[i].Health = v1.HealthBad
+	}
+
+	b.Run("full-rebuild", func(b *testing.B) {
+		tc := &IndexedTargetCache{targets: targets}
+		tc.buildIndex()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			tc.targets = flapped
+			tc.buildIndex()
+		}
+	})
+	b.Run("incremental-health", func(b *testing.B) {
+		tc := &IndexedTargetCache{targets: append([]v1.ActiveTarget(nil), targets...)}
+		tc.buildIndex()
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			if i%2 == 0 {
+				tc.updateUnsafe(flapped)
+			} else {
+				tc.updateUnsafe(targets)
+			}
+		}
+	})
+}
+
+func BenchmarkSelect(b *testing.B) {
+	tc := &IndexedTargetCache{targets: benchTargets(10000, 20)}
+	tc.buildIndex()
+	matchers := []*labels.Matcher{
+		labels.MustNewMatcher(labels.MatchEqual, "job", "job-3"),
+		labels.MustNewMatcher(labels.MatchEqual, "label_2", "value-3"),
+		labels.MustNewMatcher(labels.MatchRegexp, "instance", `10\.0\..*`),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = tc.Select(matchers...)
+	}
+}
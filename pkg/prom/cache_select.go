@@ -0,0 +1,73 @@
+package prom
+
+import (
+	"strings"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// Select 返回满足所有 matcher 的目标，支持 =、!=、=~、!~，不传 matcher 时返回全部目标
+//
+// 以 "__" 开头的标签名（如 __address__、__meta_kubernetes_namespace）匹配 discoveredLabels，
+// 其余匹配目标标签，与 Prometheus 重标记后丢弃 "__" 前缀标签的约定一致。
+// 标签不存在时按空值匹配，例如 foo!="bar" 会选中没有 foo 标签的目标。
+//
+// 目标标签的非空等值匹配使用 ByCustomLabel 的倒排列表求交集得到候选，其余 matcher 在候选上逐个过滤。
+//
+// 不检查缓存是否就绪与最大陈旧时间，需要检查时使用 TargetsFor("", matchers...)。
+func (tc *IndexedTargetCache) Select(matchers ...*labels.Matcher) []v1.ActiveTarget {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	return tc.selectUnsafe(nil, matchers)
+}
+
+// selectUnsafe 在 postings 的交集（为空时为全部目标）中选择满足 matchers 的目标
+func (tc *IndexedTargetCache) selectUnsafe(postings [][]int, matchers []*labels.Matcher) []v1.ActiveTarget {
+	var filters []*labels.Matcher
+	for _, m := range matchers {
+		if m.Type == labels.MatchEqual && m.Value != "" && !isDiscoveredLabel(m.Name) {
+			postings = append(postings, tc.index.ByCustomLabel[m.Name][m.Value])
+			continue
+		}
+		filters = append(filters, m)
+	}
+
+	var result []v1.ActiveTarget
+	if len(postings) > 0 {
+		for _, id := range intersectPostings(postings) {
+			if matchTarget(tc.targets[id], filters) {
+				result = append(result, tc.targets[id])
+			}
+		}
+		return result
+	}
+	for _, target := range tc.targets {
+		if matchTarget(target, filters) {
+			result = append(result, target)
+		}
+	}
+	return result
+}
+
+// isDiscoveredLabel 标签名是否应匹配 discoveredLabels
+func isDiscoveredLabel(name string) bool {
+	return strings.HasPrefix(name, model.ReservedLabelPrefix)
+}
+
+// matchTarget 目标是否满足所有 matcher
+func matchTarget(target v1.ActiveTarget, matchers []*labels.Matcher) bool {
+	for _, m := range matchers {
+		var value string
+		if isDiscoveredLabel(m.Name) {
+			value = target.DiscoveredLabels[m.Name]
+		} else {
+			value = string(target.Labels[model.LabelName(m.Name)])
+		}
+		if !m.Matches(value) {
+			return false
+		}
+	}
+	return true
+}
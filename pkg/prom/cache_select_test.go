@@ -0,0 +1,92 @@
+package prom
+
+import (
+	"sort"
+	"testing"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func newSelectTestCache(t *testing.T) *IndexedTargetCache {
+	t.Helper()
+	cache := NewStaticIndexedTargetCache([]v1.ActiveTarget{
+		{
+			ScrapePool: "node_exporter", Health: v1.HealthGood,
+			Labels:           model.LabelSet{"job": "node", "instance": "10.0.0.1:9100", "dc": "dc-1", "rack": "r1"},
+			DiscoveredLabels: map[string]string{"__address__": "10.0.0.1:9100", "__meta_consul_service": "node"},
+		},
+		{
+			ScrapePool: "node_exporter", Health: v1.HealthBad,
+			Labels:           model.LabelSet{"job": "node", "instance": "10.0.0.2:9100", "dc": "dc-1"},
+			DiscoveredLabels: map[string]string{"__address__": "10.0.0.2:9100", "__meta_consul_service": "node"},
+		},
+		{
+			ScrapePool: "node_exporter", Health: v1.HealthGood,
+			Labels:           model.LabelSet{"job": "node", "instance": "10.1.0.1:9100", "dc": "dc-2", "rack": "r1"},
+			DiscoveredLabels: map[string]string{"__address__": "10.1.0.1:9100"},
+		},
+		{
+			ScrapePool: "gpu_exporter", Health: v1.HealthGood,
+			Labels:           model.LabelSet{"job": "gpu", "instance": "10.0.0.1:9400", "dc": "dc-1", "rack": "r1"},
+			DiscoveredLabels: map[string]string{"__address__": "10.0.0.1:9400", "__meta_consul_service": "gpu"},
+		},
+	})
+	t.Cleanup(cache.Close)
+	return cache
+}
+
+func TestIndexedTargetCacheSelect(t *testing.T) {
+	cache := newSelectTestCache(t)
+	m := func(typ labels.MatchType, name, value string) *labels.Matcher {
+		return labels.MustNewMatcher(typ, name, value)
+	}
+
+	tests := []struct {
+		name     string
+		matchers []*labels.Matcher
+		want     []string
+	}{
+		{"all", nil, []string{"10.0.0.1:9100", "10.0.0.1:9400", "10.0.0.2:9100", "10.1.0.1:9100"}},
+		{"equal", []*labels.Matcher{m(labels.MatchEqual, "job", "node")},
+			[]string{"10.0.0.1:9100", "10.0.0.2:9100", "10.1.0.1:9100"}},
+		{"intersect", []*labels.Matcher{m(labels.MatchEqual, "dc", "dc-1"), m(labels.MatchEqual, "rack", "r1"), m(labels.MatchEqual, "job", "node")},
+			[]string{"10.0.0.1:9100"}},
+		{"empty intersection", []*labels.Matcher{m(labels.MatchEqual, "dc", "dc-2"), m(labels.MatchEqual, "job", "gpu")}, nil},
+		{"unknown value", []*labels.Matcher{m(labels.MatchEqual, "dc", "dc-9")}, nil},
+		{"not equal", []*labels.Matcher{m(labels.MatchEqual, "job", "node"), m(labels.MatchNotEqual, "dc", "dc-1")},
+			[]string{"10.1.0.1:9100"}},
+		{"not equal matches missing label", []*labels.Matcher{m(labels.MatchNotEqual, "rack", "r1")},
+			[]string{"10.0.0.2:9100"}},
+		{"equal empty matches missing label", []*labels.Matcher{m(labels.MatchEqual, "rack", "")},
+			[]string{"10.0.0.2:9100"}},
+		{"regexp", []*labels.Matcher{m(labels.MatchRegexp, "instance", `10\.0\..+`)},
+			[]string{"10.0.0.1:9100", "10.0.0.1:9400", "10.0.0.2:9100"}},
+		{"not regexp", []*labels.Matcher{m(labels.MatchNotRegexp, "instance", `.+:9100`)},
+			[]string{"10.0.0.1:9400"}},
+		{"discovered label", []*labels.Matcher{m(labels.MatchEqual, "__meta_consul_service", "node")},
+			[]string{"10.0.0.1:9100", "10.0.0.2:9100"}},
+		{"discovered label missing", []*labels.Matcher{m(labels.MatchEqual, "dc", "dc-2"), m(labels.MatchEqual, "__meta_consul_service", "")},
+			[]string{"10.1.0.1:9100"}},
+		{"discovered regexp", []*labels.Matcher{m(labels.MatchRegexp, "__address__", `.+:9400`)},
+			[]string{"10.0.0.1:9400"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, target := range cache.Select(tt.matchers...) {
+				got = append(got, string(target.Labels["instance"]))
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,160 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCacheNotReady 缓存尚未成功刷新过，没有可用数据
+	ErrCacheNotReady = errors.New("target cache not ready")
+	// ErrCacheStale 缓存数据超过最大陈旧时间
+	ErrCacheStale = errors.New("target cache stale")
+)
+
+// 刷新失败后重试间隔的默认值
+const (
+	defaultInitialBackoff = time.Second
+	defaultMaxBackoff     = 5 * time.Minute
+)
+
+// CacheOption 配置目标缓存
+type CacheOption func(*refreshState)
+
+// WithMaxStaleness 设置缓存数据的最大陈旧时间：距上次成功刷新超过该时间后，
+// 读取返回 ErrCacheStale。默认为 0，不限制
+func WithMaxStaleness(d time.Duration) CacheOption {
+	return func(s *refreshState) {
+		s.maxStaleness = d
+	}
+}
+
+// WithRefreshBackoff 设置刷新失败后的重试间隔：从 initial 开始每次失败翻倍，不超过 maxDelay，
+// 成功后恢复按 TTL 刷新。默认 1s 到 5m
+func WithRefreshBackoff(initial, maxDelay time.Duration) CacheOption {
+	return func(s *refreshState) {
+		s.initialBackoff = initial
+		s.maxBackoff = maxDelay
+	}
+}
+
+// refreshState 记录缓存的刷新结果，除构造与 WaitReady 外均由所属缓存的锁保护
+type refreshState struct {
+	lastError error
+	failures  int // 连续失败次数
+	ready     chan struct{}
+	readyOnce sync.Once
+
+	maxStaleness   time.Duration
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func newRefreshState(ttl time.Duration, opts []CacheOption) *refreshState {
+	s := &refreshState{
+		ready:          make(chan struct{}),
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.initialBackoff <= 0 || s.initialBackoff > ttl {
+		s.initialBackoff = ttl
+	}
+	if s.maxBackoff < s.initialBackoff {
+		s.maxBackoff = s.initialBackoff
+	}
+	return s
+}
+
+// recordUnsafe 记录一次刷新的结果
+func (s *refreshState) recordUnsafe(err error) {
+	s.lastError = err
+	if err != nil {
+		s.failures++
+		return
+	}
+	s.failures = 0
+	s.readyOnce.Do(func() { close(s.ready) })
+}
+
+// nextDelayUnsafe 下一次刷新的等待时间：成功时为 TTL，连续失败时指数退避
+func (s *refreshState) nextDelayUnsafe(ttl time.Duration) time.Duration {
+	if s.failures == 0 {
+		return ttl
+	}
+	delay := s.initialBackoff
+	for i := 1; i < s.failures && delay < s.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, s.maxBackoff)
+}
+
+// checkUnsafe 检查缓存数据是否可读，lastRefresh 为上次成功刷新的时间
+func (s *refreshState) checkUnsafe(lastRefresh time.Time) error {
+	if lastRefresh.IsZero() {
+		if s.lastError != nil {
+			return fmt.Errorf("%w: %w", ErrCacheNotReady, s.lastError)
+		}
+		return ErrCacheNotReady
+	}
+	if s.maxStaleness > 0 {
+		if age := time.Since(lastRefresh); age > s.maxStaleness {
+			err := fmt.Errorf("%w: last refresh %s ago", ErrCacheStale, age.Truncate(time.Second))
+			if s.lastError != nil {
+				err = fmt.Errorf("%w: %w", err, s.lastError)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// loop 后台刷新，两种缓存共用：成功后按 TTL 刷新，失败后指数退避，stop 关闭时退出。
+// refresh 在持有 mutex 写锁时调用
+func (s *refreshState) loop(stop <-chan struct{}, mutex *sync.RWMutex, ttl time.Duration, refresh func() error) {
+	mutex.RLock()
+	timer := time.NewTimer(s.nextDelayUnsafe(ttl))
+	mutex.RUnlock()
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			mutex.Lock()
+			err := refresh()
+			delay := s.nextDelayUnsafe(ttl)
+			mutex.Unlock()
+			if err != nil {
+				fmt.Printf("Background cache refresh failed, retry in %v: %v\n", delay, err)
+			}
+			timer.Reset(delay)
+		case <-stop:
+			return
+		}
+	}
+}
+
+// waitReady 等待首次成功刷新
+func (s *refreshState) waitReady(ctx context.Context) error {
+	select {
+	case <-s.ready:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isReady 是否已成功刷新过
+func (s *refreshState) isReady() bool {
+	select {
+	case <-s.ready:
+		return true
+	default:
+		return false
+	}
+}
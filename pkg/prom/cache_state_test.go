@@ -0,0 +1,163 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// toggleTransport 在 fail 为 true 时让所有请求失败，并记录请求次数
+type toggleTransport struct {
+	fail     atomic.Bool
+	requests atomic.Int64
+}
+
+func (t *toggleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests.Add(1)
+	if t.fail.Load() {
+		return nil, errors.New("connection refused")
+	}
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func newToggleClient(t *testing.T, fail bool) (*Client, *toggleTransport) {
+	t.Helper()
+	srv, _ := newTestServer(t)
+	rt := &toggleTransport{}
+	rt.fail.Store(fail)
+	client, err := NewClient(srv.URL, WithRoundTripper(rt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(client.Close)
+	return client, rt
+}
+
+func TestIndexedTargetCacheNotReady(t *testing.T) {
+	client, rt := newToggleClient(t, true)
+	cache := NewIndexedTargetCache(client, time.Hour, WithRefreshBackoff(10*time.Millisecond, 20*time.Millisecond))
+	defer cache.Close()
+
+	// 首次刷新失败：未就绪，错误可见，读取返回错误而不是空列表
+	if cache.Ready() || cache.LastError() == nil || !cache.LastRefresh().IsZero() {
+		t.Fatalf("unexpected state: ready %v, last error %v, last refresh %v", cache.Ready(), cache.LastError(), cache.LastRefresh())
+	}
+	if _, err := cache.TargetsByPool("node_exporter"); !errors.Is(err, ErrCacheNotReady) {
+		t.Errorf("err = %v, want ErrCacheNotReady", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if err := cache.WaitReady(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("WaitReady err = %v, want deadline exceeded", err)
+	}
+
+	// 恢复后后台按退避间隔重试，不需要等待 TTL
+	rt.fail.Store(false)
+	ctx, cancel = context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := cache.WaitReady(ctx); err != nil {
+		t.Fatalf("WaitReady: %v", err)
+	}
+	if !cache.Ready() || cache.LastRefresh().IsZero() {
+		t.Error("cache should be ready after recovery")
+	}
+	targets, err := cache.TargetsByPool("node_exporter")
+	if err != nil || len(targets) != 2 {
+		t.Errorf("TargetsByPool: %d targets, err %v", len(targets), err)
+	}
+}
+
+func TestIndexedTargetCacheStale(t *testing.T) {
+	client, rt := newToggleClient(t, false)
+	cache := NewIndexedTargetCache(client, time.Hour, WithMaxStaleness(50*time.Millisecond))
+	defer cache.Close()
+	if _, err := cache.TargetsByPool("node_exporter"); err != nil {
+		t.Fatal(err)
+	}
+
+	rt.fail.Store(true)
+	if err := cache.Refresh(context.Background()); err == nil {
+		t.Fatal("expected refresh error")
+	}
+	if cache.LastError() == nil || !cache.Ready() {
+		t.Errorf("failed refresh should keep the cache ready and record the error")
+	}
+	time.Sleep(60 * time.Millisecond)
+
+	if _, err := cache.TargetsByPool("node_exporter"); !errors.Is(err, ErrCacheStale) {
+		t.Errorf("err = %v, want ErrCacheStale", err)
+	}
+	// 不带错误的读取仍返回旧数据
+	if targets := cache.GetTargetsByPool("node_exporter"); len(targets) != 2 {
+		t.Errorf("expected stale data from GetTargetsByPool, got %d targets", len(targets))
+	}
+
+	rt.fail.Store(false)
+	if err := cache.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.TargetsByPool("node_exporter"); err != nil || cache.LastError() != nil {
+		t.Errorf("after refresh: err %v, last error %v", err, cache.LastError())
+	}
+}
+
+func TestTargetCacheStale(t *testing.T) {
+	client, rt := newToggleClient(t, false)
+	cache := NewTargetCache(client, time.Hour, WithMaxStaleness(50*time.Millisecond))
+	defer cache.Close()
+	if !cache.Ready() {
+		t.Fatal("cache should be ready")
+	}
+
+	rt.fail.Store(true)
+	time.Sleep(60 * time.Millisecond)
+	if _, err := cache.GetTargetsByType("all"); !errors.Is(err, ErrCacheStale) {
+		t.Errorf("err = %v, want ErrCacheStale", err)
+	}
+}
+
+func TestTargetCacheNotReady(t *testing.T) {
+	client, rt := newToggleClient(t, true)
+	cache := NewTargetCache(client, time.Hour, WithRefreshBackoff(time.Hour, time.Hour))
+	defer cache.Close()
+
+	// 未就绪时读取不同步刷新，重试交给后台退避
+	for range 5 {
+		if _, err := cache.GetTargetsByType("all"); !errors.Is(err, ErrCacheNotReady) || !strings.Contains(err.Error(), "connection refused") {
+			t.Fatalf("err = %v, want ErrCacheNotReady with the last error", err)
+		}
+	}
+	if n := rt.requests.Load(); n != 1 {
+		t.Errorf("%d requests, want only the initial refresh", n)
+	}
+}
+
+func TestRefreshBackoff(t *testing.T) {
+	s := newRefreshState(time.Minute, []CacheOption{WithRefreshBackoff(time.Second, 5*time.Second)})
+	if d := s.nextDelayUnsafe(time.Minute); d != time.Minute {
+		t.Errorf("delay without failures = %v, want TTL", d)
+	}
+
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, w := range want {
+		s.recordUnsafe(errors.New("boom"))
+		if d := s.nextDelayUnsafe(time.Minute); d != w {
+			t.Errorf("delay after %d failures = %v, want %v", i+1, d, w)
+		}
+	}
+
+	s.recordUnsafe(nil)
+	if d := s.nextDelayUnsafe(time.Minute); d != time.Minute {
+		t.Errorf("delay after success = %v, want TTL", d)
+	}
+
+	// 起点不超过 TTL，上限不小于起点
+	s = newRefreshState(10*time.Millisecond, []CacheOption{WithRefreshBackoff(time.Second, time.Millisecond)})
+	if s.initialBackoff != 10*time.Millisecond || s.maxBackoff != 10*time.Millisecond {
+		t.Errorf("unexpected backoff bounds: %v..%v", s.initialBackoff, s.maxBackoff)
+	}
+}
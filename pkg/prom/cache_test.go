@@ -3,6 +3,9 @@ package prom
 import (
 	"testing"
 	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
 )
 
 func TestIndexedTargetCache(t *testing.T) {
@@ -18,27 +21,40 @@ func TestIndexedTargetCache(t *testing.T) {
 	}
 
 	// 测试基本查询功能
-	targets := cache.GetTargetsByHealth("up")
-	t.Logf("Found %d online targets", len(targets))
-
-	targets = cache.GetTargetsByHealth("down")
-	t.Logf("Found %d offline targets", len(targets))
+	if targets := cache.GetTargetsByHealth("up"); len(targets) != 2 {
+		t.Errorf("expected 2 online targets, got %d", len(targets))
+	}
+	if targets := cache.GetTargetsByHealth("down"); len(targets) != 1 {
+		t.Errorf("expected 1 offline target, got %d", len(targets))
+	}
 
 	// 测试按 job 查询
-	targets = cache.GetTargetsByJob("prometheus")
-	t.Logf("Found %d prometheus targets", len(targets))
+	if targets := cache.GetTargetsByJob("node_exporter"); len(targets) != 2 {
+		t.Errorf("expected 2 node_exporter targets, got %d", len(targets))
+	}
 
 	// 测试按 pool 查询
-	targets = cache.GetTargetsByPool("prometheus")
-	t.Logf("Found %d targets in prometheus pool", len(targets))
+	if targets := cache.GetTargetsByPool("prometheus"); len(targets) != 1 {
+		t.Errorf("expected 1 target in prometheus pool, got %d", len(targets))
+	}
+
+	// 测试按 instance 与自定义标签查询
+	if targets := cache.GetTargetsByInstance("10.0.0.2:9100"); len(targets) != 1 || targets[0].Health != TargetHealthBad {
+		t.Errorf("unexpected targets for instance 10.0.0.2:9100: %+v", targets)
+	}
+	if targets := cache.GetTargetsByLabel("data_center_id", "dc-1"); len(targets) != 3 {
+		t.Errorf("expected 3 targets in dc-1, got %d", len(targets))
+	}
 
 	// 测试组合查询
-	targets = cache.GetTargetsByJobAndHealth("prometheus", "up")
-	t.Logf("Found %d online prometheus targets", len(targets))
+	if targets := cache.GetTargetsByJobAndHealth("node_exporter", "up"); len(targets) != 1 {
+		t.Errorf("expected 1 online node_exporter target, got %d", len(targets))
+	}
 
 	// 测试获取所有 targets
-	allTargets := cache.GetAllTargetsByPool()
-	t.Logf("Found %d target pools in cache", len(allTargets))
+	if allTargets := cache.GetAllTargetsByPool(); len(allTargets) != 3 {
+		t.Errorf("expected 3 target pools, got %d", len(allTargets))
+	}
 }
 
 func TestNormalTargetCache(t *testing.T) {
@@ -52,14 +68,16 @@ func TestNormalTargetCache(t *testing.T) {
 		t.Fatal("Failed to create target cache")
 	}
 
-	// 测试获取不同类型的数据
-	testCases := []string{"all", "online", "offline"}
-	for _, targetType := range testCases {
+	// 测试获取不同类型的数据：每种类型期望的 pool 数
+	testCases := map[string]int{"all": 3, "online": 2, "offline": 1}
+	for targetType, want := range testCases {
 		targets, err := cache.GetTargetsByType(targetType)
 		if err != nil {
-			t.Logf("Cache miss for %s targets (expected on first access): %v", targetType, err)
-		} else {
-			t.Logf("Found %d %s target pools", len(targets), targetType)
+			t.Errorf("GetTargetsByType(%s) failed: %v", targetType, err)
+			continue
+		}
+		if len(targets) != want {
+			t.Errorf("expected %d %s target pools, got %d", want, targetType, len(targets))
 		}
 	}
 }
@@ -102,7 +120,7 @@ func TestConcurrentAccess(t *testing.T) {
 }
 
 func TestCacheRefresh(t *testing.T) {
-	client := RequireTestClient(t)
+	srv, client := newTestServer(t)
 
 	// 创建短 TTL 的缓存用于测试
 	cache := NewIndexedTargetCache(client, 1*time.Second)
@@ -125,7 +143,27 @@ func TestCacheRefresh(t *testing.T) {
 		t.Error("Cache time should be set after refresh")
 	}
 
-	t.Logf("Cache last updated: %v", cacheTime)
+	// 目标变化后刷新，索引随之更新
+	srv.SetTargets(v1.ActiveTarget{
+		ScrapePool: "gpu_exporter",
+		Health:     v1.HealthGood,
+		Labels:     model.LabelSet{"job": "gpu_exporter", "instance": "10.0.0.3:9400"},
+	})
+	cache.mutex.Lock()
+	err = cache.refreshCacheUnsafe()
+	cache.mutex.Unlock()
+	if err != nil {
+		t.Fatalf("Cache refresh failed: %v", err)
+	}
+	if pools := cache.GetAllTargetsByPool(); len(pools) != 1 {
+		t.Errorf("expected 1 target pool after refresh, got %d", len(pools))
+	}
+	if targets := cache.GetTargetsByJob("node_exporter"); len(targets) != 0 {
+		t.Errorf("stale node_exporter targets after refresh: %+v", targets)
+	}
+	if targets := cache.GetTargetsByInstance("10.0.0.3:9400"); len(targets) != 1 {
+		t.Errorf("expected new target after refresh, got %+v", targets)
+	}
 }
 
 func TestCacheBackgroundRefresh(t *testing.T) {
@@ -163,3 +201,52 @@ func TestCacheClose(t *testing.T) {
 
 	t.Log("Cache closed successfully")
 }
+
+func TestStaticIndexedTargetCache(t *testing.T) {
+	cache := NewStaticIndexedTargetCache([]v1.ActiveTarget{
+		{ScrapePool: "gpu_exporter", Health: v1.HealthGood, Labels: model.LabelSet{"job": "gpu", "instance": "a:9400"}},
+		{ScrapePool: "gpu_exporter", Health: v1.HealthBad, Labels: model.LabelSet{"job": "gpu", "instance": "b:9400"}},
+		{ScrapePool: "node_exporter", Health: v1.HealthGood, Labels: model.LabelSet{"job": "node", "instance": "a:9100"}},
+	})
+	defer cache.Close()
+
+	if targets := cache.GetTargetsByPool("gpu_exporter"); len(targets) != 2 {
+		t.Errorf("expected 2 gpu_exporter targets, got %d", len(targets))
+	}
+	if targets := cache.GetTargetsByJobAndHealth("gpu", "down"); len(targets) != 1 || targets[0].Labels["instance"] != "b:9400" {
+		t.Errorf("unexpected offline gpu targets: %+v", targets)
+	}
+	if pools := cache.GetAllTargetsByPool(); len(pools) != 2 {
+		t.Errorf("expected 2 pools, got %d", len(pools))
+	}
+}
+
+func TestIndexedTargetCacheStats(t *testing.T) {
+	srv, client := newTestServer(t)
+	cache := NewIndexedTargetCache(client, time.Hour)
+	defer cache.Close()
+
+	stats := cache.Stats()
+	if stats.Refreshes != 1 || stats.RefreshFailures != 0 || stats.LastRefresh.IsZero() {
+		t.Errorf("unexpected stats after initial refresh: %+v", stats)
+	}
+	if stats.TargetsByPool["node_exporter"] != 2 || len(stats.TargetsByPool) != 3 {
+		t.Errorf("unexpected targets by pool: %v", stats.TargetsByPool)
+	}
+
+	// 刷新失败计入失败次数，保留上次成功的时间与数据
+	srv.Close()
+	cache.mutex.Lock()
+	err := cache.refreshCacheUnsafe()
+	cache.mutex.Unlock()
+	if err == nil {
+		t.Fatal("expected refresh error after server closed")
+	}
+	after := cache.Stats()
+	if after.Refreshes != 2 || after.RefreshFailures != 1 || !after.LastRefresh.Equal(stats.LastRefresh) {
+		t.Errorf("unexpected stats after failed refresh: %+v", after)
+	}
+	if after.TargetsByPool["node_exporter"] != 2 {
+		t.Errorf("targets lost after failed refresh: %v", after.TargetsByPool)
+	}
+}
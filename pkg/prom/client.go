@@ -2,6 +2,7 @@ package prom
 
 import (
 	"context"
+	"net/http"
 	"time"
 
 	"github.com/prometheus/client_golang/api"
@@ -15,6 +16,7 @@ type Client struct {
 	ctx          context.Context
 	cancel       context.CancelFunc
 	queryTimeout time.Duration // 查询超时时间
+	roundTripper http.RoundTripper
 }
 
 // Option configures the Client.
@@ -34,19 +36,19 @@ func WithContext(ctx context.Context) Option {
 	}
 }
 
+// WithRoundTripper sets the HTTP transport used to talk to Prometheus,
+// e.g. a Recorder to capture responses or a Replayer to serve recorded ones.
+func WithRoundTripper(rt http.RoundTripper) Option {
+	return func(c *Client) {
+		c.roundTripper = rt
+	}
+}
+
 const defaultTimeout = 30 * time.Second
 
 // NewClient creates a new Prometheus query client.
 func NewClient(addr string, opts ...Option) (*Client, error) {
-	client, err := api.NewClient(api.Config{
-		Address: addr,
-	})
-	if err != nil {
-		return nil, err
-	}
-
 	c := &Client{
-		api:          v1.NewAPI(client),
 		ctx:          context.Background(),
 		cancel:       func() {},      // 默认空函数，避免nil调用
 		queryTimeout: defaultTimeout, // 默认30秒超时
@@ -57,6 +59,16 @@ func NewClient(addr string, opts ...Option) (*Client, error) {
 		opt(c)
 	}
 
+	client, err := api.NewClient(api.Config{
+		Address:      addr,
+		RoundTripper: c.roundTripper,
+	})
+	if err != nil {
+		c.cancel()
+		return nil, err
+	}
+	c.api = v1.NewAPI(client)
+
 	return c, nil
 }
 
@@ -68,6 +80,7 @@ func (c *Client) WithTimeout(timeout time.Duration) *Client {
 		ctx:          c.ctx,
 		cancel:       c.cancel,
 		queryTimeout: timeout,
+		roundTripper: c.roundTripper,
 	}
 }
 
@@ -80,6 +93,7 @@ func (c *Client) WithContext(ctx context.Context) *Client {
 		ctx:          ctx,
 		cancel:       cancel,
 		queryTimeout: c.queryTimeout,
+		roundTripper: c.roundTripper,
 	}
 }
 
@@ -111,6 +125,21 @@ func (c *Client) Alerts() (v1.AlertsResult, error) {
 	return c.api.Alerts(ctx)
 }
 
+// LabelNames returns the label names of series matching the given selectors using the configured timeout.
+func (c *Client) LabelNames(matches []string, start, end time.Time) ([]string, v1.Warnings, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.queryTimeout)
+	defer cancel()
+	return c.api.LabelNames(ctx, matches, start, end)
+}
+
+// Metadata returns metric metadata using the configured timeout.
+// An empty metric returns metadata for all metrics; an empty limit means no limit.
+func (c *Client) Metadata(metric, limit string) (map[string][]v1.Metadata, error) {
+	ctx, cancel := context.WithTimeout(c.ctx, c.queryTimeout)
+	defer cancel()
+	return c.api.Metadata(ctx, metric, limit)
+}
+
 // AlertManagers retrieves the list of alert managers using the configured timeout.
 func (c *Client) AlertManagers() (v1.AlertManagersResult, error) {
 	ctx, cancel := context.WithTimeout(c.ctx, c.queryTimeout)
@@ -2,35 +2,28 @@ package prom
 
 import (
 	"testing"
-)
 
-var (
-	// 全局测试客户端
-	GlobalTestClient *Client
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
 )
 
-// TestMain 在所有测试开始前初始化全局客户端
-func TestMain(m *testing.M) {
-	var err error
-	GlobalTestClient, err = NewClient("http://10.111.201.1:9090")
-	if err != nil {
-		// 如果无法连接到 Prometheus，记录但不退出
-		GlobalTestClient = nil
-	}
-
-	// 运行所有测试
-	m.Run()
+// 测试数据中的目标：prometheus 1 个在线，node_exporter 1 在线 1 离线，gpu_exporter 1 个未知
+const testFixture = "testdata/prometheus.yaml"
 
-	// 清理资源
-	if GlobalTestClient != nil {
-		GlobalTestClient.Close()
-	}
+// RequireTestClient 启动加载了测试数据的假 Prometheus，返回连接它的客户端
+func RequireTestClient(t *testing.T) *Client {
+	t.Helper()
+	_, client := newTestServer(t)
+	return client
 }
 
-// RequireTestClient 检查测试客户端是否可用
-func RequireTestClient(t *testing.T) *Client {
-	if GlobalTestClient == nil {
-		t.Skip("Skipping test - cannot connect to Prometheus at http://10.111.201.1:9090")
+// newTestServer 同 RequireTestClient，同时返回假服务以便修改数据
+func newTestServer(t *testing.T) (*promtest.Server, *Client) {
+	t.Helper()
+	srv := promtest.NewServer(t, promtest.WithFixtureFile(testFixture))
+	client, err := NewClient(srv.URL)
+	if err != nil {
+		t.Fatalf("NewClient failed: %v", err)
 	}
-	return GlobalTestClient
+	t.Cleanup(client.Close)
+	return srv, client
 }
@@ -0,0 +1,104 @@
+package promtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixture 假服务的数据文件（YAML），示例：
+//
+//	series: |
+//	  load 1m
+//	    up{job="node_exporter", instance="10.0.0.1:9100"} 1x60
+//	queries:
+//	  - query: up{job="broken"}
+//	    error: "query timed out"
+//	targets:
+//	  - scrapePool: node_exporter
+//	    health: up
+//	    labels: { job: node_exporter, instance: "10.0.0.1:9100" }
+//	droppedTargets:
+//	  - discoveredLabels: { job: node_exporter, __address__: "10.0.0.9:9100" }
+//	alerts:
+//	  - state: firing
+//	    labels: { alertname: NodeDown }
+//
+// targets、droppedTargets 与 alerts 使用 Prometheus API 的 JSON 字段名。
+type Fixture struct {
+	Series  string             `yaml:"series"`
+	Queries []QueryResponse    `yaml:"queries"`
+	Targets []v1.ActiveTarget  `yaml:"-"`
+	Dropped []v1.DroppedTarget `yaml:"-"`
+	Alerts  []v1.Alert         `yaml:"-"`
+}
+
+// LoadFixture 读取并解析数据文件
+func LoadFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw struct {
+		Fixture `yaml:",inline"`
+		Targets any `yaml:"targets"`
+		Dropped any `yaml:"droppedTargets"`
+		Alerts  any `yaml:"alerts"`
+	}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: %w", path, err)
+	}
+	f := raw.Fixture
+	// v1 中的类型只有 JSON 标签，经 JSON 中转解码
+	if err := convertJSON(raw.Targets, &f.Targets); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: targets: %w", path, err)
+	}
+	if err := convertJSON(raw.Dropped, &f.Dropped); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: droppedTargets: %w", path, err)
+	}
+	if err := convertJSON(raw.Alerts, &f.Alerts); err != nil {
+		return nil, fmt.Errorf("parse fixture %s: alerts: %w", path, err)
+	}
+	return &f, nil
+}
+
+// WithFixture 使用数据文件中的序列、固定响应、目标和告警
+func WithFixture(f *Fixture) Option {
+	return func(s *Server) {
+		if f.Series != "" {
+			WithSeries(f.Series)(s)
+		}
+		for _, q := range f.Queries {
+			WithQueryResponse(q)(s)
+		}
+		WithTargets(f.Targets...)(s)
+		WithDroppedTargets(f.Dropped...)(s)
+		WithAlerts(f.Alerts...)(s)
+	}
+}
+
+// WithFixtureFile 读取数据文件，读取失败时 NewServer 使测试失败
+func WithFixtureFile(path string) Option {
+	return func(s *Server) {
+		f, err := LoadFixture(path)
+		if err != nil {
+			s.optErr = err
+			return
+		}
+		WithFixture(f)(s)
+	}
+}
+
+func convertJSON(in, out any) error {
+	if in == nil {
+		return nil
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
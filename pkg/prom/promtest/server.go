@@ -0,0 +1,394 @@
+// Package promtest 提供进程内的 Prometheus HTTP API 假服务，用于离线测试
+//
+// 假服务实现 /api/v1/query、/api/v1/query_range、/api/v1/targets、/api/v1/alerts，
+// 可直接配合 prom.NewClient(server.URL) 使用：
+//
+//	srv := promtest.NewServer(t, promtest.WithFixtureFile("testdata/prometheus.yaml"))
+//	client, _ := prom.NewClient(srv.URL)
+//
+// 查询结果有两种来源：
+//   - 固定响应：按查询语句（忽略格式差异）匹配，直接返回配置的结果或错误
+//   - 序列脚本：使用 Prometheus promqltest 的 load 语法声明序列，由真实的 PromQL 引擎求值
+//
+// 序列脚本中的时间从 0 开始，服务会把脚本中最后一个样本的时间对齐到 Now，
+// 因此以当前时间查询即可读到脚本末尾的数据，查询过去一段时间即对应脚本中更早的样本。
+package promtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/promql"
+	"github.com/prometheus/prometheus/promql/parser"
+	"github.com/prometheus/prometheus/promql/promqltest"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Server 进程内的 Prometheus 假服务
+type Server struct {
+	*httptest.Server
+
+	// Now 序列脚本末尾对应的真实时间
+	Now time.Time
+
+	mu      sync.Mutex
+	script  []string
+	canned  map[string]*QueryResponse
+	targets []v1.ActiveTarget
+	dropped []v1.DroppedTarget
+	alerts  []v1.Alert
+	queries []string
+
+	storage storage.Storage
+	engine  *promql.Engine
+	dataEnd int64 // 序列脚本中最后一个样本的时间（毫秒）
+	optErr  error
+}
+
+// QueryResponse 查询的固定响应
+type QueryResponse struct {
+	// Query 匹配的查询语句，按解析后的表达式比较，忽略空白与格式差异
+	Query string `yaml:"query" json:"query"`
+	// ResultType vector / matrix / scalar / string
+	ResultType string `yaml:"result_type" json:"result_type"`
+	// Result Prometheus API 格式的结果
+	Result any `yaml:"result" json:"result"`
+	// Error 非空时返回 bad_data 错误
+	Error string `yaml:"error" json:"error"`
+}
+
+// Option 配置 Server
+type Option func(*Server)
+
+// WithSeries 追加 promqltest 格式的序列脚本，如：
+//
+//	load 1m
+//	  up{job="node_exporter", instance="10.0.0.1:9100"} 1x60
+func WithSeries(script string) Option {
+	return func(s *Server) {
+		s.script = append(s.script, script)
+	}
+}
+
+// WithQueryResponse 为查询配置固定响应，优先于序列脚本求值
+func WithQueryResponse(resp QueryResponse) Option {
+	return func(s *Server) {
+		s.canned[normalizeQuery(resp.Query)] = &resp
+	}
+}
+
+// WithTargets 追加 /api/v1/targets 返回的活跃目标
+func WithTargets(targets ...v1.ActiveTarget) Option {
+	return func(s *Server) {
+		s.targets = append(s.targets, targets...)
+	}
+}
+
+// WithDroppedTargets 追加 /api/v1/targets 返回的被丢弃目标
+func WithDroppedTargets(targets ...v1.DroppedTarget) Option {
+	return func(s *Server) {
+		s.dropped = append(s.dropped, targets...)
+	}
+}
+
+// WithAlerts 追加 /api/v1/alerts 返回的告警
+func WithAlerts(alerts ...v1.Alert) Option {
+	return func(s *Server) {
+		s.alerts = append(s.alerts, alerts...)
+	}
+}
+
+// WithNow 设置序列脚本末尾对应的真实时间，默认为创建服务的时间
+func WithNow(now time.Time) Option {
+	return func(s *Server) {
+		s.Now = now
+	}
+}
+
+// NewServer 启动假服务，测试结束时自动关闭
+func NewServer(t testing.TB, opts ...Option) *Server {
+	t.Helper()
+	s := &Server{
+		Now:    time.Now(),
+		canned: make(map[string]*QueryResponse),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	if s.optErr != nil {
+		t.Fatalf("promtest: %v", s.optErr)
+	}
+
+	if len(s.script) > 0 {
+		st := promqltest.LoadedStorage(t, strings.Join(s.script, "\n"))
+		s.storage = st
+		s.dataEnd = st.DB.Head().MaxTime()
+		if s.dataEnd == math.MinInt64 {
+			s.dataEnd = 0
+		}
+		s.engine = promqltest.NewTestEngine(t, false, 0, promqltest.DefaultMaxSamplesPerQuery)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query", s.handleQuery)
+	mux.HandleFunc("/api/v1/query_range", s.handleQueryRange)
+	mux.HandleFunc("/api/v1/targets", s.handleTargets)
+	mux.HandleFunc("/api/v1/alerts", s.handleAlerts)
+	s.Server = httptest.NewServer(mux)
+
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Close 关闭服务并释放序列存储
+func (s *Server) Close() {
+	s.Server.Close()
+	if s.storage != nil {
+		s.storage.Close()
+		s.storage = nil
+	}
+}
+
+// Queries 返回服务收到的查询语句（按请求顺序）
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// SetTargets 替换 /api/v1/targets 返回的活跃目标，用于模拟目标变化
+func (s *Server) SetTargets(targets ...v1.ActiveTarget) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.targets = append([]v1.ActiveTarget(nil), targets...)
+}
+
+func (s *Server) handleQuery(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+	query := r.Form.Get("query")
+	ts := s.Now
+	if v := r.Form.Get("time"); v != "" {
+		t, err := parseTime(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid parameter \"time\": %v", err))
+			return
+		}
+		ts = t
+	}
+
+	s.record(query)
+	if resp, ok := s.cannedResponse(query); ok {
+		writeCanned(w, resp)
+		return
+	}
+	s.eval(w, r.Context(), query, func(ctx context.Context) (promql.Query, error) {
+		return s.engine.NewInstantQuery(ctx, s.storage, nil, query, s.toScript(ts))
+	})
+}
+
+func (s *Server) handleQueryRange(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+	query := r.Form.Get("query")
+	start, err := parseTime(r.Form.Get("start"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid parameter \"start\": %v", err))
+		return
+	}
+	end, err := parseTime(r.Form.Get("end"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid parameter \"end\": %v", err))
+		return
+	}
+	step, err := parseDuration(r.Form.Get("step"))
+	if err != nil || step <= 0 {
+		writeError(w, http.StatusBadRequest, "bad_data", fmt.Sprintf("invalid parameter \"step\": %q", r.Form.Get("step")))
+		return
+	}
+
+	s.record(query)
+	if resp, ok := s.cannedResponse(query); ok {
+		writeCanned(w, resp)
+		return
+	}
+	s.eval(w, r.Context(), query, func(ctx context.Context) (promql.Query, error) {
+		return s.engine.NewRangeQuery(ctx, s.storage, nil, query, s.toScript(start), s.toScript(end), step)
+	})
+}
+
+func (s *Server) handleTargets(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	targets := append([]v1.ActiveTarget{}, s.targets...)
+	dropped := append([]v1.DroppedTarget{}, s.dropped...)
+	s.mu.Unlock()
+	writeData(w, v1.TargetsResult{Active: targets, Dropped: dropped})
+}
+
+func (s *Server) handleAlerts(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	alerts := append([]v1.Alert{}, s.alerts...)
+	s.mu.Unlock()
+	writeData(w, v1.AlertsResult{Alerts: alerts})
+}
+
+func (s *Server) record(query string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queries = append(s.queries, query)
+}
+
+func (s *Server) cannedResponse(query string) (*QueryResponse, bool) {
+	resp, ok := s.canned[normalizeQuery(query)]
+	return resp, ok
+}
+
+// eval 使用 PromQL 引擎求值；未配置序列脚本时返回空结果
+func (s *Server) eval(w http.ResponseWriter, ctx context.Context, query string, newQuery func(context.Context) (promql.Query, error)) {
+	if _, err := parser.ParseExpr(query); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+	if s.engine == nil {
+		writeData(w, map[string]any{"resultType": model.ValVector.String(), "result": model.Vector{}})
+		return
+	}
+
+	q, err := newQuery(ctx)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad_data", err.Error())
+		return
+	}
+	defer q.Close()
+	res := q.Exec(ctx)
+	if res.Err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "execution", res.Err.Error())
+		return
+	}
+	value := s.toModel(res.Value)
+	writeData(w, map[string]any{"resultType": value.Type().String(), "result": value})
+}
+
+// toScript 将真实时间换算为序列脚本中的时间
+func (s *Server) toScript(t time.Time) time.Time {
+	return time.UnixMilli(s.dataEnd).Add(t.Sub(s.Now))
+}
+
+// fromScript 将序列脚本中的时间（毫秒）换算为真实时间
+func (s *Server) fromScript(ms int64) model.Time {
+	return model.TimeFromUnixNano(s.Now.Add(time.Duration(ms-s.dataEnd) * time.Millisecond).UnixNano())
+}
+
+// toModel 将引擎结果转换为 API 返回的 model.Value
+func (s *Server) toModel(v parser.Value) model.Value {
+	switch v := v.(type) {
+	case promql.Vector:
+		vec := make(model.Vector, 0, len(v))
+		for _, sample := range v {
+			if sample.H != nil {
+				continue
+			}
+			vec = append(vec, &model.Sample{
+				Metric:    toMetric(sample.Metric),
+				Value:     model.SampleValue(sample.F),
+				Timestamp: s.fromScript(sample.T),
+			})
+		}
+		return vec
+	case promql.Matrix:
+		matrix := make(model.Matrix, 0, len(v))
+		for _, series := range v {
+			stream := &model.SampleStream{Metric: toMetric(series.Metric)}
+			for _, p := range series.Floats {
+				stream.Values = append(stream.Values, model.SamplePair{Timestamp: s.fromScript(p.T), Value: model.SampleValue(p.F)})
+			}
+			matrix = append(matrix, stream)
+		}
+		return matrix
+	case promql.Scalar:
+		return &model.Scalar{Value: model.SampleValue(v.V), Timestamp: s.fromScript(v.T)}
+	case promql.String:
+		return &model.String{Value: v.V, Timestamp: s.fromScript(v.T)}
+	}
+	return model.Vector{}
+}
+
+func toMetric(ls labels.Labels) model.Metric {
+	m := make(model.Metric, ls.Len())
+	ls.Range(func(l labels.Label) {
+		m[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	})
+	return m
+}
+
+// normalizeQuery 解析成功时使用表达式的规范形式，使格式不同的同一查询能够匹配
+func normalizeQuery(query string) string {
+	if expr, err := parser.ParseExpr(query); err == nil {
+		return expr.String()
+	}
+	return strings.TrimSpace(query)
+}
+
+// parseTime 解析 unix 秒（可带小数）或 RFC3339 时间
+func parseTime(s string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, frac := math.Modf(f)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e9))), nil
+	}
+	return time.Parse(time.RFC3339Nano, s)
+}
+
+// parseDuration 解析秒数（可带小数）或 Prometheus 时长
+func parseDuration(s string) (time.Duration, error) {
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return time.Duration(f * float64(time.Second)), nil
+	}
+	d, err := model.ParseDuration(s)
+	return time.Duration(d), err
+}
+
+func writeCanned(w http.ResponseWriter, resp *QueryResponse) {
+	if resp.Error != "" {
+		writeError(w, http.StatusBadRequest, "bad_data", resp.Error)
+		return
+	}
+	resultType := resp.ResultType
+	if resultType == "" {
+		resultType = model.ValVector.String()
+	}
+	result := resp.Result
+	if result == nil {
+		result = []any{}
+	}
+	writeData(w, map[string]any{"resultType": resultType, "result": result})
+}
+
+func writeData(w http.ResponseWriter, data any) {
+	writeJSON(w, http.StatusOK, map[string]any{"status": "success", "data": data})
+}
+
+func writeError(w http.ResponseWriter, code int, errType, msg string) {
+	writeJSON(w, code, map[string]any{"status": "error", "errorType": errType, "error": msg})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,177 @@
+package promtest_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+
+	"github.com/kekexiaoai/inspection/pkg/prom"
+	"github.com/kekexiaoai/inspection/pkg/prom/promtest"
+)
+
+const testSeries = `
+load 1m
+  gpu_util{instance="a"} 0+1x60
+  gpu_util{instance="b"} 50x60
+`
+
+func newClient(t *testing.T, srv *promtest.Server) *prom.Client {
+	t.Helper()
+	client, err := prom.NewClient(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestServer_Query(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	srv := promtest.NewServer(t, promtest.WithSeries(testSeries), promtest.WithNow(now))
+	client := newClient(t, srv)
+
+	// Now 对齐脚本末尾（60m），10 分钟前对应脚本中的 50m
+	value, _, err := client.Query(`gpu_util{instance="a"}`, now.Add(-10*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec := value.(model.Vector)
+	if len(vec) != 1 || vec[0].Value != 50 || !vec[0].Timestamp.Time().Equal(now.Add(-10*time.Minute)) {
+		t.Errorf("unexpected vector: %v", vec)
+	}
+
+	value, _, err = client.Query(`max(max_over_time(gpu_util[1h]))`, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vec := value.(model.Vector); len(vec) != 1 || vec[0].Value != 60 {
+		t.Errorf("unexpected max: %v", vec)
+	}
+
+	value, _, err = client.Query(`scalar(gpu_util{instance="b"})`, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s, ok := value.(*model.Scalar); !ok || s.Value != 50 {
+		t.Errorf("unexpected scalar: %v", value)
+	}
+
+	if got := srv.Queries(); len(got) != 3 || got[0] != `gpu_util{instance="a"}` {
+		t.Errorf("unexpected recorded queries: %v", got)
+	}
+}
+
+func TestServer_QueryRange(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	srv := promtest.NewServer(t, promtest.WithSeries(testSeries), promtest.WithNow(now))
+	client := newClient(t, srv)
+
+	value, _, err := client.QueryRange(`gpu_util{instance="a"}`, prom.NewRange(now.Add(-30*time.Minute), now, 15*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	matrix := value.(model.Matrix)
+	if len(matrix) != 1 || len(matrix[0].Values) != 3 {
+		t.Fatalf("unexpected matrix: %v", matrix)
+	}
+	for i, want := range []float64{30, 45, 60} {
+		p := matrix[0].Values[i]
+		if float64(p.Value) != want || !p.Timestamp.Time().Equal(now.Add(time.Duration(i-2)*15*time.Minute)) {
+			t.Errorf("point %d = %v, want %v", i, p, want)
+		}
+	}
+}
+
+func TestServer_QueryResponse(t *testing.T) {
+	srv := promtest.NewServer(t,
+		promtest.WithQueryResponse(promtest.QueryResponse{
+			Query: `sum by (job) (up)`,
+			Result: []any{
+				map[string]any{"metric": map[string]string{"job": "x"}, "value": []any{1767322800, "3"}},
+			},
+		}),
+		promtest.WithQueryResponse(promtest.QueryResponse{Query: `up{job="slow"}`, Error: "query timed out"}),
+	)
+	client := newClient(t, srv)
+
+	// 格式不同的同一查询也能匹配固定响应
+	value, _, err := client.Query("sum  by(job)(\n  up\n)", time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vec := value.(model.Vector); len(vec) != 1 || vec[0].Metric["job"] != "x" || vec[0].Value != 3 {
+		t.Errorf("unexpected canned vector: %v", vec)
+	}
+
+	if _, _, err := client.Query(`up{job="slow"}`, time.Now()); err == nil || !strings.Contains(err.Error(), "query timed out") {
+		t.Errorf("expected canned error, got %v", err)
+	}
+
+	// 未配置序列且未匹配固定响应时返回空结果
+	value, _, err = client.Query(`up`, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vec := value.(model.Vector); len(vec) != 0 {
+		t.Errorf("expected empty vector, got %v", vec)
+	}
+}
+
+func TestLoadFixture(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "prometheus.yaml")
+	data := `
+series: |
+  load 1m
+    up{instance="a"} 1x5
+queries:
+  - query: up{instance="b"}
+    result_type: vector
+    result: []
+targets:
+  - scrapePool: node_exporter
+    health: down
+    lastError: timeout
+    labels: { instance: a, job: node }
+droppedTargets:
+  - discoveredLabels: { job: node, __address__: "b:9100" }
+alerts:
+  - state: firing
+    labels: { alertname: Down }
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := promtest.NewServer(t, promtest.WithFixtureFile(path))
+	client := newClient(t, srv)
+
+	targets, err := client.Targets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets.Active) != 1 || targets.Active[0].LastError != "timeout" || targets.Active[0].Labels["job"] != "node" {
+		t.Errorf("unexpected targets: %+v", targets.Active)
+	}
+	if len(targets.Dropped) != 1 || targets.Dropped[0].DiscoveredLabels["__address__"] != "b:9100" {
+		t.Errorf("unexpected dropped targets: %+v", targets.Dropped)
+	}
+
+	alerts, err := client.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts.Alerts) != 1 || alerts.Alerts[0].State != "firing" {
+		t.Errorf("unexpected alerts: %+v", alerts.Alerts)
+	}
+
+	value, _, err := client.Query(`up`, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if vec := value.(model.Vector); len(vec) != 1 || vec[0].Value != 1 {
+		t.Errorf("unexpected vector: %v", vec)
+	}
+}
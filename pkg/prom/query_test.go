@@ -0,0 +1,70 @@
+package prom
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestExecuteQuery(t *testing.T) {
+	client := RequireTestClient(t)
+
+	values := make(map[string]float64)
+	err := ExecuteQuery(client, `up{job="node_exporter"}`, time.Now(), func(data any) error {
+		sample := data.(*model.Sample)
+		values[string(sample.Metric["instance"])] = float64(sample.Value)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values["10.0.0.1:9100"] != 1 || values["10.0.0.2:9100"] != 0 || len(values) != 2 {
+		t.Errorf("unexpected values: %v", values)
+	}
+
+	var empty string
+	err = ExecuteQuery(client, `up{job="missing"}`, time.Now(), DefaultVectorHandler, func(q string) { empty = q })
+	if err != nil || empty != `up{job="missing"}` {
+		t.Errorf("onEmpty not called: err=%v, query=%q", err, empty)
+	}
+
+	err = ExecuteQuery(client, `up{job=`, time.Now(), DefaultVectorHandler)
+	if err == nil || !strings.Contains(err.Error(), "bad_data") {
+		t.Errorf("expected bad_data error, got %v", err)
+	}
+}
+
+func TestExecuteQueryRange(t *testing.T) {
+	client := RequireTestClient(t)
+
+	now := time.Now()
+	var streams []*model.SampleStream
+	err := ExecuteQueryRange(client, `up{instance="10.0.0.2:9100"}`, now.Add(-time.Hour), now, 10*time.Minute, func(data any) error {
+		streams = append(streams, data.(*model.SampleStream))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 测试数据中该实例前 30 分钟在线，之后离线
+	if len(streams) != 1 || len(streams[0].Values) != 7 {
+		t.Fatalf("unexpected streams: %v", streams)
+	}
+	if first, last := streams[0].Values[0], streams[0].Values[6]; first.Value != 1 || last.Value != 0 {
+		t.Errorf("unexpected values: first=%v last=%v", first, last)
+	}
+}
+
+func TestAlerts(t *testing.T) {
+	client := RequireTestClient(t)
+
+	alerts, err := client.Alerts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts.Alerts) != 1 || alerts.Alerts[0].Labels["alertname"] != "InstanceDown" {
+		t.Errorf("unexpected alerts: %+v", alerts)
+	}
+}
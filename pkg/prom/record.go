@@ -0,0 +1,176 @@
+package prom
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/api"
+)
+
+// Exchange 一次录制的 Prometheus API 请求与响应
+type Exchange struct {
+	Path       string     `json:"path"`   // 如 /api/v1/query
+	Params     url.Values `json:"params"` // URL 参数与表单参数合并后的结果
+	StatusCode int        `json:"status_code"`
+	// Body 原始响应体；Prometheus API 总是返回 JSON，非 JSON 响应按 JSON 字符串保存
+	Body json.RawMessage `json:"body"`
+}
+
+// key 请求的匹配键：API 路径与排序后的参数，不区分 GET / POST，
+// 忽略 Prometheus 地址中的路径前缀（如 /prometheus/api/v1/query）
+func (e *Exchange) key() string {
+	path := e.Path
+	if i := strings.Index(path, "/api/"); i > 0 {
+		path = path[i:]
+	}
+	return path + "?" + e.Params.Encode()
+}
+
+// Recorder 录制经过的 Prometheus API 请求与响应的 http.RoundTripper，
+// 通过 WithRoundTripper 配置到 Client
+type Recorder struct {
+	next      http.RoundTripper
+	mu        sync.Mutex
+	exchanges []Exchange
+}
+
+// NewRecorder 创建 Recorder，next 为空时使用 api.DefaultRoundTripper
+func NewRecorder(next http.RoundTripper) *Recorder {
+	if next == nil {
+		next = api.DefaultRoundTripper
+	}
+	return &Recorder{next: next}
+}
+
+// RoundTrip 转发请求并录制成功读取的响应
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	raw := json.RawMessage(body)
+	if !json.Valid(body) {
+		raw, _ = json.Marshal(string(body))
+	}
+	r.mu.Lock()
+	r.exchanges = append(r.exchanges, Exchange{
+		Path:       req.URL.Path,
+		Params:     params,
+		StatusCode: resp.StatusCode,
+		Body:       raw,
+	})
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Exchanges 返回已录制的请求与响应（按请求顺序）
+func (r *Recorder) Exchanges() []Exchange {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Exchange(nil), r.exchanges...)
+}
+
+// Replayer 按录制结果响应请求的 http.RoundTripper，不访问网络
+//
+// 请求按路径与参数匹配；同一请求录制了多次时按顺序返回，用尽后重复返回最后一次。
+// 没有匹配的录制时返回 ErrNotRecorded。
+type Replayer struct {
+	mu        sync.Mutex
+	exchanges map[string][]Exchange
+	served    map[string]int
+}
+
+// ErrNotRecorded 回放时请求没有对应的录制
+var ErrNotRecorded = errors.New("request not recorded")
+
+// NewReplayer 使用录制结果创建 Replayer
+func NewReplayer(exchanges []Exchange) *Replayer {
+	r := &Replayer{
+		exchanges: make(map[string][]Exchange),
+		served:    make(map[string]int),
+	}
+	for _, e := range exchanges {
+		r.exchanges[e.key()] = append(r.exchanges[e.key()], e)
+	}
+	return r
+}
+
+// RoundTrip 返回与请求匹配的录制响应
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	params, err := requestParams(req)
+	if err != nil {
+		return nil, err
+	}
+	key := (&Exchange{Path: req.URL.Path, Params: params}).key()
+
+	r.mu.Lock()
+	recorded := r.exchanges[key]
+	i := r.served[key]
+	if i < len(recorded) {
+		r.served[key] = i + 1
+	}
+	r.mu.Unlock()
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("replay %s %s: %w", req.URL.Path, params.Encode(), ErrNotRecorded)
+	}
+	e := recorded[min(i, len(recorded)-1)]
+
+	body := []byte(e.Body)
+	var text string
+	if len(body) > 0 && body[0] == '"' && json.Unmarshal(body, &text) == nil {
+		body = []byte(text)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// requestParams 合并 URL 参数与表单参数，读取后恢复请求体
+func requestParams(req *http.Request) (url.Values, error) {
+	params := req.URL.Query()
+	if req.Body == nil || req.Body == http.NoBody {
+		return params, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	if strings.HasPrefix(req.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range form {
+			params[k] = append(params[k], vs...)
+		}
+	}
+	return params, nil
+}
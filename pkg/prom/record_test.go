@@ -0,0 +1,57 @@
+package prom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+)
+
+func TestRecorderReplayer(t *testing.T) {
+	srv, _ := newTestServer(t)
+	rec := NewRecorder(nil)
+	client, err := NewClient(srv.URL, WithRoundTripper(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ts := time.Now()
+	want, _, err := client.Query(`up{job="node_exporter"}`, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Targets(); err != nil {
+		t.Fatal(err)
+	}
+	exchanges := rec.Exchanges()
+	if len(exchanges) != 2 || exchanges[0].Path != "/api/v1/query" || exchanges[0].Params.Get("query") != `up{job="node_exporter"}` {
+		t.Fatalf("unexpected exchanges: %+v", exchanges)
+	}
+
+	// 回放不访问服务，地址中的路径前缀不影响匹配
+	srv.Close()
+	replay, err := NewClient("http://replay.invalid/prometheus", WithRoundTripper(NewReplayer(exchanges)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer replay.Close()
+
+	got, _, err := replay.Query(`up{job="node_exporter"}`, ts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.String() != want.String() || len(got.(model.Vector)) != 2 {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+	targets, err := replay.Targets()
+	if err != nil || len(targets.Active) != 4 {
+		t.Errorf("replayed targets: %d, err %v", len(targets.Active), err)
+	}
+
+	// 时间不同即为不同的请求
+	if _, _, err := replay.Query(`up{job="node_exporter"}`, ts.Add(time.Second)); !errors.Is(err, ErrNotRecorded) {
+		t.Errorf("err = %v, want ErrNotRecorded", err)
+	}
+}
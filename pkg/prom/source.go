@@ -0,0 +1,120 @@
+package prom
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+// TargetSource 提供应被巡检的目标列表，用于判断查询结果中缺失的目标
+//
+// 实现包括 IndexedTargetCache、TargetCache、StaticTargets 以及基于元数据登记（CMDB 等）的
+// NewRegistryTargetCache，调用方只依赖该接口，便于注入其它来源。
+type TargetSource interface {
+	// TargetsFor 返回 pool 中满足所有 matcher 的目标，pool 为空时不限 scrapePool；
+	// matcher 的语义见 IndexedTargetCache.Select。来源不可用时返回错误
+	TargetsFor(pool string, matchers ...*labels.Matcher) ([]v1.ActiveTarget, error)
+	// Close 释放来源占用的资源，如停止后台刷新
+	Close()
+}
+
+var (
+	_ TargetSource = (*IndexedTargetCache)(nil)
+	_ TargetSource = (*TargetCache)(nil)
+	_ TargetSource = StaticTargets(nil)
+)
+
+// TargetsFor 实现 TargetSource，缓存未就绪或超过最大陈旧时间时返回错误
+func (tc *IndexedTargetCache) TargetsFor(pool string, matchers ...*labels.Matcher) ([]v1.ActiveTarget, error) {
+	tc.mutex.RLock()
+	defer tc.mutex.RUnlock()
+	if err := tc.state.checkUnsafe(tc.cacheTime); err != nil {
+		return nil, err
+	}
+	var postings [][]int
+	if pool != "" {
+		postings = [][]int{tc.index.ByPool[pool]}
+	}
+	return tc.selectUnsafe(postings, matchers), nil
+}
+
+// TargetsFor 实现 TargetSource，在全部目标中过滤
+func (tc *TargetCache) TargetsFor(pool string, matchers ...*labels.Matcher) ([]v1.ActiveTarget, error) {
+	pools, err := tc.GetTargetsByType("all")
+	if err != nil {
+		return nil, err
+	}
+	var result []v1.ActiveTarget
+	for _, p := range pools {
+		if pool != "" && p.ScrapePool != pool {
+			continue
+		}
+		for _, target := range p.Targets {
+			if matchTarget(target, matchers) {
+				result = append(result, target)
+			}
+		}
+	}
+	return result, nil
+}
+
+// StaticTargets 固定的目标列表，不访问任何服务；目标较多且需要频繁查询时使用 NewStaticIndexedTargetCache
+type StaticTargets []v1.ActiveTarget
+
+// TargetsFor 实现 TargetSource
+func (s StaticTargets) TargetsFor(pool string, matchers ...*labels.Matcher) ([]v1.ActiveTarget, error) {
+	var result []v1.ActiveTarget
+	for _, target := range s {
+		if (pool == "" || target.ScrapePool == pool) && matchTarget(target, matchers) {
+			result = append(result, target)
+		}
+	}
+	return result, nil
+}
+
+// Close 实现 TargetSource，无需释放资源
+func (s StaticTargets) Close() {}
+
+// RegistryEntity 元数据登记中应被监控的实体
+type RegistryEntity struct {
+	Pool   string            // 对应的 scrapePool，与指标的 exporter 一致
+	Labels map[string]string // 识别目标的标签，至少包含 instance 等用于匹配查询结果的标签
+}
+
+// MetadataRegistry 元数据登记服务（如 CMDB），按查询条件返回应被监控的实体，
+// 查询条件对应模板 target_registry.query
+type MetadataRegistry interface {
+	Entities(ctx context.Context, query map[string]string) ([]RegistryEntity, error)
+}
+
+// MetadataRegistryFunc 将函数适配为 MetadataRegistry
+type MetadataRegistryFunc func(ctx context.Context, query map[string]string) ([]RegistryEntity, error)
+
+// Entities 实现 MetadataRegistry
+func (f MetadataRegistryFunc) Entities(ctx context.Context, query map[string]string) ([]RegistryEntity, error) {
+	return f(ctx, query)
+}
+
+// NewRegistryTargetCache 创建以元数据登记为来源的带索引缓存：定期按 query 拉取实体，
+// 以登记的实体而不是 Prometheus 实际抓取的目标判断缺失；实体的健康状态为 unknown。
+// 刷新、就绪与陈旧的处理与 NewIndexedTargetCache 相同
+func NewRegistryTargetCache(registry MetadataRegistry, query map[string]string, ttl time.Duration, opts ...CacheOption) *IndexedTargetCache {
+	return newIndexedTargetCache(context.Background(), func(ctx context.Context) ([]v1.ActiveTarget, error) {
+		entities, err := registry.Entities(ctx, query)
+		if err != nil {
+			return nil, err
+		}
+		targets := make([]v1.ActiveTarget, len(entities))
+		for i, entity := range entities {
+			ls := make(model.LabelSet, len(entity.Labels))
+			for name, value := range entity.Labels {
+				ls[model.LabelName(name)] = model.LabelValue(value)
+			}
+			targets[i] = v1.ActiveTarget{ScrapePool: entity.Pool, Labels: ls, Health: v1.HealthUnknown}
+		}
+		return targets, nil
+	}, ttl, opts)
+}
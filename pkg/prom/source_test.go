@@ -0,0 +1,101 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	v1 "github.com/prometheus/client_golang/api/prometheus/v1"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func TestTargetSources(t *testing.T) {
+	_, client := newTestServer(t)
+	targets, err := client.Targets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 元数据登记返回与 Prometheus 相同的目标
+	registry := MetadataRegistryFunc(func(_ context.Context, query map[string]string) ([]RegistryEntity, error) {
+		if query["region"] != "dc-1" {
+			return nil, nil
+		}
+		var entities []RegistryEntity
+		for _, target := range targets.Active {
+			entity := RegistryEntity{Pool: target.ScrapePool, Labels: map[string]string{}}
+			for name, value := range target.Labels {
+				entity.Labels[string(name)] = string(value)
+			}
+			entities = append(entities, entity)
+		}
+		return entities, nil
+	})
+
+	sources := map[string]TargetSource{
+		"indexed":  NewIndexedTargetCache(client, time.Hour),
+		"normal":   NewTargetCache(client, time.Hour),
+		"static":   StaticTargets(targets.Active),
+		"registry": NewRegistryTargetCache(registry, map[string]string{"region": "dc-1"}, time.Hour),
+	}
+	for _, source := range sources {
+		defer source.Close()
+	}
+
+	tests := []struct {
+		name     string
+		pool     string
+		matchers []*labels.Matcher
+		want     []string
+	}{
+		{"all", "", nil, []string{"10.0.0.1:9100", "10.0.0.1:9400", "10.0.0.2:9100", "localhost:9090"}},
+		{"pool", "node_exporter", nil, []string{"10.0.0.1:9100", "10.0.0.2:9100"}},
+		{"unknown pool", "mysql_exporter", nil, nil},
+		{"pool and matcher", "node_exporter", []*labels.Matcher{labels.MustNewMatcher(labels.MatchNotEqual, "instance", "10.0.0.1:9100")},
+			[]string{"10.0.0.2:9100"}},
+		{"matcher across pools", "", []*labels.Matcher{labels.MustNewMatcher(labels.MatchEqual, "data_center_id", "dc-1")},
+			[]string{"10.0.0.1:9100", "10.0.0.1:9400", "10.0.0.2:9100"}},
+	}
+	for name, source := range sources {
+		for _, tt := range tests {
+			t.Run(name+"/"+tt.name, func(t *testing.T) {
+				got, err := source.TargetsFor(tt.pool, tt.matchers...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var instances []string
+				for _, target := range got {
+					instances = append(instances, string(target.Labels["instance"]))
+				}
+				sort.Strings(instances)
+				if !reflect.DeepEqual(instances, tt.want) {
+					t.Errorf("got %v, want %v", instances, tt.want)
+				}
+			})
+		}
+	}
+
+	// 登记的实体没有健康状态
+	registered, _ := sources["registry"].TargetsFor("node_exporter")
+	if len(registered) == 0 || registered[0].Health != v1.HealthUnknown {
+		t.Errorf("registry targets should have unknown health: %+v", registered)
+	}
+}
+
+func TestRegistryTargetCacheError(t *testing.T) {
+	registry := MetadataRegistryFunc(func(context.Context, map[string]string) ([]RegistryEntity, error) {
+		return nil, errors.New("cmdb unavailable")
+	})
+	cache := NewRegistryTargetCache(registry, nil, time.Hour)
+	defer cache.Close()
+
+	if _, err := cache.TargetsFor("node_exporter"); !errors.Is(err, ErrCacheNotReady) {
+		t.Errorf("err = %v, want ErrCacheNotReady", err)
+	}
+	if err := cache.LastError(); err == nil || err.Error() != "failed to get targets: cmdb unavailable" {
+		t.Errorf("unexpected last error: %v", err)
+	}
+}
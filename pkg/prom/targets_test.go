@@ -24,7 +24,9 @@ func TestGetActiveTargetsByPool(t *testing.T) {
 		}
 	}
 
-	t.Logf("Found %d target pools", len(targets))
+	if len(targets) != 3 {
+		t.Errorf("expected 3 target pools, got %d", len(targets))
+	}
 }
 
 func TestGetOnlineTargetsByPool(t *testing.T) {
@@ -44,7 +46,9 @@ func TestGetOnlineTargetsByPool(t *testing.T) {
 		}
 	}
 
-	t.Logf("Found %d online target pools", len(onlineTargets))
+	if len(onlineTargets) != 2 {
+		t.Errorf("expected 2 online target pools, got %d", len(onlineTargets))
+	}
 }
 
 func TestGetOfflineTargetsByPool(t *testing.T) {
@@ -64,7 +68,9 @@ func TestGetOfflineTargetsByPool(t *testing.T) {
 		}
 	}
 
-	t.Logf("Found %d offline target pools", len(offlineTargets))
+	if len(offlineTargets) != 1 || offlineTargets[0].Targets[0].LastError != "connection refused" {
+		t.Errorf("unexpected offline target pools: %+v", offlineTargets)
+	}
 }
 
 func TestGetTargetPoolStats(t *testing.T) {
@@ -89,8 +95,9 @@ func TestGetTargetPoolStats(t *testing.T) {
 		}
 	}
 
-	t.Logf("Total targets across all pools: %d", totalCount)
-	t.Logf("Found %d target pools in stats", len(stats))
+	if totalCount != 4 || len(stats) != 3 {
+		t.Errorf("expected 4 targets in 3 pools, got %d in %d", totalCount, len(stats))
+	}
 }
 
 func TestFilterFunctionality(t *testing.T) {
@@ -154,11 +161,10 @@ func TestGetTargetHealthSummary(t *testing.T) {
 		t.Fatalf("GetTargetHealthSummary failed: %v", err)
 	}
 
-	total := 0
-	for health, count := range summary {
-		t.Logf("Health %s: %d targets", health, count)
-		total += count
+	want := map[string]int{TargetHealthGood: 2, TargetHealthBad: 1, TargetHealthUnknown: 1}
+	for health, count := range want {
+		if summary[health] != count {
+			t.Errorf("expected %d %s targets, got %d", count, health, summary[health])
+		}
 	}
-
-	t.Logf("Total targets: %d", total)
 }
@@ -0,0 +1,234 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// FileStore 将每条记录存为一个 JSON 文件：<dir>/<模板名>/<运行 ID>.json
+type FileStore struct {
+	dir   string
+	mutex sync.RWMutex
+}
+
+var _ Store = (*FileStore)(nil)
+
+// NewFileStore 使用 dir 目录存储记录，目录不存在时创建
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create store directory: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// Save 保存记录，先写临时文件再重命名，避免读到写了一半的记录
+func (s *FileStore) Save(_ context.Context, r *Record) error {
+	if r.ID == "" {
+		r.ID = NewID(r.ExecutedAt)
+	}
+	if err := checkID(r.ID); err != nil {
+		return err
+	}
+	name, err := templateDir(r.Template)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report %s: %w", r.ID, err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	// 同一 ID 可能已存于其它模板目录下，覆盖时先删除
+	if err := s.removeUnsafe(r.ID); err != nil {
+		return err
+	}
+	dir := filepath.Join(s.dir, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(dir, r.ID+".json"))
+}
+
+// Get 按运行 ID 读取记录
+func (s *FileStore) Get(_ context.Context, id string) (*Record, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	path, err := s.findUnsafe(id)
+	if err != nil {
+		return nil, err
+	}
+	return readRecord(path)
+}
+
+// List 列出满足条件的记录；需读取候选模板目录下的所有文件，MetadataOnly 时不解码报告
+func (s *FileStore) List(ctx context.Context, q Query) ([]*Record, error) {
+	pattern := filepath.Join(s.dir, "*", "*.json")
+	if q.Template != "" {
+		name, err := templateDir(q.Template)
+		if err != nil {
+			return nil, err
+		}
+		pattern = filepath.Join(s.dir, globEscape(name), "*.json")
+	}
+
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	paths, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	var records []*Record
+	for _, path := range paths {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		read := readRecord
+		if q.MetadataOnly {
+			read = readRecordMetadata
+		}
+		r, err := read(path)
+		if err != nil {
+			return nil, err
+		}
+		if q.match(r) {
+			records = append(records, r)
+		}
+	}
+	sortRecords(records)
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[:q.Limit]
+	}
+	return records, nil
+}
+
+// Delete 删除记录
+func (s *FileStore) Delete(_ context.Context, ids ...string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, id := range ids {
+		if err := checkID(id); err != nil {
+			return err
+		}
+		if err := s.removeUnsafe(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close 文件存储没有需要释放的资源
+func (s *FileStore) Close() error { return nil }
+
+// findUnsafe 查找记录文件；运行 ID 不含模板名，需在所有模板目录下查找
+func (s *FileStore) findUnsafe(id string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*", globEscape(id)+".json"))
+	if err != nil {
+		return "", err
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return paths[0], nil
+}
+
+// removeUnsafe 删除记录文件，不存在时忽略
+func (s *FileStore) removeUnsafe(id string) error {
+	path, err := s.findUnsafe(id)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func readRecord(path string) (*Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// readRecordMetadata 读取记录但跳过报告正文，返回的 Report 为 nil
+func readRecordMetadata(path string) (*Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// 外层的 Report 字段覆盖 Record.Report，报告只被扫描而不解码
+	var r struct {
+		Record
+		Report struct{} `json:"report"`
+	}
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("parse report %s: %w", path, err)
+	}
+	return &r.Record, nil
+}
+
+// templateDir 模板名对应的目录名：按 url.PathEscape 转义，只由点组成的名称（如 "."、".."）
+// 再将点转义为 %2E，避免写到存储目录本身或其上级目录
+func templateDir(template string) (string, error) {
+	if template == "" {
+		return "", errors.New("empty template name")
+	}
+	name := url.PathEscape(template)
+	if strings.Trim(name, ".") == "" {
+		name = strings.ReplaceAll(name, ".", "%2E")
+	}
+	return name, nil
+}
+
+// checkID 运行 ID 用作文件名，不能包含路径分隔符
+func checkID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) {
+		return fmt.Errorf("invalid report id %q", id)
+	}
+	return nil
+}
+
+// globEscape 转义 filepath.Glob 的元字符
+func globEscape(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
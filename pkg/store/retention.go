@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// Retention 保留策略，零值字段不生效；同时设置时满足任一条件的记录都会被删除
+type Retention struct {
+	// MaxAge 删除执行时间早于 now-MaxAge 的记录
+	MaxAge time.Duration
+	// MaxPerTemplate 每个模板只保留最新的 N 条记录
+	MaxPerTemplate int
+}
+
+// Prune 按保留策略删除记录，返回删除的条数
+//
+// 只列出记录的元数据，不解码报告正文；文件存储仍需逐个读取所有记录文件，
+// SQLite 存储在数据库内去掉报告后再返回。
+func Prune(ctx context.Context, s Store, policy Retention, now time.Time) (int, error) {
+	if policy.MaxAge <= 0 && policy.MaxPerTemplate <= 0 {
+		return 0, nil
+	}
+	records, err := s.List(ctx, Query{MetadataOnly: true})
+	if err != nil {
+		return 0, err
+	}
+
+	var expired []string
+	kept := make(map[string]int) // 模板 -> 已保留的条数
+	cutoff := now.Add(-policy.MaxAge)
+	// records 从新到旧，每个模板的前 MaxPerTemplate 条是最新的
+	for _, r := range records {
+		if policy.MaxAge > 0 && r.ExecutedAt.Before(cutoff) {
+			expired = append(expired, r.ID)
+			continue
+		}
+		if policy.MaxPerTemplate > 0 && kept[r.Template] >= policy.MaxPerTemplate {
+			expired = append(expired, r.ID)
+			continue
+		}
+		kept[r.Template]++
+	}
+	if len(expired) == 0 {
+		return 0, nil
+	}
+	if err := s.Delete(ctx, expired...); err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
@@ -0,0 +1,192 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	_ "modernc.org/sqlite" // 纯 Go 实现的 SQLite 驱动，无需 cgo
+)
+
+// sqliteSchema 记录表与标签表；报告正文以 JSON 存放，过滤用到的字段单独成列
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS reports (
+	id               TEXT PRIMARY KEY,
+	template         TEXT NOT NULL,
+	template_version TEXT NOT NULL DEFAULT '',
+	executed_at      INTEGER NOT NULL, -- Unix 纳秒
+	record           TEXT NOT NULL     -- Record 的 JSON
+);
+CREATE INDEX IF NOT EXISTS reports_template_executed_at ON reports (template, executed_at);
+CREATE INDEX IF NOT EXISTS reports_executed_at ON reports (executed_at);
+CREATE TABLE IF NOT EXISTS report_tags (
+	id  TEXT NOT NULL REFERENCES reports (id) ON DELETE CASCADE,
+	tag TEXT NOT NULL,
+	PRIMARY KEY (tag, id)
+);
+`
+
+// SQLiteStore 使用嵌入式 SQLite 数据库存储记录
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+var _ Store = (*SQLiteStore)(nil)
+
+// OpenSQLite 打开（不存在时创建）SQLite 数据库文件
+func OpenSQLite(path string) (*SQLiteStore, error) {
+	// SQLite 按 URI 解析 file: 开头的地址，路径中的 ?、#、% 需要转义；
+	// Windows 的绝对路径（C:/...）在 URI 中写作 file:///C:/...
+	uriPath := filepath.ToSlash(path)
+	if filepath.IsAbs(path) && !strings.HasPrefix(uriPath, "/") {
+		uriPath = "/" + uriPath
+	}
+	dsn := url.URL{
+		Scheme:   "file",
+		Path:     uriPath,
+		RawQuery: "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}
+	db, err := sql.Open("sqlite", dsn.String())
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("init sqlite store %s: %w", path, err)
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+// Save 保存记录
+func (s *SQLiteStore) Save(ctx context.Context, r *Record) error {
+	if r.ID == "" {
+		r.ID = NewID(r.ExecutedAt)
+	}
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode report %s: %w", r.ID, err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	// 覆盖已有记录时先删除旧行，标签随外键级联删除
+	if _, err := tx.ExecContext(ctx, `DELETE FROM reports WHERE id = ?`, r.ID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO reports (id, template, template_version, executed_at, record) VALUES (?, ?, ?, ?, ?)`,
+		r.ID, r.Template, r.TemplateVersion, r.ExecutedAt.UnixNano(), string(data)); err != nil {
+		return err
+	}
+	for _, tag := range r.Tags {
+		if _, err := tx.ExecContext(ctx, `INSERT OR IGNORE INTO report_tags (id, tag) VALUES (?, ?)`, r.ID, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Get 按运行 ID 读取记录
+func (s *SQLiteStore) Get(ctx context.Context, id string) (*Record, error) {
+	var data string
+	err := s.db.QueryRowContext(ctx, `SELECT record FROM reports WHERE id = ?`, id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeRecord(id, data)
+}
+
+// List 列出满足条件的记录，过滤与排序在数据库中完成
+func (s *SQLiteStore) List(ctx context.Context, q Query) ([]*Record, error) {
+	var where []string
+	var args []any
+	if q.Template != "" {
+		where = append(where, "template = ?")
+		args = append(args, q.Template)
+	}
+	if !q.Since.IsZero() {
+		where = append(where, "executed_at >= ?")
+		args = append(args, q.Since.UnixNano())
+	}
+	if !q.Until.IsZero() {
+		where = append(where, "executed_at < ?")
+		args = append(args, q.Until.UnixNano())
+	}
+	for _, tag := range q.Tags {
+		where = append(where, "id IN (SELECT id FROM report_tags WHERE tag = ?)")
+		args = append(args, tag)
+	}
+	query := "SELECT id, record FROM reports"
+	if q.MetadataOnly {
+		query = "SELECT id, json_remove(record, '$.report') FROM reports"
+	}
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY executed_at DESC, id DESC"
+	if q.Limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, q.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var records []*Record
+	for rows.Next() {
+		var id, data string
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		r, err := decodeRecord(id, data)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, r)
+	}
+	return records, rows.Err()
+}
+
+// Delete 删除记录
+func (s *SQLiteStore) Delete(ctx context.Context, ids ...string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, `DELETE FROM reports WHERE id = ?`, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Close 关闭数据库
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+func decodeRecord(id, data string) (*Record, error) {
+	var r Record
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return nil, fmt.Errorf("parse report %s: %w", id, err)
+	}
+	return &r, nil
+}
@@ -0,0 +1,153 @@
+// Package store 持久化巡检报告：每次执行的报告连同模板版本、变量与标签存为一条记录，
+// 支持按模板、时间范围与标签列出，按运行 ID 读取，以及按保留策略清理。
+//
+// 提供两种后端：文件系统 JSON（NewFileStore）与嵌入式 SQLite（OpenSQLite），
+// 也可通过 Open 按地址选择。
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("report not found")
+
+// Record 一次巡检执行的存档
+type Record struct {
+	// ID 运行 ID，以执行时间开头，按字典序即按时间排序
+	ID              string            `json:"id"`
+	Template        string            `json:"template"`
+	TemplateVersion string            `json:"template_version,omitempty"`
+	Vars            map[string]string `json:"vars,omitempty"`
+	Tags            []string          `json:"tags,omitempty"`
+	ExecutedAt      time.Time         `json:"executed_at"`
+	// Error 执行中失败指标的汇总，报告中只包含成功的指标
+	Error  string             `json:"error,omitempty"`
+	Report *inspection.Report `json:"report"`
+}
+
+// NewRecord 根据一次执行的结果创建记录，标签取自模板，runErr 为 Executor.Execute 返回的错误
+func NewRecord(tpl *inspection.Template, vars map[string]string, report *inspection.Report, runErr error) *Record {
+	r := &Record{
+		ID:              NewID(report.Template.ExecutedAt),
+		Template:        tpl.Name,
+		TemplateVersion: tpl.Version,
+		Vars:            vars,
+		Tags:            slices.Clone(tpl.Tags),
+		ExecutedAt:      report.Template.ExecutedAt,
+		Report:          report,
+	}
+	if runErr != nil {
+		r.Error = runErr.Error()
+	}
+	return r
+}
+
+// NewID 生成运行 ID：UTC 执行时间加随机后缀，如 20260101T090000.000Z-1a2b3c4d
+func NewID(executedAt time.Time) string {
+	var suffix [4]byte
+	_, _ = rand.Read(suffix[:])
+	return executedAt.UTC().Format("20060102T150405.000Z") + "-" + hex.EncodeToString(suffix[:])
+}
+
+// Query 列出记录的条件，零值字段不参与过滤
+type Query struct {
+	Template string
+	// Since、Until 执行时间范围 [Since, Until)
+	Since time.Time
+	Until time.Time
+	// Tags 记录须包含所有标签
+	Tags []string
+	// Limit 最多返回的条数，0 表示不限制
+	Limit int
+	// MetadataOnly 只返回记录的元数据，Report 为 nil，用于不需要报告正文的场景（如清理）
+	MetadataOnly bool
+}
+
+// match 记录是否满足条件（不含 Limit）
+func (q Query) match(r *Record) bool {
+	if q.Template != "" && r.Template != q.Template {
+		return false
+	}
+	if !q.Since.IsZero() && r.ExecutedAt.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !r.ExecutedAt.Before(q.Until) {
+		return false
+	}
+	for _, tag := range q.Tags {
+		if !slices.Contains(r.Tags, tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Store 报告存储后端
+type Store interface {
+	// Save 保存记录，ID 为空时自动生成；ID 已存在时覆盖
+	Save(ctx context.Context, r *Record) error
+	// Get 按运行 ID 读取记录，不存在时返回 ErrNotFound
+	Get(ctx context.Context, id string) (*Record, error)
+	// List 列出满足条件的记录，按执行时间从新到旧排序
+	List(ctx context.Context, q Query) ([]*Record, error)
+	// Delete 删除记录，不存在的 ID 忽略
+	Delete(ctx context.Context, ids ...string) error
+	Close() error
+}
+
+// Open 按地址打开存储：
+//
+//	sqlite:/var/lib/inspection/reports.db   SQLite 数据库文件
+//	file:/var/lib/inspection/reports        文件系统目录，不带前缀时同样视为目录
+func Open(addr string) (Store, error) {
+	scheme, path, ok := strings.Cut(addr, ":")
+	if !ok || len(scheme) == 1 { // 不带前缀，或 Windows 盘符
+		scheme, path = "file", addr
+	}
+	if path == "" {
+		return nil, fmt.Errorf("store address %q: empty path", addr)
+	}
+	switch scheme {
+	case "file":
+		return NewFileStore(path)
+	case "sqlite":
+		return OpenSQLite(path)
+	default:
+		return nil, fmt.Errorf("store address %q: unsupported backend %q, want file or sqlite", addr, scheme)
+	}
+}
+
+// sortRecords 按执行时间从新到旧排序，时间相同时按 ID
+func sortRecords(records []*Record) {
+	slices.SortFunc(records, func(a, b *Record) int {
+		if c := b.ExecutedAt.Compare(a.ExecutedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(b.ID, a.ID)
+	})
+}
+
+// History 以存储中的记录作为执行器的历史报告来源，每次取该模板最近的 limit 条
+func History(s Store, limit int) inspection.HistoryFunc {
+	return func(ctx context.Context, template string, before time.Time) ([]*inspection.Report, error) {
+		records, err := s.List(ctx, Query{Template: template, Until: before, Limit: limit})
+		if err != nil {
+			return nil, err
+		}
+		reports := make([]*inspection.Report, len(records))
+		for i, r := range records {
+			reports[i] = r.Report
+		}
+		return reports, nil
+	}
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kekexiaoai/inspection/pkg/inspection"
+)
+
+var baseTime = time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+// newTestRecord 创建 days 天后执行的记录
+func newTestRecord(template string, days int, tags ...string) *Record {
+	report := &inspection.Report{}
+	report.Template.Name = template
+	report.Template.ExecutedAt = baseTime.AddDate(0, 0, days)
+	value := 42.0
+	report.Results = []*inspection.IndicatorResult{{
+		Indicator: "GPU 使用率",
+		Summary:   inspection.Summary{Total: 1, Ok: 1},
+		Values:    []inspection.ValueItem{{Target: "10.0.0.1", Value: &value, Status: inspection.ThresholdLevelOk}},
+	}}
+	tpl := &inspection.Template{Name: template, Version: "v1", Tags: tags}
+	return NewRecord(tpl, map[string]string{"ClusterRegex": ".*"}, report, nil)
+}
+
+func ids(records []*Record) []string {
+	result := make([]string, len(records))
+	for i, r := range records {
+		result[i] = r.ID
+	}
+	return result
+}
+
+func openStores(t *testing.T) map[string]Store {
+	t.Helper()
+	fileStore, err := NewFileStore(filepath.Join(t.TempDir(), "reports"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqliteStore, err := OpenSQLite(filepath.Join(t.TempDir(), "reports.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqliteStore.Close() })
+	return map[string]Store{"file": fileStore, "sqlite": sqliteStore}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			gpu0 := newTestRecord("daily-gpu", 0, "gpu", "daily")
+			gpu1 := newTestRecord("daily-gpu", 1, "gpu", "daily")
+			gpu2 := newTestRecord("daily-gpu", 2, "gpu")
+			node1 := newTestRecord("daily-node", 1, "daily")
+			node1.ExecutedAt = node1.ExecutedAt.Add(time.Hour)
+			for _, r := range []*Record{gpu0, gpu1, gpu2, node1} {
+				if err := s.Save(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := s.Get(ctx, gpu1.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Template != "daily-gpu" || got.TemplateVersion != "v1" || got.Vars["ClusterRegex"] != ".*" ||
+				!got.ExecutedAt.Equal(gpu1.ExecutedAt) || *got.Report.Results[0].Values[0].Value != 42 {
+				t.Errorf("Get(%s) = %+v", gpu1.ID, got)
+			}
+			if _, err := s.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get(missing): got %v, want ErrNotFound", err)
+			}
+
+			tests := []struct {
+				name  string
+				query Query
+				want  []*Record
+			}{
+				{"all", Query{}, []*Record{gpu2, node1, gpu1, gpu0}},
+				{"template", Query{Template: "daily-gpu"}, []*Record{gpu2, gpu1, gpu0}},
+				{"time range", Query{Since: baseTime.AddDate(0, 0, 1), Until: baseTime.AddDate(0, 0, 2)}, []*Record{node1, gpu1}},
+				{"tags", Query{Tags: []string{"gpu", "daily"}}, []*Record{gpu1, gpu0}},
+				{"limit", Query{Template: "daily-gpu", Limit: 2}, []*Record{gpu2, gpu1}},
+				{"no match", Query{Template: "other"}, nil},
+			}
+			for _, tt := range tests {
+				records, err := s.List(ctx, tt.query)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !slices.Equal(ids(records), ids(tt.want)) {
+					t.Errorf("%s: got %v, want %v", tt.name, ids(records), ids(tt.want))
+				}
+			}
+
+			metadata, err := s.List(ctx, Query{Template: "daily-gpu", MetadataOnly: true, Limit: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(metadata) != 1 || metadata[0].ID != gpu2.ID || metadata[0].Report != nil || !slices.Equal(metadata[0].Tags, gpu2.Tags) {
+				t.Errorf("MetadataOnly: got %+v", metadata)
+			}
+
+			// 覆盖已有记录时标签随之更新
+			gpu0.Tags = []string{"weekly"}
+			if err := s.Save(ctx, gpu0); err != nil {
+				t.Fatal(err)
+			}
+			if records, _ := s.List(ctx, Query{Tags: []string{"gpu"}}); len(records) != 2 {
+				t.Errorf("after overwrite: got %v with tag gpu, want 2 records", ids(records))
+			}
+
+			if err := s.Delete(ctx, gpu0.ID, "missing"); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := s.Get(ctx, gpu0.ID); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get after Delete: got %v, want ErrNotFound", err)
+			}
+		})
+	}
+}
+
+func TestFileStoreTemplateDir(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	dir := filepath.Join(root, "reports")
+	s, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, template := range []string{"..", ".", "a/../.."} {
+		r := newTestRecord(template, 0)
+		if err := s.Save(ctx, r); err != nil {
+			t.Fatalf("Save(%q): %v", template, err)
+		}
+		records, err := s.List(ctx, Query{Template: template})
+		if err != nil || len(records) != 1 || records[0].ID != r.ID {
+			t.Errorf("List(%q) = %v, %v", template, ids(records), err)
+		}
+	}
+	// 记录都在模板子目录下，不会写到存储目录之外或根目录
+	if outside, _ := filepath.Glob(filepath.Join(root, "*.json")); len(outside) > 0 {
+		t.Errorf("records written outside the store: %v", outside)
+	}
+	if inRoot, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(inRoot) > 0 {
+		t.Errorf("records written into the store root: %v", inRoot)
+	}
+	if records, _ := s.List(ctx, Query{}); len(records) != 3 {
+		t.Errorf("List() = %v, want 3 records", ids(records))
+	}
+	if err := s.Save(ctx, newTestRecord("", 0)); err == nil {
+		t.Error("Save with empty template: expected error")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	ctx := context.Background()
+	for name, s := range openStores(t) {
+		t.Run(name, func(t *testing.T) {
+			var gpu []*Record
+			for day := range 5 {
+				r := newTestRecord("daily-gpu", day)
+				gpu = append(gpu, r)
+				if err := s.Save(ctx, r); err != nil {
+					t.Fatal(err)
+				}
+			}
+			node := newTestRecord("daily-node", 0)
+			if err := s.Save(ctx, node); err != nil {
+				t.Fatal(err)
+			}
+
+			now := baseTime.AddDate(0, 0, 5)
+			n, err := Prune(ctx, s, Retention{MaxPerTemplate: 3}, now)
+			if err != nil || n != 2 {
+				t.Fatalf("Prune(MaxPerTemplate: 3) = %d, %v; want 2", n, err)
+			}
+			n, err = Prune(ctx, s, Retention{MaxAge: 72 * time.Hour}, now)
+			if err != nil || n != 1 {
+				t.Fatalf("Prune(MaxAge: 72h) = %d, %v; want 1", n, err)
+			}
+
+			records, err := s.List(ctx, Query{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := []string{gpu[4].ID, gpu[3].ID, gpu[2].ID}
+			if !slices.Equal(ids(records), want) {
+				t.Errorf("remaining: got %v, want %v", ids(records), want)
+			}
+		})
+	}
+}
+
+func TestOpenSQLite_SpecialPath(t *testing.T) {
+	// 路径中的 URI 特殊字符不能截断路径或丢掉 pragma
+	path := filepath.Join(t.TempDir(), "a?b#c%20d", "reports?.db")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	s, err := OpenSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Save(context.Background(), newTestRecord("daily-gpu", 0)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("database not created at %s: %v", path, err)
+	}
+	var mode string
+	if err := s.db.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %q, %v; want wal", mode, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		addr    string
+		want    string
+		wantErr bool
+	}{
+		{addr: filepath.Join(dir, "plain"), want: "*store.FileStore"},
+		{addr: "file:" + filepath.Join(dir, "file"), want: "*store.FileStore"},
+		{addr: "sqlite:" + filepath.Join(dir, "reports.db"), want: "*store.SQLiteStore"},
+		{addr: "s3:bucket", wantErr: true},
+		{addr: "sqlite:", wantErr: true},
+	}
+	for _, tt := range tests {
+		s, err := Open(tt.addr)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("Open(%q): expected error", tt.addr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Open(%q): %v", tt.addr, err)
+			continue
+		}
+		if got := fmt.Sprintf("%T", s); got != tt.want {
+			t.Errorf("Open(%q) = %s, want %s", tt.addr, got, tt.want)
+		}
+		s.Close()
+	}
+}
+
+func TestHistory(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for day := range 4 {
+		if err := s.Save(ctx, newTestRecord("daily-gpu", day)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Save(ctx, newTestRecord("daily-node", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := History(s, 2)(ctx, "daily-gpu", baseTime.AddDate(0, 0, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 2 {
+		t.Fatalf("got %d reports, want 2", len(reports))
+	}
+	for i, want := range []time.Time{baseTime.AddDate(0, 0, 2), baseTime.AddDate(0, 0, 1)} {
+		if got := reports[i].Template.ExecutedAt; !got.Equal(want) || reports[i].Template.Name != "daily-gpu" {
+			t.Errorf("report %d: %s executed at %s, want daily-gpu at %s", i, reports[i].Template.Name, got, want)
+		}
+	}
+}